@@ -0,0 +1,229 @@
+package scrape
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/crypto/ssh"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// agentReply describes a scrape taken by the agent, and how many families
+// follow it.
+type agentReply struct {
+	Families    int    `json:"families"`
+	Error       string `json:"error,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	Bytes       int64  `json:"bytes"`
+	ParseFailed bool   `json:"parseFailed,omitempty"`
+}
+
+// RunAgent is met agent. Run on a remote host, started over SSH by met
+// connect, it scrapes src whenever it's asked to, so only SSH has to be let
+// through the firewall. Each line read from r asks for a scrape, answered
+// on w with a line of JSON describing it followed by the parsed families,
+// each a delimited protobuf message. It returns once r is closed.
+func RunAgent(r io.Reader, w io.Writer, src HTTP) error {
+	requests := bufio.NewScanner(r)
+	out := bufio.NewWriter(w)
+	for requests.Scan() {
+		fams, stats, err := src.ScrapeWithStats()
+		reply := agentReply{Status: stats.Status, Bytes: stats.Bytes, ParseFailed: stats.ParseFailed}
+		if err != nil {
+			// What was parsed before the error isn't shown either.
+			fams = nil
+			reply.Error = err.Error()
+		}
+		reply.Families = len(fams)
+		header, err := json.Marshal(reply)
+		if err != nil {
+			return err
+		}
+		out.Write(append(header, '\n'))
+		for _, mf := range fams {
+			if _, err := protodelim.MarshalTo(out, mf); err != nil {
+				return err
+			}
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+	}
+	return requests.Err()
+}
+
+// AgentSource scrapes through met agent on a remote host, started on the
+// first scrape and again whenever the session drops.
+type AgentSource struct {
+	tunnel  *sshTunnel
+	host    string
+	command string
+
+	// mu serializes scrapes, which share the session.
+	mu      sync.Mutex
+	session *ssh.Session
+	stdin   io.WriteCloser
+	stdout  *bufio.Reader
+	stderr  *syncBuffer
+}
+
+// NewAgentSource connects to host to run the agent, scraping endpoint with
+// the given format and limit.
+func NewAgentSource(host, agent, endpoint, format string, maxBodySize int64) (*AgentSource, error) {
+	t, err := newSSHTunnel(host)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{agent, "--endpoint", shellQuote(endpoint), "--format", format,
+		"--max-body-size", strconv.FormatInt(maxBodySize, 10)}
+	return &AgentSource{tunnel: t, host: host, command: strings.Join(args, " ")}, nil
+}
+
+func (s *AgentSource) String() string {
+	return "agent on " + s.host
+}
+
+func (s *AgentSource) Scrape() (map[string]*dto.MetricFamily, error) {
+	fams, _, err := s.ScrapeWithStats()
+	return fams, err
+}
+
+// ScrapeWithStats passes on the agent's status and size of the response it
+// scraped, for the health panel.
+func (s *AgentSource) ScrapeWithStats() (map[string]*dto.MetricFamily, Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := Stats{Bytes: -1}
+	if s.session == nil {
+		if err := s.start(); err != nil {
+			return nil, stats, err
+		}
+	}
+	fams, reply, err := s.request()
+	if err != nil {
+		err = s.stop(err)
+		return nil, stats, err
+	}
+	stats = Stats{Bytes: reply.Bytes, Status: reply.Status, ParseFailed: reply.ParseFailed}
+	if reply.Error != "" {
+		return nil, stats, errors.New(reply.Error)
+	}
+	return fams, stats, nil
+}
+
+// start runs the agent in a new session.
+func (s *AgentSource) start() error {
+	c, err := s.tunnel.connect()
+	if err != nil {
+		return err
+	}
+	session, err := c.NewSession()
+	if err != nil {
+		// The connection may have dropped since it was last used.
+		s.tunnel.reset(c)
+		if c, err = s.tunnel.connect(); err != nil {
+			return err
+		}
+		if session, err = c.NewSession(); err != nil {
+			return fmt.Errorf("opening an SSH session on %s: %w", s.host, err)
+		}
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return err
+	}
+	s.stderr = &syncBuffer{}
+	session.Stderr = s.stderr
+	if err := session.Start(s.command); err != nil {
+		session.Close()
+		return fmt.Errorf("starting %q on %s: %w", s.command, s.host, err)
+	}
+	s.session, s.stdin, s.stdout = session, stdin, bufio.NewReader(stdout)
+	return nil
+}
+
+// request asks the agent for a scrape and reads its reply.
+func (s *AgentSource) request() (map[string]*dto.MetricFamily, agentReply, error) {
+	var reply agentReply
+	if _, err := io.WriteString(s.stdin, "scrape\n"); err != nil {
+		return nil, reply, err
+	}
+	line, err := s.stdout.ReadBytes('\n')
+	if err != nil {
+		return nil, reply, err
+	}
+	if err := json.Unmarshal(line, &reply); err != nil {
+		return nil, reply, fmt.Errorf("unexpected reply from the agent: %q", bytes.TrimSpace(line))
+	}
+	fams := make(map[string]*dto.MetricFamily, reply.Families)
+	for range reply.Families {
+		mf := &dto.MetricFamily{}
+		if err := (protodelim.UnmarshalOptions{MaxSize: -1}).UnmarshalFrom(s.stdout, mf); err != nil {
+			return nil, reply, err
+		}
+		fams[mf.GetName()] = mf
+	}
+	return fams, reply, nil
+}
+
+// stop closes the session after err, for the next scrape to start another,
+// and explains err with what the agent wrote to stderr, such as met not
+// being installed.
+func (s *AgentSource) stop(err error) error {
+	s.stdin.Close()
+	if errors.Is(err, io.EOF) {
+		// The agent exited, so wait for the last of its stderr.
+		s.session.Wait()
+	}
+	s.session.Close()
+	s.session = nil
+	if msg := strings.TrimSpace(s.stderr.String()); errors.Is(err, io.EOF) && msg != "" {
+		return fmt.Errorf("agent on %s: %s", s.host, lastLine(msg))
+	}
+	return fmt.Errorf("agent on %s: %w", s.host, err)
+}
+
+// syncBuffer is a buffer written by the SSH session's goroutines and read by
+// scrapes.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// shellQuote quotes s for the remote shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
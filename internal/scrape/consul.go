@@ -0,0 +1,120 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jaxxstorm/met/pkg/met"
+)
+
+// Consul finds the instances of a service in the Consul catalog,
+// along with the state of their health checks.
+type Consul struct {
+	Client      *http.Client
+	Addr        string
+	Service     string
+	Tag         []string
+	Token       string
+	Datacenter  string
+	PassingOnly bool
+	Scheme      string
+	MetricsPath string
+}
+
+// consulServiceEntry is the part of an entry from Consul's
+// /v1/health/service endpoint that's needed to scrape an instance.
+type consulServiceEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+	}
+	Checks []struct {
+		Status string
+	}
+}
+
+func (d Consul) Discover() ([]Target, error) {
+	addr := d.Addr
+	if !strings.Contains(addr, "://") {
+		// CONSUL_HTTP_ADDR is usually set without a scheme.
+		addr = "http://" + addr
+	}
+	q := url.Values{}
+	for _, tag := range d.Tag {
+		q.Add("tag", tag)
+	}
+	if d.Datacenter != "" {
+		q.Set("dc", d.Datacenter)
+	}
+	if d.PassingOnly {
+		q.Set("passing", "true")
+	}
+	u := strings.TrimSuffix(addr, "/") + "/v1/health/service/" + url.PathEscape(d.Service) + "?" + q.Encode()
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if d.Token != "" {
+		req.Header.Set("X-Consul-Token", d.Token)
+	}
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("querying Consul: %w", met.StatusError(resp.StatusCode))
+	}
+	var entries []consulServiceEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("decoding Consul response: %w", err)
+	}
+
+	targets := make([]Target, 0, len(entries))
+	for _, e := range entries {
+		host := e.Service.Address
+		if host == "" {
+			host = e.Node.Address
+		}
+		ep := (&url.URL{
+			Scheme: d.Scheme,
+			Host:   net.JoinHostPort(host, strconv.Itoa(e.Service.Port)),
+			Path:   d.MetricsPath,
+		}).String()
+		targets = append(targets, Target{Endpoint: ep, Health: consulHealth(e)})
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Endpoint < targets[j].Endpoint })
+	return targets, nil
+}
+
+// consulHealth sums up an instance's checks the way Consul does: critical if
+// any check is, then warning, otherwise passing.
+func consulHealth(e consulServiceEntry) string {
+	health := "passing"
+	for _, c := range e.Checks {
+		switch c.Status {
+		case "critical":
+			return "critical"
+		case "warning":
+			health = "warning"
+		}
+	}
+	return health
+}
+
+func (d Consul) String() string {
+	return "Consul service " + d.Service
+}
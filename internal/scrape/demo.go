@@ -0,0 +1,200 @@
+package scrape
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// demoBuckets are the upper bounds of the demo latency histogram.
+var demoBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// demoPods are the instances the demo's per-pod series are spread over,
+// with the long generated names label completion is meant for.
+var demoPods = []string{"checkout-7d9f8b6c4-x2kqp", "checkout-7d9f8b6c4-m8rtw", "checkout-7d9f8b6c4-9hzvl"}
+
+// demoExporter serves synthetic metrics that move like a real service's:
+// traffic that rises and falls, occasional bursts of errors, a queue that
+// wanders, memory that climbs until it's collected, a worker whose counter
+// resets, and series that come and go. Everything is advanced to the
+// current time when scraped.
+type demoExporter struct {
+	mu    sync.Mutex
+	rng   *rand.Rand
+	start time.Time
+	last  time.Time
+	// version is reported by demo_build_info.
+	version string
+
+	requests    map[string]float64
+	buckets     []float64
+	latencySum  float64
+	latencyN    float64
+	bytesSent   float64
+	jobs        float64
+	queue       float64
+	heap        float64
+	temperature float64
+	cacheSize   float64
+}
+
+func newDemoExporter(now time.Time, version string) *demoExporter {
+	return &demoExporter{
+		rng:       rand.New(rand.NewPCG(uint64(now.UnixNano()), 0)),
+		version:   version,
+		start:     now,
+		last:      now,
+		requests:  make(map[string]float64),
+		buckets:   make([]float64, len(demoBuckets)),
+		queue:     10,
+		heap:      64 << 20,
+		cacheSize: 1000,
+	}
+}
+
+// advance moves the metrics on to now.
+func (d *demoExporter) advance(now time.Time) {
+	dt := now.Sub(d.last).Seconds()
+	if dt <= 0 {
+		return
+	}
+	d.last = now
+	up := now.Sub(d.start).Seconds()
+
+	// Traffic follows a slow wave with some noise, and errors come in
+	// bursts for a few seconds each minute.
+	rps := 50 + 30*math.Sin(up/60) + 10*d.rng.NormFloat64()
+	rps = max(rps, 5)
+	errRate := 0.01
+	if math.Mod(up, 60) > 50 {
+		errRate = 0.2
+	}
+	for _, pod := range demoPods {
+		n := math.Round(rps * dt / float64(len(demoPods)))
+		errs := math.Round(n * errRate * d.rng.Float64() * 2)
+		d.requests[pod+"|GET|200"] += n - errs
+		d.requests[pod+"|GET|500"] += errs
+		d.requests[pod+"|POST|201"] += math.Round(n / 5)
+		for i := 0.0; i < n; i++ {
+			d.observeLatency(0.02 * math.Exp(d.rng.NormFloat64()) * (1 + 4*errRate))
+		}
+		d.bytesSent += n * (2048 + 512*d.rng.NormFloat64())
+	}
+
+	// The worker restarts every five minutes, resetting its counter.
+	if int(up)/300 != int(up-dt)/300 {
+		d.jobs = 0
+	}
+	d.jobs += math.Round(5 * dt * d.rng.Float64() * 2)
+
+	d.queue = max(d.queue+3*d.rng.NormFloat64()*math.Sqrt(dt), 0)
+	d.heap += (2 << 20) * dt * d.rng.Float64()
+	if d.heap > 256<<20 {
+		d.heap = 64 << 20
+	}
+	d.temperature = 45 + 10*math.Sin(up/120) + d.rng.NormFloat64()
+	if d.rng.Float64() < 0.1 {
+		d.cacheSize += math.Round(50 * d.rng.NormFloat64())
+	}
+}
+
+func (d *demoExporter) observeLatency(v float64) {
+	for i, le := range demoBuckets {
+		if v <= le {
+			d.buckets[i]++
+		}
+	}
+	d.latencySum += v
+	d.latencyN++
+}
+
+func (d *demoExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	d.advance(now)
+	up := now.Sub(d.start)
+
+	var sb strings.Builder
+	family := func(name, typ, help string) {
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+	sample := func(name, labels string, v float64) {
+		if labels != "" {
+			labels = "{" + labels + "}"
+		}
+		fmt.Fprintf(&sb, "%s%s %s\n", name, labels, strconv.FormatFloat(v, 'g', -1, 64))
+	}
+
+	family("demo_http_requests_total", "counter", "HTTP requests served.")
+	keys := make([]string, 0, len(d.requests))
+	for k := range d.requests {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts := strings.Split(k, "|")
+		sample("demo_http_requests_total", fmt.Sprintf(`pod=%q,method=%q,code=%q`, parts[0], parts[1], parts[2]), d.requests[k])
+	}
+
+	family("demo_http_request_duration_seconds", "histogram", "HTTP request latency.")
+	for i, le := range demoBuckets {
+		sample("demo_http_request_duration_seconds_bucket", fmt.Sprintf(`le="%g"`, le), d.buckets[i])
+	}
+	sample("demo_http_request_duration_seconds_bucket", `le="+Inf"`, d.latencyN)
+	sample("demo_http_request_duration_seconds_sum", "", d.latencySum)
+	sample("demo_http_request_duration_seconds_count", "", d.latencyN)
+
+	family("demo_http_response_size_bytes_total", "counter", "Bytes sent in responses.")
+	sample("demo_http_response_size_bytes_total", "", d.bytesSent)
+	family("demo_jobs_processed_total", "counter", "Jobs processed by the worker, which restarts every five minutes.")
+	sample("demo_jobs_processed_total", `worker="1"`, d.jobs)
+	family("demo_queue_depth", "gauge", "Jobs waiting in the queue.")
+	sample("demo_queue_depth", "", math.Round(d.queue))
+	family("demo_heap_bytes", "gauge", "Heap in use.")
+	sample("demo_heap_bytes", "", d.heap)
+	family("demo_temperature_celsius", "gauge", "Temperature of the imaginary server.")
+	sample("demo_temperature_celsius", "", d.temperature)
+	family("demo_cache_entries", "gauge", "Entries in the cache, which rarely changes.")
+	sample("demo_cache_entries", "", d.cacheSize)
+
+	// A batch job shows up after the first minute and is gone for half of
+	// every other one.
+	if up > time.Minute && math.Mod(up.Seconds(), 120) < 60 {
+		family("demo_batch_last_success_timestamp_seconds", "gauge", "When the batch job last succeeded.")
+		sample("demo_batch_last_success_timestamp_seconds", "", float64(d.start.Unix()+60))
+	}
+
+	family("demo_build_info", "gauge", "Build information.")
+	sample("demo_build_info", fmt.Sprintf(`version=%q`, d.version), 1)
+	family("process_start_time_seconds", "gauge", "Start time of the process since unix epoch in seconds.")
+	sample("process_start_time_seconds", "", float64(d.start.Unix()))
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprint(w, sb.String())
+}
+
+// StartDemo serves the demo exporter on addr, returning the URL to scrape.
+// Its build info reports version, and logger is told if it stops.
+func StartDemo(addr, version string, logger *slog.Logger) (string, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", newDemoExporter(time.Now(), version))
+	go func() {
+		if err := http.Serve(ln, mux); err != nil {
+			logger.Error("Demo exporter stopped", "err", err)
+		}
+	}()
+	return "http://" + ln.Addr().String() + "/metrics", nil
+}
@@ -0,0 +1,73 @@
+package scrape
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// detectPaths are the paths metrics are commonly served on, tried in turn
+// for endpoints given without one.
+var detectPaths = map[string][]string{
+	"prometheus": {"/metrics", "/actuator/prometheus", "/probe"},
+	"expvar":     {"/debug/vars"},
+}
+
+// detectPorts are the ports exporters commonly listen on, tried in turn
+// for endpoints given without one: Prometheus itself, the node exporter,
+// the Pushgateway, the blackbox exporter, and the usual HTTP port.
+var detectPorts = []int{9090, 9100, 9091, 9115, 8080}
+
+// detectTimeout is how long each candidate has to answer.
+const detectTimeout = 2 * time.Second
+
+// Detect looks for where the metrics of an endpoint given without
+// a path, such as http://host:9100, are served, trying the common paths
+// and, if it has no port either, the common ports. It returns the endpoint
+// unchanged, and found false, if it has a path already or nothing
+// answered.
+func Detect(client *http.Client, endpoint, format string, maxBodySize int64) (string, bool) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return endpoint, false
+	}
+	paths := detectPaths[format]
+	if len(paths) == 0 {
+		return endpoint, false
+	}
+	hosts := []string{u.Host}
+	if u.Port() == "" {
+		for _, port := range detectPorts {
+			hosts = append(hosts, net.JoinHostPort(u.Hostname(), strconv.Itoa(port)))
+		}
+	}
+	probe := *client
+	probe.Timeout = detectTimeout
+	for _, host := range hosts {
+		// A closed port refuses the connection far quicker than
+		// each of the paths would time out.
+		addr := host
+		if u.Port() == "" && host == u.Host {
+			port := "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+			addr = net.JoinHostPort(u.Hostname(), port)
+		}
+		conn, err := net.DialTimeout("tcp", addr, detectTimeout)
+		if err != nil {
+			continue
+		}
+		conn.Close()
+		for _, path := range paths {
+			ep := (&url.URL{Scheme: u.Scheme, User: u.User, Host: host, Path: path}).String()
+			fams, err := HTTP{URL: ep, Format: format, Client: &probe, MaxBodySize: maxBodySize}.Scrape()
+			if err == nil && len(fams) > 0 {
+				return ep, true
+			}
+		}
+	}
+	return endpoint, false
+}
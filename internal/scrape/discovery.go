@@ -0,0 +1,73 @@
+package scrape
+
+import "strings"
+
+// Discoverer finds endpoints to scrape, such as the targets listed in a
+// file_sd file or behind a DNS SRV record. Tabs call it every interval,
+// adding and removing tabs as endpoints come and go.
+type Discoverer interface {
+	Discover() ([]Target, error)
+	// String describes where endpoints come from, for status lines.
+	String() string
+}
+
+// Target is an endpoint found by a Discoverer.
+type Target struct {
+	Endpoint string
+	// Health is the target's health check status, for discovery
+	// mechanisms that track one, such as Consul.
+	Health string
+}
+
+// Discoverers merges the endpoints found by several Discoverers. If any of
+// them fails, the whole discovery fails, so that tabs aren't dropped because
+// one source couldn't be read.
+type Discoverers []Discoverer
+
+func (ds Discoverers) Discover() ([]Target, error) {
+	var out []Target
+	seen := make(map[string]bool)
+	for _, d := range ds {
+		targets, err := d.Discover()
+		if err != nil {
+			return nil, err
+		}
+		for _, tg := range targets {
+			if !seen[tg.Endpoint] {
+				seen[tg.Endpoint] = true
+				out = append(out, tg)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (ds Discoverers) String() string {
+	names := make([]string, len(ds))
+	for i, d := range ds {
+		names[i] = d.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// FileSD discovers the targets listed in a Prometheus file_sd file.
+type FileSD string
+
+func (f FileSD) Discover() ([]Target, error) {
+	endpoints, err := readFileSD(string(f))
+	return endpointTargets(endpoints), err
+}
+
+func (f FileSD) String() string {
+	return string(f)
+}
+
+// endpointTargets wraps endpoints found by a Discoverer without health
+// checks.
+func endpointTargets(endpoints []string) []Target {
+	out := make([]Target, len(endpoints))
+	for i, ep := range endpoints {
+		out[i] = Target{Endpoint: ep}
+	}
+	return out
+}
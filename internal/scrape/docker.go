@@ -0,0 +1,190 @@
+package scrape
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jaxxstorm/met/pkg/met"
+)
+
+// dockerContainer is the part of an entry from the Docker API's
+// /containers/json endpoint that's needed to find its metrics.
+type dockerContainer struct {
+	Names  []string
+	Image  string
+	Labels map[string]string
+	Ports  []struct {
+		IP          string
+		PrivatePort int
+		PublicPort  int
+		Type        string
+	}
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress string
+		}
+	}
+}
+
+// DockerTarget is a port on a running container that might serve metrics.
+type DockerTarget struct {
+	Container string
+	Image     string
+	Port      int
+	Endpoint  string
+}
+
+// key identifies the target across restarts of its container, which can
+// change its address.
+func (t DockerTarget) Key() string {
+	return t.Container + ":" + strconv.Itoa(t.Port)
+}
+
+// Docker finds metrics endpoints on running containers. Targets
+// are re-listed every interval, so containers that are recreated, as with
+// docker compose up, are followed to their new address.
+type Docker struct {
+	client *http.Client
+	base   string
+	// daemonHost is where published ports are reachable: localhost for a
+	// local daemon, or the remote daemon's host.
+	daemonHost string
+	// Selected limits discovery to these targets, by key, and Containers
+	// to every target on these containers, by name. If both are nil,
+	// every target is scraped.
+	Selected   map[string]bool
+	Containers map[string]bool
+}
+
+// NewDocker discovers the containers of the Docker daemon at host, a
+// unix:// socket or tcp:// address.
+func NewDocker(host string) (*Docker, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("bad Docker host %q: %w", host, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+		return &Docker{client: &http.Client{Transport: transport}, base: "http://docker", daemonHost: "localhost"}, nil
+	case "tcp", "http":
+		return &Docker{client: http.DefaultClient, base: "http://" + u.Host, daemonHost: u.Hostname()}, nil
+	}
+	return nil, fmt.Errorf("unsupported Docker host %q, want unix:// or tcp://", host)
+}
+
+// targets lists the metrics ports of every running container. A container
+// labelled with metrics.port (or prometheus.io/port) has just that port,
+// with the path and scheme from metrics.path and metrics.scheme; otherwise
+// each of its TCP ports is a candidate.
+func (d *Docker) Targets() ([]DockerTarget, error) {
+	resp, err := d.client.Get(d.base + "/containers/json")
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing containers: %w", met.StatusError(resp.StatusCode))
+	}
+	var containers []dockerContainer
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("decoding container list: %w", err)
+	}
+
+	var out []DockerTarget
+	for _, c := range containers {
+		name := c.Image
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		label := func(key, def string) string {
+			for _, prefix := range []string{"metrics.", "prometheus.io/"} {
+				if v := c.Labels[prefix+key]; v != "" {
+					return v
+				}
+			}
+			return def
+		}
+		var ports []int
+		if p, err := strconv.Atoi(label("port", "")); err == nil {
+			ports = []int{p}
+		} else {
+			seen := make(map[int]bool)
+			for _, p := range c.Ports {
+				if p.Type == "tcp" && !seen[p.PrivatePort] {
+					seen[p.PrivatePort] = true
+					ports = append(ports, p.PrivatePort)
+				}
+			}
+		}
+		for _, port := range ports {
+			host := d.address(c, port)
+			if host == "" {
+				continue
+			}
+			ep := (&url.URL{Scheme: label("scheme", "http"), Host: host, Path: label("path", "/metrics")}).String()
+			out = append(out, DockerTarget{Container: name, Image: c.Image, Port: port, Endpoint: ep})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key() < out[j].Key() })
+	return out, nil
+}
+
+// address returns where a container's port can be reached: the published
+// port if there is one, otherwise the container's own address, which only
+// works when met runs on the same host as the daemon.
+func (d *Docker) address(c dockerContainer, port int) string {
+	for _, p := range c.Ports {
+		if p.PrivatePort != port || p.PublicPort == 0 {
+			continue
+		}
+		host := p.IP
+		if host == "" || host == "0.0.0.0" || host == "::" {
+			host = d.daemonHost
+		}
+		return net.JoinHostPort(host, strconv.Itoa(p.PublicPort))
+	}
+	networks := make([]string, 0, len(c.NetworkSettings.Networks))
+	for name := range c.NetworkSettings.Networks {
+		networks = append(networks, name)
+	}
+	sort.Strings(networks)
+	for _, name := range networks {
+		if ip := c.NetworkSettings.Networks[name].IPAddress; ip != "" {
+			return net.JoinHostPort(ip, strconv.Itoa(port))
+		}
+	}
+	return ""
+}
+
+func (d *Docker) Discover() ([]Target, error) {
+	targets, err := d.Targets()
+	if err != nil {
+		return nil, err
+	}
+	var out []Target
+	for _, t := range targets {
+		all := d.Selected == nil && d.Containers == nil
+		if all || d.Selected[t.Key()] || d.Containers[t.Container] {
+			out = append(out, Target{Endpoint: t.Endpoint})
+		}
+	}
+	return out, nil
+}
+
+func (d *Docker) String() string {
+	return "Docker containers"
+}
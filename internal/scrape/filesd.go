@@ -0,0 +1,50 @@
+package scrape
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileSDGroup is one target group of a Prometheus file_sd file.
+type fileSDGroup struct {
+	Targets []string          `yaml:"targets"`
+	Labels  map[string]string `yaml:"labels"`
+}
+
+// readFileSD returns the endpoint URL of every target in a file_sd file, in
+// the JSON or YAML format Prometheus uses. The __scheme__ and
+// __metrics_path__ labels are honoured; other labels are ignored.
+func readFileSD(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// YAML is a superset of JSON, so this reads both formats.
+	var groups []fileSDGroup
+	if err := yaml.Unmarshal(b, &groups); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	var endpoints []string
+	seen := make(map[string]bool)
+	for _, g := range groups {
+		scheme := g.Labels["__scheme__"]
+		if scheme == "" {
+			scheme = "http"
+		}
+		metricsPath := g.Labels["__metrics_path__"]
+		if metricsPath == "" {
+			metricsPath = "/metrics"
+		}
+		for _, target := range g.Targets {
+			ep := (&url.URL{Scheme: scheme, Host: target, Path: metricsPath}).String()
+			if !seen[ep] {
+				seen[ep] = true
+				endpoints = append(endpoints, ep)
+			}
+		}
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,115 @@
+package scrape
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/jaxxstorm/met/pkg/met"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// Graphite polls a Graphite server's render API and exposes the most
+// recent non-null datapoint of every returned series.
+type Graphite struct {
+	BaseURL string
+	Targets []string
+	From    string
+	Client  *http.Client
+}
+
+type graphiteSeries struct {
+	Target     string            `json:"target"`
+	Tags       map[string]string `json:"tags"`
+	Datapoints [][2]*float64     `json:"datapoints"`
+}
+
+func (g Graphite) String() string {
+	return fmt.Sprintf("%s (%s)", g.BaseURL, strings.Join(g.Targets, ", "))
+}
+
+func (g Graphite) renderURL() (string, error) {
+	u, err := url.Parse(g.BaseURL)
+	if err != nil {
+		return "", err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/render"
+	q := url.Values{}
+	q.Set("format", "json")
+	q.Set("from", g.From)
+	for _, t := range g.Targets {
+		q.Add("target", t)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func (g Graphite) Scrape() (map[string]*dto.MetricFamily, error) {
+	renderURL, err := g.renderURL()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(context.Background(), "GET", renderURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got status %d from server", resp.StatusCode)
+	}
+	var series []graphiteSeries
+	if err := json.NewDecoder(resp.Body).Decode(&series); err != nil {
+		return nil, fmt.Errorf("decoding render response: %w", err)
+	}
+
+	fams := make(map[string]*dto.MetricFamily)
+	for _, s := range series {
+		v, ok := lastDatapoint(s.Datapoints)
+		if !ok {
+			continue
+		}
+		// Tagged series carry their name in the "name" tag; plain ones
+		// are identified by the target path alone.
+		name := s.Target
+		if n, ok := s.Tags["name"]; ok {
+			name = n
+		}
+		name = met.SanitizeName(name)
+		var lbls []*dto.LabelPair
+		tagNames := make([]string, 0, len(s.Tags))
+		for k := range s.Tags {
+			if k != "name" {
+				tagNames = append(tagNames, k)
+			}
+		}
+		sort.Strings(tagNames)
+		for _, k := range tagNames {
+			lbls = append(lbls, met.LabelPair(met.SanitizeName(k), s.Tags[k]))
+		}
+		mf, ok := fams[name]
+		if !ok {
+			mf = &dto.MetricFamily{Name: proto.String(name), Type: dto.MetricType_GAUGE.Enum()}
+			fams[name] = mf
+		}
+		mf.Metric = append(mf.Metric, &dto.Metric{Label: lbls, Gauge: &dto.Gauge{Value: proto.Float64(v)}})
+	}
+	return fams, nil
+}
+
+func lastDatapoint(points [][2]*float64) (float64, bool) {
+	for i := len(points) - 1; i >= 0; i-- {
+		if points[i][0] != nil {
+			return *points[i][0], true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,157 @@
+package scrape
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaxxstorm/met/pkg/met"
+)
+
+// oauth2ExpiryMargin is how long before a token expires it's replaced, so
+// a scrape doesn't race its expiry.
+const oauth2ExpiryMargin = 30 * time.Second
+
+// oauth2Transport authenticates requests with a bearer token obtained with
+// the OAuth2 client credentials grant, fetching a new token once the last
+// one expires or is rejected.
+type oauth2Transport struct {
+	base         http.RoundTripper
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewOAuth2Transport returns a transport authenticating requests made
+// with base with tokens from tokenURL.
+func NewOAuth2Transport(base http.RoundTripper, tokenURL, clientID, clientSecret string, scopes []string) (http.RoundTripper, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, errors.New("--oauth2-token-url needs --oauth2-client-id and --oauth2-client-secret")
+	}
+	if _, err := url.Parse(tokenURL); err != nil {
+		return nil, fmt.Errorf("parsing OAuth2 token URL: %w", err)
+	}
+	return &oauth2Transport{base: base, tokenURL: tokenURL, clientID: clientID, clientSecret: clientSecret, scopes: scopes}, nil
+}
+
+func (t *oauth2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(withBearer(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	// The token may have been revoked before it expired, so get a new one
+	// and try again, if the request can be sent twice.
+	t.invalidate(token)
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+	if token, err = t.currentToken(); err != nil {
+		return resp, nil
+	}
+	retry := withBearer(req, token)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	return t.base.RoundTrip(retry)
+}
+
+// withBearer clones req with token in its Authorization header, as
+// RoundTrippers mustn't modify the requests they're given.
+func withBearer(req *http.Request, token string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func (t *oauth2Transport) invalidate(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token == token {
+		t.token = ""
+	}
+}
+
+// currentToken returns the cached token, fetching a new one if there isn't
+// one or it's about to expire.
+func (t *oauth2Transport) currentToken() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && (t.expiry.IsZero() || time.Until(t.expiry) > oauth2ExpiryMargin) {
+		return t.token, nil
+	}
+	token, expiry, err := t.fetchToken()
+	if err != nil {
+		return "", fmt.Errorf("getting OAuth2 token: %w", err)
+	}
+	t.token, t.expiry = token, expiry
+	return token, nil
+}
+
+func (t *oauth2Transport) fetchToken() (string, time.Time, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(t.scopes) > 0 {
+		form.Set("scope", strings.Join(t.scopes, " "))
+	}
+	req, err := http.NewRequest(http.MethodPost, t.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(t.clientID), url.QueryEscape(t.clientSecret))
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	var tok struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	jsonErr := json.Unmarshal(body, &tok)
+	if resp.StatusCode != http.StatusOK {
+		if tok.Error != "" {
+			return "", time.Time{}, fmt.Errorf("%w: %s %s", met.StatusError(resp.StatusCode), tok.Error, tok.ErrorDescription)
+		}
+		return "", time.Time{}, met.StatusError(resp.StatusCode)
+	}
+	if jsonErr != nil {
+		return "", time.Time{}, fmt.Errorf("decoding token response: %w", jsonErr)
+	}
+	if tok.AccessToken == "" {
+		return "", time.Time{}, errors.New("token response has no access_token")
+	}
+	if tok.TokenType != "" && !strings.EqualFold(tok.TokenType, "bearer") {
+		return "", time.Time{}, fmt.Errorf("unsupported token type %q", tok.TokenType)
+	}
+	var expiry time.Time
+	if tok.ExpiresIn > 0 {
+		expiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	}
+	return tok.AccessToken, expiry, nil
+}
@@ -0,0 +1,239 @@
+package scrape
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/jaxxstorm/met/pkg/met"
+	dto "github.com/prometheus/client_model/go"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// OTLPReceiver accepts OTLP/HTTP metric exports and keeps the latest value
+// of every data point, mapped onto the Prometheus data model, so it can be
+// polled like a scrape target.
+type OTLPReceiver struct {
+	pushStore
+	addr string
+	// maxBodySize caps the decompressed request body, if non-zero.
+	maxBodySize int64
+}
+
+// ListenOTLP starts receiving OTLP/HTTP exports on addr, at /v1/metrics,
+// logging to logger if the server stops.
+func ListenOTLP(addr string, maxBodySize int64, logger *slog.Logger) (*OTLPReceiver, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	r := &OTLPReceiver{addr: ln.Addr().String(), maxBodySize: maxBodySize}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/metrics", r.handle)
+	go func() {
+		if err := http.Serve(ln, mux); err != nil {
+			logger.Error("OTLP receiver stopped", "err", err)
+		}
+	}()
+	return r, nil
+}
+
+func (r *OTLPReceiver) String() string {
+	return "otlp://" + r.addr
+}
+
+func (r *OTLPReceiver) handle(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := met.DecodeBody(req.Body, req.Header.Get("Content-Encoding"), r.maxBodySize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b, err := io.ReadAll(body)
+	var tooLarge *met.BodyTooLargeError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, MaxBodyError(err).Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// MetricsData is wire compatible with ExportMetricsServiceRequest, which
+	// saves depending on the gRPC collector packages.
+	var data metricspb.MetricsData
+	ct, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	isJSON := ct == "application/json"
+	if isJSON {
+		err = protojson.Unmarshal(b, &data)
+	} else {
+		err = proto.Unmarshal(b, &data)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.ingest(&data)
+
+	if isJSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *OTLPReceiver) ingest(data *metricspb.MetricsData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rm := range data.GetResourceMetrics() {
+		var resLabels []*dto.LabelPair
+		for _, kv := range rm.GetResource().GetAttributes() {
+			switch kv.GetKey() {
+			case "service.name":
+				resLabels = append(resLabels, met.LabelPair("job", anyValueString(kv.GetValue())))
+			case "service.instance.id":
+				resLabels = append(resLabels, met.LabelPair("instance", anyValueString(kv.GetValue())))
+			}
+		}
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, om := range sm.GetMetrics() {
+				r.ingestMetric(om, resLabels)
+			}
+		}
+	}
+}
+
+func (r *OTLPReceiver) ingestMetric(om *metricspb.Metric, resLabels []*dto.LabelPair) {
+	name := met.SanitizeName(om.GetName())
+	help := om.GetDescription()
+	switch {
+	case om.GetGauge() != nil:
+		for _, dp := range om.GetGauge().GetDataPoints() {
+			s := r.get(name, help, dto.MetricType_GAUGE, resLabels, dp.GetAttributes())
+			s.metric.Gauge = &dto.Gauge{Value: proto.Float64(numberValue(dp))}
+		}
+	case om.GetSum() != nil:
+		sum := om.GetSum()
+		delta := sum.GetAggregationTemporality() == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+		typ := dto.MetricType_GAUGE
+		if sum.GetIsMonotonic() {
+			typ = dto.MetricType_COUNTER
+		}
+		for _, dp := range sum.GetDataPoints() {
+			s := r.get(name, help, typ, resLabels, dp.GetAttributes())
+			v := numberValue(dp)
+			if typ == dto.MetricType_COUNTER {
+				if delta {
+					v += s.metric.GetCounter().GetValue()
+				}
+				s.metric.Counter = &dto.Counter{Value: proto.Float64(v)}
+			} else {
+				if delta {
+					v += s.metric.GetGauge().GetValue()
+				}
+				s.metric.Gauge = &dto.Gauge{Value: proto.Float64(v)}
+			}
+		}
+	case om.GetHistogram() != nil:
+		h := om.GetHistogram()
+		delta := h.GetAggregationTemporality() == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+		for _, dp := range h.GetDataPoints() {
+			s := r.get(name, help, dto.MetricType_HISTOGRAM, resLabels, dp.GetAttributes())
+			var prev *dto.Histogram
+			if delta {
+				prev = s.metric.GetHistogram()
+			}
+			s.metric.Histogram = otlpHistogram(dp, prev)
+		}
+	case om.GetExponentialHistogram() != nil:
+		// Exponential buckets have no Prometheus text equivalent, so only
+		// the count and sum are kept.
+		eh := om.GetExponentialHistogram()
+		delta := eh.GetAggregationTemporality() == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+		for _, dp := range eh.GetDataPoints() {
+			s := r.get(name, help, dto.MetricType_HISTOGRAM, resLabels, dp.GetAttributes())
+			count, sum := dp.GetCount(), dp.GetSum()
+			if delta {
+				count += s.metric.GetHistogram().GetSampleCount()
+				sum += s.metric.GetHistogram().GetSampleSum()
+			}
+			s.metric.Histogram = &dto.Histogram{SampleCount: proto.Uint64(count), SampleSum: proto.Float64(sum)}
+		}
+	case om.GetSummary() != nil:
+		for _, dp := range om.GetSummary().GetDataPoints() {
+			s := r.get(name, help, dto.MetricType_SUMMARY, resLabels, dp.GetAttributes())
+			summary := &dto.Summary{SampleCount: proto.Uint64(dp.GetCount()), SampleSum: proto.Float64(dp.GetSum())}
+			for _, q := range dp.GetQuantileValues() {
+				summary.Quantile = append(summary.Quantile, &dto.Quantile{Quantile: proto.Float64(q.GetQuantile()), Value: proto.Float64(q.GetValue())})
+			}
+			s.metric.Summary = summary
+		}
+	}
+}
+
+// otlpHistogram converts OTLP's per-bucket counts into Prometheus
+// cumulative buckets, adding them onto prev for delta temporality.
+func otlpHistogram(dp *metricspb.HistogramDataPoint, prev *dto.Histogram) *dto.Histogram {
+	h := &dto.Histogram{SampleCount: proto.Uint64(dp.GetCount()), SampleSum: proto.Float64(dp.GetSum())}
+	var cum uint64
+	for i, bound := range dp.GetExplicitBounds() {
+		if i < len(dp.GetBucketCounts()) {
+			cum += dp.GetBucketCounts()[i]
+		}
+		h.Bucket = append(h.Bucket, &dto.Bucket{UpperBound: proto.Float64(bound), CumulativeCount: proto.Uint64(cum)})
+	}
+	if prev != nil && len(prev.GetBucket()) == len(h.Bucket) {
+		*h.SampleCount += prev.GetSampleCount()
+		*h.SampleSum += prev.GetSampleSum()
+		for i, b := range h.Bucket {
+			*b.CumulativeCount += prev.GetBucket()[i].GetCumulativeCount()
+		}
+	}
+	return h
+}
+
+// get finds or creates the series for a data point. Callers hold r.mu.
+func (r *OTLPReceiver) get(name, help string, typ dto.MetricType, resLabels []*dto.LabelPair, attrs []*commonpb.KeyValue) *pushedSeries {
+	lbls := append([]*dto.LabelPair(nil), resLabels...)
+	for _, kv := range attrs {
+		lbls = append(lbls, met.LabelPair(met.SanitizeName(kv.GetKey()), anyValueString(kv.GetValue())))
+	}
+	return r.series(name, help, typ, lbls)
+}
+
+func numberValue(dp *metricspb.NumberDataPoint) float64 {
+	if v, ok := dp.GetValue().(*metricspb.NumberDataPoint_AsInt); ok {
+		return float64(v.AsInt)
+	}
+	return dp.GetAsDouble()
+}
+
+func anyValueString(v *commonpb.AnyValue) string {
+	switch x := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return x.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(x.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(x.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(x.DoubleValue, 'g', -1, 64)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,195 @@
+package scrape
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpExport wraps metrics in a single resource for the checkout service.
+func otlpExport(metrics ...*metricspb.Metric) *metricspb.MetricsData {
+	return &metricspb.MetricsData{ResourceMetrics: []*metricspb.ResourceMetrics{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "checkout"}}},
+		}},
+		ScopeMetrics: []*metricspb.ScopeMetrics{{Metrics: metrics}},
+	}}}
+}
+
+func otlpSum(name string, temporality metricspb.AggregationTemporality, monotonic bool, v int64) *metricspb.Metric {
+	return &metricspb.Metric{Name: name, Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+		AggregationTemporality: temporality,
+		IsMonotonic:            monotonic,
+		DataPoints: []*metricspb.NumberDataPoint{{
+			Attributes: []*commonpb.KeyValue{
+				{Key: "http.method", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "GET"}}},
+			},
+			Value: &metricspb.NumberDataPoint_AsInt{AsInt: v},
+		}},
+	}}}
+}
+
+func TestOTLPIngest(t *testing.T) {
+	cumulative := metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	delta := metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	tests := []struct {
+		name    string
+		exports []*metricspb.MetricsData
+		metric  string
+		labels  string
+		want    float64
+	}{
+		{
+			name: "gauge",
+			exports: []*metricspb.MetricsData{otlpExport(&metricspb.Metric{Name: "queue.depth", Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+				DataPoints: []*metricspb.NumberDataPoint{{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 2.5}}},
+			}}})},
+			metric: "queue_depth",
+			labels: `job="checkout"`,
+			want:   2.5,
+		},
+		{
+			name:    "cumulative sum",
+			exports: []*metricspb.MetricsData{otlpExport(otlpSum("requests", cumulative, true, 5)), otlpExport(otlpSum("requests", cumulative, true, 7))},
+			metric:  "requests",
+			labels:  `http_method="GET",job="checkout"`,
+			want:    7,
+		},
+		{
+			name:    "delta sums are added up",
+			exports: []*metricspb.MetricsData{otlpExport(otlpSum("requests", delta, true, 5)), otlpExport(otlpSum("requests", delta, true, 7))},
+			metric:  "requests",
+			labels:  `http_method="GET",job="checkout"`,
+			want:    12,
+		},
+		{
+			name:    "non-monotonic sum",
+			exports: []*metricspb.MetricsData{otlpExport(otlpSum("in_flight", cumulative, false, 3))},
+			metric:  "in_flight",
+			labels:  `http_method="GET",job="checkout"`,
+			want:    3,
+		},
+		{
+			name: "summary count",
+			exports: []*metricspb.MetricsData{otlpExport(&metricspb.Metric{Name: "latency", Data: &metricspb.Metric_Summary{Summary: &metricspb.Summary{
+				DataPoints: []*metricspb.SummaryDataPoint{{Count: 4, Sum: 1}},
+			}}})},
+			metric: "latency",
+			labels: `job="checkout"`,
+			want:   4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r OTLPReceiver
+			for _, data := range tt.exports {
+				r.ingest(data)
+			}
+			fams, err := r.Scrape()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := seriesValue(t, fams, tt.metric, tt.labels); got != tt.want {
+				t.Errorf("%s{%s} = %v, want %v", tt.metric, tt.labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOTLPHistogram(t *testing.T) {
+	export := func() *metricspb.MetricsData {
+		return otlpExport(&metricspb.Metric{Name: "latency", Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+			DataPoints: []*metricspb.HistogramDataPoint{{
+				Count:          6,
+				Sum:            proto.Float64(3),
+				ExplicitBounds: []float64{0.1, 1},
+				BucketCounts:   []uint64{1, 2, 3},
+			}},
+		}}})
+	}
+	var r OTLPReceiver
+	r.ingest(export())
+	r.ingest(export())
+	fams, err := r.Scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := fams["latency"].GetMetric()[0].GetHistogram()
+	if h.GetSampleCount() != 12 || h.GetSampleSum() != 6 {
+		t.Errorf("count, sum = %d, %v, want 12, 6", h.GetSampleCount(), h.GetSampleSum())
+	}
+	// OTLP counts each bucket on its own; Prometheus buckets are
+	// cumulative.
+	want := []uint64{2, 6}
+	if len(h.GetBucket()) != len(want) {
+		t.Fatalf("buckets = %v, want counts %v", h.GetBucket(), want)
+	}
+	for i, b := range h.GetBucket() {
+		if b.GetCumulativeCount() != want[i] {
+			t.Errorf("bucket le=%v = %d, want %d", b.GetUpperBound(), b.GetCumulativeCount(), want[i])
+		}
+	}
+}
+
+func TestOTLPHandle(t *testing.T) {
+	data := otlpExport(otlpSum("requests", metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, true, 5))
+	pb, err := proto.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := protojson.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        []byte
+		maxBodySize int64
+		wantStatus  int
+	}{
+		{"protobuf", http.MethodPost, "application/x-protobuf", pb, 0, http.StatusOK},
+		{"json", http.MethodPost, "application/json", js, 0, http.StatusOK},
+		{"not a post", http.MethodGet, "application/x-protobuf", nil, 0, http.StatusMethodNotAllowed},
+		{"malformed", http.MethodPost, "application/json", []byte("{"), 0, http.StatusBadRequest},
+		{"too large", http.MethodPost, "application/x-protobuf", pb, 4, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &OTLPReceiver{maxBodySize: tt.maxBodySize}
+			req := httptest.NewRequest(tt.method, "/v1/metrics", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			r.handle(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("response Content-Type = %q, want %q", ct, tt.contentType)
+			}
+			fams, err := r.Scrape()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mf := fams["requests"]; mf.GetType() != dto.MetricType_COUNTER {
+				t.Errorf("requests is a %v, want a counter", mf.GetType())
+			}
+			if got := seriesValue(t, fams, "requests", `http_method="GET",job="checkout"`); got != 5 {
+				t.Errorf("requests = %v, want 5", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package scrape
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// userHZ is the unit of the CPU times in /proc/<pid>/stat. It's 100 on
+// practically every Linux system, and can't be read without cgo.
+const userHZ = 100
+
+// procProbeTimeout is how long each candidate port has to answer.
+const procProbeTimeout = 2 * time.Second
+
+// procListenFlag matches the ports in command line arguments that look like
+// they set a listen address, such as --web.listen-address=:9100 or
+// -metrics-addr localhost:8080.
+var procListenFlag = regexp.MustCompile(`(?i)(listen|metrics|addr|port|http)`)
+
+var procPort = regexp.MustCompile(`(?:^|[:=])(\d{2,5})$`)
+
+// FindProcEndpoint looks for a metrics endpoint served by pid, on the ports
+// it's listening on or named in its command line, trying the Prometheus
+// format at /metrics and then Go's expvar at /debug/vars.
+func FindProcEndpoint(client *http.Client, pid int) (endpoint, format string, err error) {
+	addrs, err := procListenAddrs(pid)
+	if err != nil {
+		return "", "", err
+	}
+	probe := *client
+	probe.Timeout = procProbeTimeout
+	for _, addr := range addrs {
+		for _, try := range []struct{ path, format string }{{"/metrics", "prometheus"}, {"/debug/vars", "expvar"}} {
+			ep := (&url.URL{Scheme: "http", Host: addr, Path: try.path}).String()
+			fams, err := HTTP{URL: ep, Format: try.format, Client: &probe}.Scrape()
+			if err == nil && len(fams) > 0 {
+				return ep, try.format, nil
+			}
+		}
+	}
+	return "", "", nil
+}
+
+// procListenAddrs lists the addresses pid is listening on, followed by any
+// other ports named in its command line.
+func procListenAddrs(pid int) ([]string, error) {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("process %d: %w", pid, err)
+	}
+	inodes := make(map[string]bool)
+	fds, _ := os.ReadDir(filepath.Join(dir, "fd"))
+	for _, fd := range fds {
+		link, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+		if err == nil && strings.HasPrefix(link, "socket:[") {
+			inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] = true
+		}
+	}
+	var addrs []string
+	seen := make(map[string]bool)
+	add := func(addr string) {
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	for _, table := range []string{"tcp", "tcp6"} {
+		listening, err := readListening(filepath.Join(dir, "net", table), inodes)
+		if err != nil {
+			continue
+		}
+		for _, addr := range listening {
+			add(addr)
+		}
+	}
+	cmdline, _ := os.ReadFile(filepath.Join(dir, "cmdline"))
+	args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+	for i, arg := range args {
+		if !procListenFlag.MatchString(arg) {
+			continue
+		}
+		// The value is either part of the flag or the next argument.
+		for _, v := range []string{arg, argAfter(args, i)} {
+			if m := procPort.FindStringSubmatch(v); m != nil {
+				add(net.JoinHostPort("localhost", m[1]))
+			}
+		}
+	}
+	return addrs, nil
+}
+
+func argAfter(args []string, i int) string {
+	if i+1 < len(args) {
+		return args[i+1]
+	}
+	return ""
+}
+
+// readListening returns the addresses of the listening sockets in a
+// /proc/net/tcp style table whose inodes are in inodes, ordered by port.
+func readListening(path string, inodes map[string]bool) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	type listener struct {
+		addr string
+		port int
+	}
+	var ls []listener
+	sc := bufio.NewScanner(f)
+	sc.Scan() // header
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		if len(fields) < 10 || fields[3] != "0A" || !inodes[fields[9]] {
+			continue
+		}
+		hostHex, portHex, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		port, err := strconv.ParseUint(portHex, 16, 16)
+		if err != nil {
+			continue
+		}
+		ip, err := procIP(hostHex)
+		if err != nil {
+			continue
+		}
+		host := ip.String()
+		if ip.IsUnspecified() {
+			host = "localhost"
+		}
+		ls = append(ls, listener{addr: net.JoinHostPort(host, strconv.Itoa(int(port))), port: int(port)})
+	}
+	sort.Slice(ls, func(i, j int) bool { return ls[i].port < ls[j].port })
+	addrs := make([]string, len(ls))
+	for i, l := range ls {
+		addrs[i] = l.addr
+	}
+	return addrs, sc.Err()
+}
+
+// procIP decodes an address from /proc/net/tcp, which is written as
+// 32-bit words in host (little-endian) byte order.
+func procIP(s string) (net.IP, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+		return nil, fmt.Errorf("bad address %q", s)
+	}
+	ip := make(net.IP, len(b))
+	for i := 0; i < len(b); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+	return ip, nil
+}
+
+// ProcSource collects the standard process metrics for the process PID
+// from /proc, for processes that don't serve any of their own.
+type ProcSource struct {
+	PID int
+}
+
+func (s ProcSource) String() string {
+	return fmt.Sprintf("process %d", s.PID)
+}
+
+func (s ProcSource) Scrape() (map[string]*dto.MetricFamily, error) {
+	dir := filepath.Join("/proc", strconv.Itoa(s.PID))
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, fmt.Errorf("reading process %d: %w", s.PID, err)
+	}
+	// The command name is in parentheses and can contain spaces, so the
+	// fields are counted from the last closing one.
+	i := strings.LastIndexByte(string(stat), ')')
+	if i < 0 {
+		return nil, fmt.Errorf("parsing %s/stat", dir)
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("parsing %s/stat", dir)
+	}
+	// fields[0] is the process state, the third field in proc(5).
+	field := func(n int) float64 {
+		v, _ := strconv.ParseFloat(fields[n-3], 64)
+		return v
+	}
+
+	fams := make(map[string]*dto.MetricFamily)
+	add := func(name, help string, typ dto.MetricType, v float64) {
+		m := &dto.Metric{}
+		if typ == dto.MetricType_COUNTER {
+			m.Counter = &dto.Counter{Value: proto.Float64(v)}
+		} else {
+			m.Gauge = &dto.Gauge{Value: proto.Float64(v)}
+		}
+		fams[name] = &dto.MetricFamily{Name: proto.String(name), Help: proto.String(help), Type: typ.Enum(), Metric: []*dto.Metric{m}}
+	}
+	add("process_cpu_seconds_total", "Total user and system CPU time spent in seconds.", dto.MetricType_COUNTER, (field(14)+field(15))/userHZ)
+	add("process_resident_memory_bytes", "Resident memory size in bytes.", dto.MetricType_GAUGE, field(24)*float64(os.Getpagesize()))
+	add("process_virtual_memory_bytes", "Virtual memory size in bytes.", dto.MetricType_GAUGE, field(23))
+	add("process_threads", "Number of OS threads in the process.", dto.MetricType_GAUGE, field(20))
+	if boot, err := bootTime(); err == nil {
+		add("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", dto.MetricType_GAUGE, boot+field(22)/userHZ)
+	}
+	if fds, err := os.ReadDir(filepath.Join(dir, "fd")); err == nil {
+		add("process_open_fds", "Number of open file descriptors.", dto.MetricType_GAUGE, float64(len(fds)))
+	}
+	if limit, err := maxFDs(dir); err == nil {
+		add("process_max_fds", "Maximum number of open file descriptors.", dto.MetricType_GAUGE, limit)
+	}
+	return fams, nil
+}
+
+// bootTime reads when the system booted, which process start times in
+// /proc/<pid>/stat are relative to.
+func bootTime() (float64, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "btime "); ok {
+			return strconv.ParseFloat(strings.TrimSpace(v), 64)
+		}
+	}
+	return 0, fmt.Errorf("no btime in /proc/stat")
+}
+
+// maxFDs reads the soft limit on the process's open files.
+func maxFDs(dir string) (float64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "limits"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "Max open files"); ok {
+			fields := strings.Fields(v)
+			if len(fields) == 0 || fields[0] == "unlimited" {
+				break
+			}
+			return strconv.ParseFloat(fields[0], 64)
+		}
+	}
+	return 0, fmt.Errorf("no open files limit in %s/limits", dir)
+}
@@ -0,0 +1,87 @@
+package scrape
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// pushStore holds the latest state of series pushed to met by a receiver
+// such as OTLP or StatsD, and serves it as a source like a scrape target.
+type pushStore struct {
+	mu   sync.Mutex
+	byID map[string]*pushedSeries
+}
+
+type pushedSeries struct {
+	name   string
+	help   string
+	typ    dto.MetricType
+	metric *dto.Metric
+}
+
+// series finds or creates a series. Callers hold p.mu.
+func (p *pushStore) series(name, help string, typ dto.MetricType, lbls []*dto.LabelPair) *pushedSeries {
+	if p.byID == nil {
+		p.byID = make(map[string]*pushedSeries)
+	}
+	key := seriesKey(name, lbls)
+	s, ok := p.byID[key]
+	if !ok || s.typ != typ {
+		s = &pushedSeries{name: name, help: help, typ: typ, metric: &dto.Metric{Label: lbls}}
+		p.byID[key] = s
+	}
+	return s
+}
+
+// Scrape returns a copy of the series pushed so far.
+func (p *pushStore) Scrape() (map[string]*dto.MetricFamily, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.snapshot(), nil
+}
+
+// snapshot copies the series into metric families. Callers hold p.mu.
+func (p *pushStore) snapshot() map[string]*dto.MetricFamily {
+	fams := make(map[string]*dto.MetricFamily)
+	keys := make([]string, 0, len(p.byID))
+	for k := range p.byID {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := p.byID[k]
+		mf, ok := fams[s.name]
+		if !ok {
+			mf = &dto.MetricFamily{Name: proto.String(s.name), Help: proto.String(s.help), Type: s.typ.Enum()}
+			fams[s.name] = mf
+		}
+		mf.Metric = append(mf.Metric, proto.Clone(s.metric).(*dto.Metric))
+	}
+	return fams
+}
+
+// seriesKey sorts lbls by name and identifies the series name{lbls} by
+// them.
+func seriesKey(name string, lbls []*dto.LabelPair) string {
+	sort.Slice(lbls, func(i, j int) bool {
+		return lbls[i].GetName() < lbls[j].GetName()
+	})
+	var sb strings.Builder
+	sb.WriteString(name)
+	sb.WriteByte('{')
+	for i, lp := range lbls {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(lp.GetName())
+		sb.WriteString(`="`)
+		sb.WriteString(lp.GetValue())
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
@@ -0,0 +1,175 @@
+package scrape
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jaxxstorm/met/pkg/met"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// RelabelConfig is a Prometheus-style relabel_config from the config file.
+// Optional fields are pointers so an explicitly empty value can be told
+// apart from a missing one.
+type RelabelConfig struct {
+	SourceLabels []string `json:"source_labels,omitempty"`
+	Separator    *string  `json:"separator,omitempty"`
+	Regex        *string  `json:"regex,omitempty"`
+	TargetLabel  string   `json:"target_label,omitempty"`
+	Replacement  *string  `json:"replacement,omitempty"`
+	Action       string   `json:"action,omitempty"`
+}
+
+// RelabelRule is a RelabelConfig with its defaults filled in and its regex
+// compiled.
+type RelabelRule struct {
+	sourceLabels []string
+	separator    string
+	re           *regexp.Regexp
+	targetLabel  string
+	replacement  string
+	action       string
+}
+
+// CompileRelabelConfigs checks the relabel configs from the config file
+// and fills in their defaults.
+func CompileRelabelConfigs(cfgs []RelabelConfig) ([]RelabelRule, error) {
+	rules := make([]RelabelRule, 0, len(cfgs))
+	for i, c := range cfgs {
+		r := RelabelRule{
+			sourceLabels: c.SourceLabels,
+			separator:    ";",
+			targetLabel:  c.TargetLabel,
+			replacement:  "$1",
+			action:       strings.ToLower(c.Action),
+		}
+		if c.Separator != nil {
+			r.separator = *c.Separator
+		}
+		if c.Replacement != nil {
+			r.replacement = *c.Replacement
+		}
+		if r.action == "" {
+			r.action = "replace"
+		}
+		regex := "(.*)"
+		if c.Regex != nil {
+			regex = *c.Regex
+		}
+		re, err := regexp.Compile("^(?:" + regex + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("relabel_configs[%d]: %w", i, err)
+		}
+		r.re = re
+		switch r.action {
+		case "replace", "lowercase", "uppercase":
+			if r.targetLabel == "" {
+				return nil, fmt.Errorf("relabel_configs[%d]: %s needs a target_label", i, r.action)
+			}
+		case "keep", "drop", "labeldrop", "labelkeep", "labelmap":
+		default:
+			return nil, fmt.Errorf("relabel_configs[%d]: unsupported action %q", i, c.Action)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// apply rewrites lbls, which includes the metric name as __name__, and
+// reports whether the series should be kept.
+func (r RelabelRule) apply(lbls map[string]string) bool {
+	values := make([]string, len(r.sourceLabels))
+	for i, name := range r.sourceLabels {
+		values[i] = lbls[name]
+	}
+	value := strings.Join(values, r.separator)
+	switch r.action {
+	case "keep":
+		return r.re.MatchString(value)
+	case "drop":
+		return !r.re.MatchString(value)
+	case "replace":
+		idx := r.re.FindStringSubmatchIndex(value)
+		if idx == nil {
+			return true
+		}
+		target := string(r.re.ExpandString(nil, r.targetLabel, value, idx))
+		res := string(r.re.ExpandString(nil, r.replacement, value, idx))
+		if res == "" {
+			delete(lbls, target)
+		} else {
+			lbls[target] = res
+		}
+	case "lowercase":
+		lbls[r.targetLabel] = strings.ToLower(value)
+	case "uppercase":
+		lbls[r.targetLabel] = strings.ToUpper(value)
+	case "labeldrop", "labelkeep":
+		for name := range lbls {
+			if name != "__name__" && r.re.MatchString(name) == (r.action == "labeldrop") {
+				delete(lbls, name)
+			}
+		}
+	case "labelmap":
+		// Adding to lbls while ranging over it may or may not visit the
+		// new labels, so the mapped ones are added afterwards.
+		mapped := make(map[string]string)
+		for name, v := range lbls {
+			if r.re.MatchString(name) {
+				mapped[r.re.ReplaceAllString(name, r.replacement)] = v
+			}
+		}
+		maps.Copy(lbls, mapped)
+	}
+	return true
+}
+
+// RelabelFamilies applies rules to every series, before their keys are
+// built. Series can be dropped, have labels rewritten or, by setting
+// __name__, move to another family. As in Prometheus, labels starting with
+// __ are removed afterwards.
+func RelabelFamilies(fams map[string]*dto.MetricFamily, rules []RelabelRule) map[string]*dto.MetricFamily {
+	if len(rules) == 0 {
+		return fams
+	}
+	out := make(map[string]*dto.MetricFamily, len(fams))
+	for name, mf := range fams {
+	series:
+		for _, pm := range mf.Metric {
+			lbls := make(map[string]string, len(pm.Label)+1)
+			for _, lp := range pm.Label {
+				lbls[lp.GetName()] = lp.GetValue()
+			}
+			lbls["__name__"] = name
+			for _, r := range rules {
+				if !r.apply(lbls) {
+					continue series
+				}
+			}
+			newName := lbls["__name__"]
+			if newName == "" {
+				continue
+			}
+			var pairs []*dto.LabelPair
+			for k, v := range lbls {
+				if !strings.HasPrefix(k, "__") && v != "" {
+					pairs = append(pairs, met.LabelPair(k, v))
+				}
+			}
+			sort.Slice(pairs, func(i, j int) bool { return pairs[i].GetName() < pairs[j].GetName() })
+			npm := proto.Clone(pm).(*dto.Metric)
+			npm.Label = pairs
+			nmf, ok := out[newName]
+			if !ok {
+				nmf = &dto.MetricFamily{Name: proto.String(newName), Help: mf.Help, Type: mf.Type, Unit: mf.Unit}
+				out[newName] = nmf
+			}
+			nmf.Metric = append(nmf.Metric, npm)
+		}
+	}
+	return out
+}
@@ -0,0 +1,164 @@
+package scrape
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/jaxxstorm/met/pkg/met"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestRelabelRuleApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      RelabelConfig
+		lbls     map[string]string
+		want     map[string]string
+		wantKeep bool
+	}{
+		{
+			name:     "labelmap",
+			cfg:      RelabelConfig{Action: "labelmap", Regex: ptr("__meta_(.+)")},
+			lbls:     map[string]string{"__name__": "up", "__meta_zone": "eu", "job": "node"},
+			want:     map[string]string{"__name__": "up", "__meta_zone": "eu", "zone": "eu", "job": "node"},
+			wantKeep: true,
+		},
+		{
+			// The mapped label matches the regex too, but isn't mapped
+			// again.
+			name:     "labelmap onto a matching name",
+			cfg:      RelabelConfig{Action: "labelmap", Regex: ptr("a(.*)"), Replacement: ptr("aa$1")},
+			lbls:     map[string]string{"a": "1"},
+			want:     map[string]string{"a": "1", "aa": "1"},
+			wantKeep: true,
+		},
+		{
+			name:     "labeldrop",
+			cfg:      RelabelConfig{Action: "labeldrop", Regex: ptr("pod_.*")},
+			lbls:     map[string]string{"__name__": "up", "pod_hash": "abc", "pod_name": "x", "job": "node"},
+			want:     map[string]string{"__name__": "up", "job": "node"},
+			wantKeep: true,
+		},
+		{
+			name:     "labeldrop keeps __name__",
+			cfg:      RelabelConfig{Action: "labeldrop", Regex: ptr(".*")},
+			lbls:     map[string]string{"__name__": "up", "job": "node"},
+			want:     map[string]string{"__name__": "up"},
+			wantKeep: true,
+		},
+		{
+			name:     "labelkeep",
+			cfg:      RelabelConfig{Action: "labelkeep", Regex: ptr("job")},
+			lbls:     map[string]string{"__name__": "up", "job": "node", "instance": "a:9100"},
+			want:     map[string]string{"__name__": "up", "job": "node"},
+			wantKeep: true,
+		},
+		{
+			name:     "replace",
+			cfg:      RelabelConfig{SourceLabels: []string{"code"}, Regex: ptr(`(\d)..`), TargetLabel: "code_class", Replacement: ptr("${1}xx")},
+			lbls:     map[string]string{"code": "503"},
+			want:     map[string]string{"code": "503", "code_class": "5xx"},
+			wantKeep: true,
+		},
+		{
+			name:     "replace with nothing deletes the target",
+			cfg:      RelabelConfig{SourceLabels: []string{"code"}, Regex: ptr(".*"), TargetLabel: "code", Replacement: ptr("")},
+			lbls:     map[string]string{"code": "503"},
+			want:     map[string]string{},
+			wantKeep: true,
+		},
+		{
+			name:     "keep",
+			cfg:      RelabelConfig{SourceLabels: []string{"__name__"}, Regex: ptr("go_.*"), Action: "keep"},
+			lbls:     map[string]string{"__name__": "up"},
+			want:     map[string]string{"__name__": "up"},
+			wantKeep: false,
+		},
+		{
+			name:     "drop",
+			cfg:      RelabelConfig{SourceLabels: []string{"__name__"}, Regex: ptr("go_.*"), Action: "drop"},
+			lbls:     map[string]string{"__name__": "go_goroutines"},
+			want:     map[string]string{"__name__": "go_goroutines"},
+			wantKeep: false,
+		},
+		{
+			name:     "lowercase",
+			cfg:      RelabelConfig{SourceLabels: []string{"env"}, TargetLabel: "env", Action: "lowercase"},
+			lbls:     map[string]string{"env": "PROD"},
+			want:     map[string]string{"env": "prod"},
+			wantKeep: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := CompileRelabelConfigs([]RelabelConfig{tt.cfg})
+			if err != nil {
+				t.Fatalf("CompileRelabelConfigs failed: %v", err)
+			}
+			lbls := maps.Clone(tt.lbls)
+			if keep := rules[0].apply(lbls); keep != tt.wantKeep {
+				t.Errorf("apply kept the series: %v, want %v", keep, tt.wantKeep)
+			}
+			if !maps.Equal(lbls, tt.want) {
+				t.Errorf("apply = %v, want %v", lbls, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileRelabelConfigsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  RelabelConfig
+	}{
+		{"bad regex", RelabelConfig{Action: "labeldrop", Regex: ptr("(")}},
+		{"replace without a target", RelabelConfig{SourceLabels: []string{"code"}}},
+		{"unknown action", RelabelConfig{Action: "hashmod"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := CompileRelabelConfigs([]RelabelConfig{tt.cfg}); err == nil {
+				t.Error("CompileRelabelConfigs succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestRelabelFamilies(t *testing.T) {
+	fams := map[string]*dto.MetricFamily{
+		"up": {
+			Name: proto.String("up"),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{
+				Label: []*dto.LabelPair{met.LabelPair("__meta_zone", "eu"), met.LabelPair("job", "node")},
+				Gauge: &dto.Gauge{Value: proto.Float64(1)},
+			}},
+		},
+	}
+	rules, err := CompileRelabelConfigs([]RelabelConfig{
+		{Action: "labelmap", Regex: ptr("__meta_(.+)")},
+		{SourceLabels: []string{"__name__"}, TargetLabel: "__name__", Replacement: ptr("node_up")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := RelabelFamilies(fams, rules)
+	mf, ok := out["node_up"]
+	if !ok || len(out) != 1 {
+		t.Fatalf("RelabelFamilies = %v, want only node_up", out)
+	}
+	got := make(map[string]string)
+	for _, lp := range mf.Metric[0].Label {
+		got[lp.GetName()] = lp.GetValue()
+	}
+	// Labels starting with __ are removed afterwards.
+	want := map[string]string{"job": "node", "zone": "eu"}
+	if !maps.Equal(got, want) {
+		t.Errorf("labels = %v, want %v", got, want)
+	}
+}
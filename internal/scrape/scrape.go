@@ -0,0 +1,122 @@
+// Package scrape is where met's metrics come from: the sources it polls,
+// such as HTTP endpoints, Graphite, met agent over SSH and /proc, the
+// receivers metrics are pushed to over OTLP and StatsD, the demo exporter,
+// the HTTP clients they use, the discovery of endpoints to scrape,
+// relabeling of what's scraped, and Series, which follows each series'
+// value, change and rate from one scrape to the next.
+package scrape
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jaxxstorm/met/pkg/met"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Source produces the current set of metric families each time it's polled.
+type Source interface {
+	Scrape() (map[string]*dto.MetricFamily, error)
+}
+
+// Stats describes a single scrape of a target, for spotting an exporter
+// that's getting slow or dropping series.
+type Stats struct {
+	At       time.Time
+	Duration time.Duration
+	// Bytes is the size of the response body, or -1 for sources that
+	// don't have one.
+	Bytes int64
+	// Status is the HTTP status code, or 0 for sources that aren't HTTP
+	// or when no response was received.
+	Status int
+	Series int
+	Err    error
+	// ParseFailed is set when the response arrived but couldn't be
+	// parsed.
+	ParseFailed bool
+}
+
+// StatsSource is implemented by sources that can report more about a
+// scrape than how long it took.
+type StatsSource interface {
+	ScrapeWithStats() (map[string]*dto.MetricFamily, Stats, error)
+}
+
+// HTTP scrapes an HTTP endpoint exposing metrics in the given format.
+type HTTP struct {
+	URL    string
+	Format string
+	Client *http.Client
+	// MaxBodySize caps the decompressed response body, if non-zero.
+	MaxBodySize int64
+}
+
+// Scrape fetches and parses the endpoint.
+func (s HTTP) Scrape() (map[string]*dto.MetricFamily, error) {
+	fams, _, err := s.ScrapeWithStats()
+	return fams, err
+}
+
+// ScrapeWithStats also reports the response's status and size for the
+// health panel.
+func (s HTTP) ScrapeWithStats() (map[string]*dto.MetricFamily, Stats, error) {
+	fams, st, err := s.Scraper().Scrape()
+	return fams, Stats{Bytes: st.Bytes, Status: st.Status, ParseFailed: st.ParseFailed}, MaxBodyError(err)
+}
+
+// Body fetches the endpoint's exposition without parsing it.
+func (s HTTP) Body() ([]byte, error) {
+	body, err := s.Scraper().Body()
+	return body, MaxBodyError(err)
+}
+
+// Scraper is the pkg/met scraper the source scrapes with.
+func (s HTTP) Scraper() met.Scraper {
+	return met.Scraper{URL: s.URL, Format: s.Format, Client: s.Client, MaxBodySize: s.MaxBodySize}
+}
+
+// MaxBodyError names the flag that sets the limit a body was too large for,
+// whether it was a scrape's response or a request pushed to met.
+func MaxBodyError(err error) error {
+	var tooLarge *met.BodyTooLargeError
+	if errors.As(err, &tooLarge) {
+		return fmt.Errorf("body is larger than --max-body-size of %s", FormatSize(tooLarge.Limit))
+	}
+	return err
+}
+
+// FormatSize formats a number of bytes in binary units.
+func FormatSize(n int64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	v := float64(n)
+	units := []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	i := -1
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", v, units[i])
+}
+
+// RawValue is the value of a sample as exposed: the sum of the
+// observations for histograms and summaries.
+func RawValue(mf *dto.MetricFamily, pm *dto.Metric) float64 {
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		return pm.GetCounter().GetValue()
+	case dto.MetricType_GAUGE:
+		return pm.GetGauge().GetValue()
+	case dto.MetricType_UNTYPED:
+		return pm.GetUntyped().GetValue()
+	case dto.MetricType_SUMMARY:
+		return pm.GetSummary().GetSampleSum()
+	case dto.MetricType_HISTOGRAM:
+		return pm.GetHistogram().GetSampleSum()
+	}
+	return 0
+}
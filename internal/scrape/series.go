@@ -0,0 +1,96 @@
+package scrape
+
+import "time"
+
+// MaxHistory is how many of its recent values a Series keeps.
+const MaxHistory = 30
+
+// Series follows a single series from scrape to scrape: its latest value,
+// how much it changed and how quickly, and its recent history. A counter's
+// total carries on across resets, so it keeps counting up when the process
+// exposing it restarts.
+type Series struct {
+	// Counter is set for counters, whose history is of their total rather
+	// than the values scraped.
+	Counter bool
+	// Value is the value last observed.
+	Value float64
+	// Total is what a counter has counted since it was first observed.
+	Total float64
+	// Delta is a counter's last increase, or a gauge's change since the
+	// observation before.
+	Delta float64
+	// Rate is how quickly the series changed since the observation before,
+	// per second. It's signed for gauges, which go down as well as up.
+	Rate float64
+	// History holds the series' current values, observed at HistoryAt.
+	History   []float64
+	HistoryAt []time.Time
+}
+
+// Observe records raw, observed at at, as the series' latest value. A
+// counter that's gone backwards has been reset, so everything it's counted
+// since is new.
+func (s *Series) Observe(raw float64, at time.Time) {
+	var elapsed float64
+	if n := len(s.HistoryAt); n > 0 {
+		elapsed = at.Sub(s.HistoryAt[n-1]).Seconds()
+	}
+	if s.Counter {
+		diff := raw - s.Value
+		inc := 0.0
+		if diff < 0 {
+			s.Total += raw
+			s.Delta = raw
+			inc = raw
+		} else if diff > 0 {
+			s.Total += diff
+			s.Delta = diff
+			inc = diff
+		}
+		s.Rate = 0
+		if elapsed > 0 {
+			s.Rate = inc / elapsed
+		}
+	} else {
+		s.Delta, s.Rate = 0, 0
+		if len(s.History) > 0 {
+			s.Delta = raw - s.Value
+			if elapsed > 0 {
+				s.Rate = s.Delta / elapsed
+			}
+		}
+	}
+	s.Value = raw
+	s.Record(s.Current(), at)
+}
+
+// ObserveUnchanged records an observation, at at, of the same value as the
+// last, as Observe would.
+func (s *Series) ObserveUnchanged(at time.Time) {
+	s.Rate = 0
+	if !s.Counter {
+		s.Delta = 0
+	}
+	s.Record(s.Current(), at)
+}
+
+// Record appends v, observed at at, to the series' history, keeping the
+// last MaxHistory values.
+func (s *Series) Record(v float64, at time.Time) {
+	s.History = append(s.History, v)
+	s.HistoryAt = append(s.HistoryAt, at)
+	if len(s.History) > MaxHistory {
+		s.History = s.History[len(s.History)-MaxHistory:]
+		s.HistoryAt = s.HistoryAt[len(s.HistoryAt)-MaxHistory:]
+	}
+}
+
+// Current is the value that's graphed: a counter's total, or the value
+// last observed of anything else.
+func (s Series) Current() float64 {
+	if s.Counter {
+		return s.Total
+	}
+	return s.Value
+}
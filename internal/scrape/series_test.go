@@ -0,0 +1,136 @@
+package scrape
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSeriesObserve(t *testing.T) {
+	tests := []struct {
+		name    string
+		counter bool
+		// values are observed 5s apart, the first being where the series
+		// starts from.
+		values    []float64
+		wantTotal float64
+		wantDelta float64
+		wantRate  float64
+		wantHist  []float64
+	}{
+		{
+			name:      "counter",
+			counter:   true,
+			values:    []float64{10, 15, 25},
+			wantTotal: 15,
+			wantDelta: 10,
+			wantRate:  2,
+			wantHist:  []float64{0, 5, 15},
+		},
+		{
+			// Everything counted since the reset is new.
+			name:      "counter reset",
+			counter:   true,
+			values:    []float64{10, 15, 3},
+			wantTotal: 8,
+			wantDelta: 3,
+			wantRate:  0.6,
+			wantHist:  []float64{0, 5, 8},
+		},
+		{
+			// The last increase is kept, but nothing's happening now.
+			name:      "counter standing still",
+			counter:   true,
+			values:    []float64{10, 15, 15},
+			wantTotal: 5,
+			wantDelta: 5,
+			wantRate:  0,
+			wantHist:  []float64{0, 5, 5},
+		},
+		{
+			name:      "gauge rising",
+			values:    []float64{4, 6, 16},
+			wantDelta: 10,
+			wantRate:  2,
+			wantHist:  []float64{4, 6, 16},
+		},
+		{
+			name:      "gauge falling",
+			values:    []float64{16, 6},
+			wantDelta: -10,
+			wantRate:  -2,
+			wantHist:  []float64{16, 6},
+		},
+		{
+			name:     "first observation",
+			values:   []float64{7},
+			wantHist: []float64{7},
+		},
+	}
+	start := time.Unix(1000, 0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Series{Counter: tt.counter, Value: tt.values[0]}
+			for i, v := range tt.values {
+				s.Observe(v, start.Add(time.Duration(i)*5*time.Second))
+			}
+			if s.Total != tt.wantTotal {
+				t.Errorf("Total = %v, want %v", s.Total, tt.wantTotal)
+			}
+			if s.Delta != tt.wantDelta {
+				t.Errorf("Delta = %v, want %v", s.Delta, tt.wantDelta)
+			}
+			if math.Abs(s.Rate-tt.wantRate) > 1e-9 {
+				t.Errorf("Rate = %v, want %v", s.Rate, tt.wantRate)
+			}
+			if last := tt.values[len(tt.values)-1]; s.Value != last {
+				t.Errorf("Value = %v, want %v", s.Value, last)
+			}
+			if len(s.History) != len(tt.wantHist) || len(s.HistoryAt) != len(tt.wantHist) {
+				t.Fatalf("History = %v at %v, want %v", s.History, s.HistoryAt, tt.wantHist)
+			}
+			for i, v := range tt.wantHist {
+				if s.History[i] != v {
+					t.Errorf("History = %v, want %v", s.History, tt.wantHist)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestSeriesObserveUnchanged(t *testing.T) {
+	start := time.Unix(1000, 0)
+	for _, counter := range []bool{true, false} {
+		observed := Series{Counter: counter, Value: 10}
+		observed.Observe(10, start)
+		observed.Observe(14, start.Add(5*time.Second))
+		unchanged := observed
+		unchanged.History = append([]float64(nil), observed.History...)
+		unchanged.HistoryAt = append([]time.Time(nil), observed.HistoryAt...)
+
+		at := start.Add(10 * time.Second)
+		observed.Observe(14, at)
+		unchanged.ObserveUnchanged(at)
+		if observed.Delta != unchanged.Delta || observed.Rate != unchanged.Rate || observed.Current() != unchanged.Current() {
+			t.Errorf("counter %v: ObserveUnchanged left %+v, want %+v", counter, unchanged, observed)
+		}
+		if len(unchanged.History) != 3 || unchanged.History[2] != observed.History[2] {
+			t.Errorf("counter %v: History = %v, want %v", counter, unchanged.History, observed.History)
+		}
+	}
+}
+
+func TestSeriesHistoryLimit(t *testing.T) {
+	var s Series
+	start := time.Unix(1000, 0)
+	for i := range MaxHistory + 5 {
+		s.Observe(float64(i), start.Add(time.Duration(i)*time.Second))
+	}
+	if len(s.History) != MaxHistory || len(s.HistoryAt) != MaxHistory {
+		t.Fatalf("kept %d values at %d times, want %d", len(s.History), len(s.HistoryAt), MaxHistory)
+	}
+	if s.History[0] != 5 || !s.HistoryAt[0].Equal(start.Add(5*time.Second)) {
+		t.Errorf("oldest value kept = %v at %v, want 5 at %v", s.History[0], s.HistoryAt[0], start.Add(5*time.Second))
+	}
+}
@@ -0,0 +1,66 @@
+package scrape
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SRV discovers endpoints from a DNS SRV record, given as
+// srv://_metrics._tcp.myservice.consul/metrics. Every host and port in the
+// record is scraped over HTTP, or HTTPS with srv+https://, at the path from
+// the URL.
+type SRV struct {
+	raw    string
+	name   string
+	scheme string
+	path   string
+}
+
+// IsSRV reports whether an --endpoint names an SRV record rather
+// than a URL to scrape.
+func IsSRV(endpoint string) bool {
+	return strings.HasPrefix(endpoint, "srv://") || strings.HasPrefix(endpoint, "srv+https://")
+}
+
+// ParseSRV parses an srv:// or srv+https:// endpoint.
+func ParseSRV(endpoint string) (SRV, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return SRV{}, err
+	}
+	d := SRV{raw: endpoint, name: u.Host, scheme: "http", path: u.Path}
+	if u.Scheme == "srv+https" {
+		d.scheme = "https"
+	}
+	if d.name == "" {
+		return SRV{}, fmt.Errorf("%s has no SRV record name", endpoint)
+	}
+	if d.path == "" {
+		d.path = "/metrics"
+	}
+	return d, nil
+}
+
+func (d SRV) Discover() ([]Target, error) {
+	_, addrs, err := net.LookupSRV("", "", d.name)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		host := net.JoinHostPort(strings.TrimSuffix(a.Target, "."), strconv.Itoa(int(a.Port)))
+		endpoints = append(endpoints, (&url.URL{Scheme: d.scheme, Host: host, Path: d.path}).String())
+	}
+	// Lookups shuffle records of equal priority, so sort them to keep
+	// the tabs in a stable order.
+	sort.Strings(endpoints)
+	return endpointTargets(endpoints), nil
+}
+
+func (d SRV) String() string {
+	return d.raw
+}
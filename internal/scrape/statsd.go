@@ -0,0 +1,194 @@
+package scrape
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jaxxstorm/met/pkg/met"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// timerWindow is how many recent timer samples are kept per series to
+// estimate quantiles.
+const timerWindow = 1000
+
+var timerQuantiles = []float64{0.5, 0.9, 0.99}
+
+// StatsDReceiver aggregates StatsD packets locally: counters are summed,
+// gauges keep their last value, sets count unique members, and timers,
+// histograms and distributions become summaries over a window of recent
+// samples. DogStatsD style tags become labels.
+type StatsDReceiver struct {
+	pushStore
+	addr    string
+	windows map[*pushedSeries][]float64
+	// observed counts each timer's samples, scaled up by their sample
+	// rates, which needn't add up to a whole number.
+	observed map[*pushedSeries]float64
+	// unsorted marks the timers sampled since their quantiles were last
+	// worked out, which is left until the series are scraped.
+	unsorted map[*pushedSeries]bool
+	sets     map[*pushedSeries]map[string]struct{}
+}
+
+func newStatsDReceiver(addr string) *StatsDReceiver {
+	return &StatsDReceiver{
+		addr:     addr,
+		windows:  make(map[*pushedSeries][]float64),
+		observed: make(map[*pushedSeries]float64),
+		unsorted: make(map[*pushedSeries]bool),
+		sets:     make(map[*pushedSeries]map[string]struct{}),
+	}
+}
+
+// ListenStatsD starts receiving StatsD packets over UDP on addr, logging
+// to logger if reading them fails.
+func ListenStatsD(addr string, logger *slog.Logger) (*StatsDReceiver, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	r := newStatsDReceiver(conn.LocalAddr().String())
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				logger.Error("StatsD receiver stopped", "err", err)
+				return
+			}
+			r.ingest(string(buf[:n]))
+		}
+	}()
+	return r, nil
+}
+
+func (r *StatsDReceiver) String() string {
+	return "statsd://" + r.addr
+}
+
+// Scrape returns the series received so far, with the quantiles of the
+// timers sampled since the last scrape worked out from their windows.
+func (r *StatsDReceiver) Scrape() (map[string]*dto.MetricFamily, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for s := range r.unsorted {
+		s.metric.Summary.Quantile = windowQuantiles(r.windows[s])
+		delete(r.unsorted, s)
+	}
+	return r.snapshot(), nil
+}
+
+func (r *StatsDReceiver) ingest(packet string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, line := range strings.Split(packet, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		// Malformed lines are dropped, as most StatsD servers do, but
+		// counted so a misbehaving client is visible.
+		if err := r.ingestLine(line); err != nil {
+			s := r.series("met_statsd_malformed_lines_total", "Lines met couldn't parse", dto.MetricType_COUNTER, nil)
+			s.metric.Counter = &dto.Counter{Value: proto.Float64(s.metric.GetCounter().GetValue() + 1)}
+		}
+	}
+}
+
+// ingestLine handles a single "name:value|type[|@rate][|#tags]" line.
+// Callers hold r.mu.
+func (r *StatsDReceiver) ingestLine(line string) error {
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return fmt.Errorf("missing value in %q", line)
+	}
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 {
+		return fmt.Errorf("missing type in %q", line)
+	}
+	rawValue, typ := parts[0], parts[1]
+	rate := 1.0
+	var lbls []*dto.LabelPair
+	for _, p := range parts[2:] {
+		switch {
+		case strings.HasPrefix(p, "@"):
+			f, err := strconv.ParseFloat(p[1:], 64)
+			if err != nil || f <= 0 || f > 1 {
+				return fmt.Errorf("bad sample rate in %q", line)
+			}
+			rate = f
+		case strings.HasPrefix(p, "#"):
+			for _, tag := range strings.Split(p[1:], ",") {
+				k, v, ok := strings.Cut(tag, ":")
+				if ok && k != "" {
+					lbls = append(lbls, met.LabelPair(met.SanitizeName(k), v))
+				}
+			}
+		}
+	}
+	name = met.SanitizeName(name)
+
+	if typ == "s" {
+		s := r.series(name, "StatsD set", dto.MetricType_GAUGE, lbls)
+		members, ok := r.sets[s]
+		if !ok {
+			members = make(map[string]struct{})
+			r.sets[s] = members
+		}
+		members[rawValue] = struct{}{}
+		s.metric.Gauge = &dto.Gauge{Value: proto.Float64(float64(len(members)))}
+		return nil
+	}
+
+	v, err := strconv.ParseFloat(rawValue, 64)
+	if err != nil {
+		return fmt.Errorf("bad value in %q", line)
+	}
+	switch typ {
+	case "c":
+		s := r.series(name, "StatsD counter", dto.MetricType_COUNTER, lbls)
+		s.metric.Counter = &dto.Counter{Value: proto.Float64(s.metric.GetCounter().GetValue() + v/rate)}
+	case "g":
+		s := r.series(name, "StatsD gauge", dto.MetricType_GAUGE, lbls)
+		// A leading sign makes the change relative to the current value.
+		if strings.HasPrefix(rawValue, "+") || strings.HasPrefix(rawValue, "-") {
+			v += s.metric.GetGauge().GetValue()
+		}
+		s.metric.Gauge = &dto.Gauge{Value: proto.Float64(v)}
+	case "ms", "h", "d":
+		s := r.series(name, "StatsD timer", dto.MetricType_SUMMARY, lbls)
+		window := append(r.windows[s], v)
+		if len(window) > timerWindow {
+			window = window[len(window)-timerWindow:]
+		}
+		r.windows[s] = window
+		r.unsorted[s] = true
+		r.observed[s] += 1 / rate
+		s.metric.Summary = &dto.Summary{
+			SampleCount: proto.Uint64(uint64(math.Round(r.observed[s]))),
+			SampleSum:   proto.Float64(s.metric.GetSummary().GetSampleSum() + v/rate),
+			Quantile:    s.metric.GetSummary().GetQuantile(),
+		}
+	default:
+		return fmt.Errorf("unknown type %q in %q", typ, line)
+	}
+	return nil
+}
+
+func windowQuantiles(window []float64) []*dto.Quantile {
+	sorted := append([]float64(nil), window...)
+	sort.Float64s(sorted)
+	out := make([]*dto.Quantile, 0, len(timerQuantiles))
+	for _, q := range timerQuantiles {
+		idx := int(q * float64(len(sorted)-1))
+		out = append(out, &dto.Quantile{Quantile: proto.Float64(q), Value: proto.Float64(sorted[idx])})
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package scrape
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// seriesValue finds the value of the series name{lbls} in fams: the value
+// of a counter or gauge, or the count of a summary.
+func seriesValue(t *testing.T, fams map[string]*dto.MetricFamily, name, lbls string) float64 {
+	t.Helper()
+	mf, ok := fams[name]
+	if !ok {
+		t.Fatalf("no %s in %v", name, fams)
+	}
+	for _, pm := range mf.Metric {
+		if seriesKey(name, pm.Label) != name+"{"+lbls+"}" {
+			continue
+		}
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			return pm.GetCounter().GetValue()
+		case dto.MetricType_SUMMARY:
+			return float64(pm.GetSummary().GetSampleCount())
+		}
+		return pm.GetGauge().GetValue()
+	}
+	t.Fatalf("no %s{%s} in %v", name, lbls, mf)
+	return 0
+}
+
+func TestStatsdIngest(t *testing.T) {
+	tests := []struct {
+		name    string
+		packets []string
+		metric  string
+		labels  string
+		want    float64
+	}{
+		{"counter", []string{"hits:1|c", "hits:2|c"}, "hits", "", 3},
+		{"sampled counter", []string{"hits:1|c|@0.1"}, "hits", "", 10},
+		{"sanitized name", []string{"api.hits:1|c"}, "api_hits", "", 1},
+		{"tags", []string{"hits:1|c|#env:prod,code:200"}, "hits", `code="200",env="prod"`, 1},
+		{"gauge", []string{"queue:5|g", "queue:7|g"}, "queue", "", 7},
+		{"relative gauge", []string{"queue:5|g", "queue:+3|g", "queue:-1|g"}, "queue", "", 7},
+		{"set", []string{"users:a|s", "users:b|s", "users:a|s"}, "users", "", 2},
+		{"several lines in a packet", []string{"hits:1|c\nhits:1|c\n"}, "hits", "", 2},
+		{"timer count", []string{"latency:10|ms", "latency:20|ms"}, "latency", "", 2},
+		// Three samples at a rate of 0.3 stand for ten.
+		{"sampled timer count", []string{"latency:10|ms|@0.3", "latency:10|ms|@0.3", "latency:10|ms|@0.3"}, "latency", "", 10},
+		{"malformed lines are counted", []string{"hits", "hits:1", "hits:x|c", "hits:1|q", "hits:1|c|@2"}, "met_statsd_malformed_lines_total", "", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newStatsDReceiver("")
+			for _, p := range tt.packets {
+				r.ingest(p)
+			}
+			fams, err := r.Scrape()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := seriesValue(t, fams, tt.metric, tt.labels); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("%s{%s} = %v, want %v", tt.metric, tt.labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsdTimerQuantiles(t *testing.T) {
+	r := newStatsDReceiver("")
+	for i := 1; i <= 100; i++ {
+		r.ingest("latency:" + strconv.Itoa(i) + "|ms")
+	}
+	fams, err := r.Scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm := fams["latency"].Metric[0].GetSummary()
+	if sm.GetSampleSum() != 5050 {
+		t.Errorf("sum = %v, want 5050", sm.GetSampleSum())
+	}
+	want := map[float64]float64{0.5: 50, 0.9: 90, 0.99: 99}
+	for _, q := range sm.GetQuantile() {
+		if q.GetValue() != want[q.GetQuantile()] {
+			t.Errorf("quantile %v = %v, want %v", q.GetQuantile(), q.GetValue(), want[q.GetQuantile()])
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// NewHTTPClient returns the client endpoints are scraped with. Requests go
+// through proxyURL if set, otherwise the proxy from HTTP_PROXY/HTTPS_PROXY,
+// or are tunnelled over SSH to sshTarget.
+func NewHTTPClient(proxyURL, sshTarget string) (*http.Client, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	switch {
+	case proxyURL != "" && sshTarget != "":
+		return nil, errors.New("--proxy-url and --ssh can't be used together")
+	case proxyURL != "":
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing proxy URL: %w", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q, want http, https or socks5", u.Scheme)
+		}
+		tr.Proxy = http.ProxyURL(u)
+	case sshTarget != "":
+		t, err := newSSHTunnel(sshTarget)
+		if err != nil {
+			return nil, err
+		}
+		tr.Proxy = nil
+		tr.DialContext = t.DialContext
+	}
+	return &http.Client{Transport: tr}, nil
+}
+
+// sshTunnel dials connections from an SSH server, like ssh -W, so endpoints
+// only reachable from a bastion can be scraped directly. It authenticates
+// with the SSH agent and unencrypted keys in ~/.ssh, and checks the host key
+// against ~/.ssh/known_hosts.
+type sshTunnel struct {
+	addr   string
+	config *ssh.ClientConfig
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+func newSSHTunnel(target string) (*sshTunnel, error) {
+	userName, host, ok := strings.Cut(target, "@")
+	if !ok {
+		host = target
+		u, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("finding current user: %w", err)
+		}
+		userName = u.Username
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	hostKeys, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("loading known hosts: %w", err)
+	}
+	var auth []ssh.AuthMethod
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	var signers []ssh.Signer
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		b, err := os.ReadFile(filepath.Join(home, ".ssh", name))
+		if err != nil {
+			continue
+		}
+		if s, err := ssh.ParsePrivateKey(b); err == nil {
+			signers = append(signers, s)
+		}
+	}
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("no SSH agent or unencrypted keys in ~/.ssh to authenticate with")
+	}
+	return &sshTunnel{
+		addr: host,
+		config: &ssh.ClientConfig{
+			User:            userName,
+			Auth:            auth,
+			HostKeyCallback: hostKeys,
+			Timeout:         10 * time.Second,
+		},
+	}, nil
+}
+
+func (t *sshTunnel) connect() (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != nil {
+		return t.client, nil
+	}
+	c, err := ssh.Dial("tcp", t.addr, t.config)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s over SSH: %w", t.addr, err)
+	}
+	t.client = c
+	return c, nil
+}
+
+// reset drops c so the next dial reconnects, unless another dial already
+// replaced it.
+func (t *sshTunnel) reset(c *ssh.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client == c {
+		t.client.Close()
+		t.client = nil
+	}
+}
+
+func (t *sshTunnel) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	c, err := t.connect()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := c.DialContext(ctx, network, addr)
+	if err == nil || ctx.Err() != nil {
+		return conn, err
+	}
+	// The SSH connection may have dropped since it was last used, so try
+	// again on a fresh one.
+	t.reset(c)
+	if c, err = t.connect(); err != nil {
+		return nil, err
+	}
+	return c.DialContext(ctx, network, addr)
+}
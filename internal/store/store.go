@@ -0,0 +1,349 @@
+// Package store persists met's scrapes to a bbolt database, so a session
+// can be restored after a restart, saved on exit or queried later.
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jaxxstorm/met/internal/scrape"
+	dto "github.com/prometheus/client_model/go"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	sessionsBucket = []byte("sessions")
+	scrapesBucket  = []byte("scrapes")
+)
+
+// Store persists scrapes to a bbolt database. Every endpoint watched by a
+// run of met is a session; scrapes are stored per session, keyed by scrape
+// time.
+type Store struct {
+	db *bolt.DB
+}
+
+// Session is an endpoint watched by a run of met.
+type Session struct {
+	ID       uint64
+	Endpoint string
+	Started  time.Time
+	Scrapes  int
+	Notes    map[string]string
+}
+
+// Sample is a series' value in a stored scrape. Histograms and summaries
+// keep their buckets and quantiles, with the sum of their observations as
+// the value.
+type Sample struct {
+	Name    string
+	Labels  map[string]string
+	Counter bool
+	Value   float64
+	// Type is the family's dto.MetricType name. Samples stored before it
+	// was kept are replayed as counters or gauges, going by Counter.
+	Type      string
+	Count     uint64
+	Buckets   []Bucket
+	Quantiles []Quantile
+}
+
+// Bucket is a histogram bucket, counting the observations up to its upper
+// bound.
+type Bucket struct {
+	UpperBound float64
+	Count      uint64
+}
+
+// Quantile is a summary's quantile.
+type Quantile struct {
+	Quantile float64
+	Value    float64
+}
+
+// Scrape is the samples from a single scrape.
+type Scrape struct {
+	At      time.Time
+	Samples []Sample
+}
+
+// Open opens the store at path, creating it if it doesn't exist.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%s is locked, is another met using it?", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(sessionsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(scrapesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the store's database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func encodeGob(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeGob(b []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+// StartSession creates a new session for endpoint and returns its id, which
+// is passed to Record.
+func (s *Store) StartSession(endpoint string, at time.Time) (uint64, error) {
+	var session uint64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		sessions := tx.Bucket(sessionsBucket)
+		id, err := sessions.NextSequence()
+		if err != nil {
+			return err
+		}
+		b, err := encodeGob(Session{ID: id, Endpoint: endpoint, Started: at})
+		if err != nil {
+			return err
+		}
+		if err := sessions.Put(itob(id), b); err != nil {
+			return err
+		}
+		if _, err := tx.Bucket(scrapesBucket).CreateBucket(itob(id)); err != nil {
+			return err
+		}
+		session = id
+		return nil
+	})
+	return session, err
+}
+
+// Record stores families as scraped at at in session.
+func (s *Store) Record(session uint64, families map[string]*dto.MetricFamily, at time.Time) error {
+	sc := Scrape{At: at}
+	for name, mf := range families {
+		for _, pm := range mf.Metric {
+			ss := SampleOf(mf, pm)
+			ss.Name = name
+			if len(pm.Label) > 0 {
+				ss.Labels = make(map[string]string, len(pm.Label))
+				for _, lp := range pm.Label {
+					ss.Labels[lp.GetName()] = lp.GetValue()
+				}
+			}
+			sc.Samples = append(sc.Samples, ss)
+		}
+	}
+	return s.Put(session, sc)
+}
+
+// SampleOf is pm, a series of mf, as it's stored, without its name and
+// labels.
+func SampleOf(mf *dto.MetricFamily, pm *dto.Metric) Sample {
+	ss := Sample{
+		Counter: mf.GetType() == dto.MetricType_COUNTER,
+		Value:   scrape.RawValue(mf, pm),
+		Type:    mf.GetType().String(),
+	}
+	if h := pm.GetHistogram(); h != nil {
+		ss.Count = h.GetSampleCount()
+		for _, b := range h.GetBucket() {
+			ss.Buckets = append(ss.Buckets, Bucket{UpperBound: b.GetUpperBound(), Count: b.GetCumulativeCount()})
+		}
+	}
+	if sm := pm.GetSummary(); sm != nil {
+		ss.Count = sm.GetSampleCount()
+		for _, q := range sm.GetQuantile() {
+			ss.Quantiles = append(ss.Quantiles, Quantile{Quantile: q.GetQuantile(), Value: q.GetValue()})
+		}
+	}
+	return ss
+}
+
+// Put stores sc in session.
+func (s *Store) Put(session uint64, sc Scrape) error {
+	b, err := encodeGob(sc)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(scrapesBucket).Bucket(itob(session))
+		if bkt == nil {
+			return fmt.Errorf("session %d not found", session)
+		}
+		return bkt.Put(itob(uint64(sc.At.UnixNano())), b)
+	})
+}
+
+// Sessions lists every stored session, oldest first.
+func (s *Store) Sessions() ([]Session, error) {
+	var out []Session
+	err := s.db.View(func(tx *bolt.Tx) error {
+		scrapes := tx.Bucket(scrapesBucket)
+		return tx.Bucket(sessionsBucket).ForEach(func(k, v []byte) error {
+			var si Session
+			if err := decodeGob(v, &si); err != nil {
+				return err
+			}
+			if bkt := scrapes.Bucket(k); bkt != nil {
+				si.Scrapes = bkt.Stats().KeyN
+			}
+			out = append(out, si)
+			return nil
+		})
+	})
+	return out, err
+}
+
+// LastSession finds the most recent session for endpoint, if any.
+func (s *Store) LastSession(endpoint string) (Session, bool, error) {
+	all, err := s.Sessions()
+	if err != nil {
+		return Session{}, false, err
+	}
+	for i := len(all) - 1; i >= 0; i-- {
+		if all[i].Endpoint == endpoint && all[i].Scrapes > 0 {
+			return all[i], true, nil
+		}
+	}
+	return Session{}, false, nil
+}
+
+// LoadScrapes returns up to limit of a session's most recent scrapes in
+// chronological order. A limit of zero loads everything.
+func (s *Store) LoadScrapes(session uint64, limit int) ([]Scrape, error) {
+	var out []Scrape
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(scrapesBucket).Bucket(itob(session))
+		if bkt == nil {
+			return fmt.Errorf("session %d not found", session)
+		}
+		c := bkt.Cursor()
+		for k, v := c.Last(); k != nil && (limit == 0 || len(out) < limit); k, v = c.Prev() {
+			var sc Scrape
+			if err := decodeGob(v, &sc); err != nil {
+				return err
+			}
+			out = append(out, sc)
+		}
+		return nil
+	})
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, err
+}
+
+// Families rebuilds metric families from a stored scrape so it can be
+// replayed.
+func (sc Scrape) Families() map[string]*dto.MetricFamily {
+	fams := make(map[string]*dto.MetricFamily)
+	for _, ss := range sc.Samples {
+		mf, ok := fams[ss.Name]
+		if !ok {
+			mf = &dto.MetricFamily{Name: proto.String(ss.Name), Type: ss.metricType().Enum()}
+			fams[ss.Name] = mf
+		}
+		pm := &dto.Metric{}
+		names := make([]string, 0, len(ss.Labels))
+		for n := range ss.Labels {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			pm.Label = append(pm.Label, &dto.LabelPair{Name: proto.String(n), Value: proto.String(ss.Labels[n])})
+		}
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			pm.Counter = &dto.Counter{Value: proto.Float64(ss.Value)}
+		case dto.MetricType_UNTYPED:
+			pm.Untyped = &dto.Untyped{Value: proto.Float64(ss.Value)}
+		case dto.MetricType_SUMMARY:
+			pm.Summary = &dto.Summary{SampleCount: proto.Uint64(ss.Count), SampleSum: proto.Float64(ss.Value)}
+			for _, q := range ss.Quantiles {
+				pm.Summary.Quantile = append(pm.Summary.Quantile, &dto.Quantile{Quantile: proto.Float64(q.Quantile), Value: proto.Float64(q.Value)})
+			}
+		case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+			pm.Histogram = &dto.Histogram{SampleCount: proto.Uint64(ss.Count), SampleSum: proto.Float64(ss.Value)}
+			for _, b := range ss.Buckets {
+				pm.Histogram.Bucket = append(pm.Histogram.Bucket, &dto.Bucket{UpperBound: proto.Float64(b.UpperBound), CumulativeCount: proto.Uint64(b.Count)})
+			}
+		default:
+			pm.Gauge = &dto.Gauge{Value: proto.Float64(ss.Value)}
+		}
+		mf.Metric = append(mf.Metric, pm)
+	}
+	return fams
+}
+
+// metricType is the type of the family the sample was scraped from.
+func (ss Sample) metricType() dto.MetricType {
+	if t, ok := dto.MetricType_value[ss.Type]; ok {
+		return dto.MetricType(t)
+	}
+	if ss.Counter {
+		return dto.MetricType_COUNTER
+	}
+	return dto.MetricType_GAUGE
+}
+
+// SetNotes replaces the notes stored with a session.
+func (s *Store) SetNotes(session uint64, notes map[string]string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		sessions := tx.Bucket(sessionsBucket)
+		b := sessions.Get(itob(session))
+		if b == nil {
+			return fmt.Errorf("session %d not found", session)
+		}
+		var si Session
+		if err := decodeGob(b, &si); err != nil {
+			return err
+		}
+		si.Notes = notes
+		b, err := encodeGob(si)
+		if err != nil {
+			return err
+		}
+		return sessions.Put(itob(session), b)
+	})
+}
+
+// Notes returns the notes stored with a session.
+func (s *Store) Notes(session uint64) (map[string]string, error) {
+	var si Session
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(sessionsBucket).Get(itob(session))
+		if b == nil {
+			return fmt.Errorf("session %d not found", session)
+		}
+		return decodeGob(b, &si)
+	})
+	return si.Notes, err
+}
@@ -0,0 +1,8 @@
+package ui
+
+type AgentCmd struct{}
+
+type ConnectCmd struct {
+	Host  string `arg:"" help:"Host to run the agent on over SSH, e.g. user@host or user@host:2222"`
+	Agent string `help:"Command that runs met agent on the remote host" default:"met agent"`
+}
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jaxxstorm/met/internal/scrape"
+)
+
+// aggMode controls whether identical series from every tab are collapsed
+// into a single row.
+type aggMode int
+
+const (
+	aggOff aggMode = iota
+	aggSum
+	aggAvg
+	numAggModes
+)
+
+func (a aggMode) String() string {
+	switch a {
+	case aggSum:
+		return "sum"
+	case aggAvg:
+		return "avg"
+	}
+	return "off"
+}
+
+// aggTabID addresses the aggregate view in tabMsgs, so statuses from its
+// commands find their way back to it.
+const aggTabID = -1
+
+// aggregateSeries collapses series with the same key across models into one,
+// summing or averaging their values. Histories are aligned on their most
+// recent point.
+func aggregateSeries(ms []model, mode aggMode) []metricData {
+	byKey := make(map[string]*metricData)
+	counts := make(map[string]int)
+	var keys []string
+	for _, m := range ms {
+		for _, md := range m.metricsList {
+			agg, ok := byKey[md.key]
+			if !ok {
+				agg = &metricData{
+					Series:     scrape.Series{Counter: md.Counter},
+					key:        md.key,
+					name:       md.name,
+					labels:     md.labels,
+					labelPairs: md.labelPairs,
+					synthetic:  md.synthetic,
+					unit:       md.unit,
+					lastSeen:   md.lastSeen,
+					stale:      true,
+				}
+				byKey[md.key] = agg
+				keys = append(keys, md.key)
+			}
+			counts[md.key]++
+			agg.Value += md.Value
+			agg.Total += md.Total
+			agg.Delta += md.Delta
+			agg.Rate += md.Rate
+			agg.markVal += md.markVal
+			agg.stale = agg.stale && md.stale
+			if md.lastSeen.After(agg.lastSeen) {
+				agg.lastSeen = md.lastSeen
+			}
+			if len(md.History) > len(agg.History) {
+				padded := make([]float64, len(md.History))
+				copy(padded[len(md.History)-len(agg.History):], agg.History)
+				agg.History = padded
+				agg.HistoryAt = md.HistoryAt
+			}
+			offset := len(agg.History) - len(md.History)
+			for i, v := range md.History {
+				agg.History[offset+i] += v
+			}
+		}
+	}
+	sort.Strings(keys)
+	out := make([]metricData, 0, len(keys))
+	for _, k := range keys {
+		agg := *byKey[k]
+		if mode == aggAvg {
+			n := float64(counts[k])
+			agg.Value /= n
+			agg.Total /= n
+			agg.Delta /= n
+			agg.Rate /= n
+			agg.markVal /= n
+			for i := range agg.History {
+				agg.History[i] /= n
+			}
+		}
+		out = append(out, agg)
+	}
+	return out
+}
+
+// aggregateView returns a model showing every tab's series aggregated,
+// keeping the navigation state of the previous aggregate view.
+func (t tabs) aggregateView(prev model) model {
+	ms := make([]model, len(t.tabs))
+	for i, tb := range t.tabs {
+		ms[i] = tb.m
+	}
+	m := prev
+	var selectedKey string
+	if m.initialized && m.selected >= 0 && m.selected < len(m.metricsList) {
+		selectedKey = m.metricsList[m.selected].key
+	} else {
+		m = t.tabs[t.active].m
+		m.selected, m.pageStart = 0, 0
+		m.status = ""
+		m.health = nil
+		m.baseline = nil
+	}
+	m.endpoint = fmt.Sprintf("%d endpoints (%s)", len(t.tabs), t.agg)
+	// The rows are new, so they mustn't come from the tab's row cache.
+	m.rows = nil
+	// Marks are set on every tab, so the aggregate mark is theirs.
+	m.markedAt = t.tabs[t.active].m.markedAt
+	m.metricsList = aggregateSeries(ms, t.agg)
+	m.metricsIndex = make(map[string]int, len(m.metricsList))
+	m.initialized = false
+	m.sortMetrics(m.order())
+	m.initialized = true
+	if idx, ok := m.metricsIndex[selectedKey]; ok {
+		m.selected = idx
+	}
+	if m.selected >= m.visibleLen() {
+		m.selected = m.visibleLen() - 1
+	}
+	m.enforcePageBounds()
+	return m
+}
+
+// renderBreakdown lists the selected aggregate series' value on every
+// target.
+func (t tabs) renderBreakdown() string {
+	m := t.aggModel
+	if m.selected < 0 || m.selected >= len(m.metricsList) {
+		return ""
+	}
+	sel := m.metricsList[m.selected]
+	format := m.formatter(sel)
+	var sb strings.Builder
+	table := newPlainTable(&sb, []string{"Endpoint", "Value"})
+	for _, tb := range t.tabs {
+		value := "--"
+		if idx, ok := tb.m.metricsIndex[sel.key]; ok && idx < len(tb.m.metricsList) {
+			md := tb.m.metricsList[idx]
+			value = format(md.Value)
+		}
+		table.Append([]string{tb.m.endpoint, value})
+	}
+	table.Render()
+	return sel.key + " by endpoint\n" + sb.String()
+}
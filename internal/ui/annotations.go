@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// graphEvent is something that explains a break in a series' line, marked
+// on its graph's time axis under the point it shows up at: a counter going
+// backwards, the target restarting, as seen from process_start_time_seconds
+// changing, or a scrape that failed, leaving a gap before the next point.
+type graphEvent struct {
+	at   time.Time
+	mark rune
+	what string
+}
+
+// graphEvents lists the events to mark on md's graph, those that explain
+// more coming later, so they're drawn over the others at the same point.
+func (m model) graphEvents(md metricData) []graphEvent {
+	var events []graphEvent
+	for _, h := range m.health {
+		if h.Err != nil {
+			events = append(events, graphEvent{at: h.At, mark: 'x', what: "scrape failed"})
+		}
+	}
+	if !md.synthetic {
+		for _, at := range md.resets {
+			// Resets when the target restarted are marked as the restart.
+			if !slices.Contains(m.restarts, at) {
+				events = append(events, graphEvent{at: at, mark: '↓', what: "counter reset"})
+			}
+		}
+	}
+	for _, at := range m.restarts {
+		events = append(events, graphEvent{at: at, mark: 'R', what: "target restarted"})
+	}
+	return events
+}
+
+// annotateAxis marks events on the time axis renderTimeAxis drew for
+// times, under the first point plotted at or after each, and returns it
+// with a legend of the events marked, or an empty one if none were. Events
+// before the first point are off the graph.
+func annotateAxis(timeAxis string, axis, width int, times []time.Time, events []graphEvent) (string, string) {
+	if len(times) == 0 || len(events) == 0 {
+		return timeAxis, ""
+	}
+	first, rest, _ := strings.Cut(timeAxis, "\n")
+	line := []rune(first)
+	var kinds []graphEvent
+	marked := make(map[rune][]time.Time)
+	for _, ev := range events {
+		if ev.at.Before(times[0]) {
+			continue
+		}
+		i := sort.Search(len(times), func(i int) bool { return !times[i].Before(ev.at) })
+		if i == len(times) {
+			continue
+		}
+		x := 0
+		if len(times) > 1 {
+			x = int(math.Round(float64(i) * float64(width-1) / float64(len(times)-1)))
+		}
+		if axis+x >= len(line) {
+			continue
+		}
+		line[axis+x] = ev.mark
+		if _, ok := marked[ev.mark]; !ok {
+			kinds = append(kinds, ev)
+		}
+		marked[ev.mark] = append(marked[ev.mark], ev.at)
+	}
+	if len(kinds) == 0 {
+		return timeAxis, ""
+	}
+	parts := make([]string, len(kinds))
+	for i, k := range kinds {
+		at := marked[k.mark]
+		last := at[len(at)-1].Format(time.TimeOnly)
+		if len(at) == 1 {
+			parts[i] = fmt.Sprintf("%c %s at %s", k.mark, k.what, last)
+		} else {
+			parts[i] = fmt.Sprintf("%c %s %d times, last at %s", k.mark, k.what, len(at), last)
+		}
+	}
+	return string(line) + "\n" + rest, strings.Join(parts, " · ")
+}
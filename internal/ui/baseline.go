@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type BaselineCmd struct {
+	Save BaselineSaveCmd `cmd:"" help:"Scrape once and save the values as a named baseline"`
+	List struct{}        `cmd:"" help:"List saved baselines"`
+}
+
+type BaselineSaveCmd struct {
+	Name string `arg:"" help:"Name of the baseline"`
+}
+
+// baseline is a saved set of series values that later sessions can be
+// compared against with --baseline.
+type baseline struct {
+	Endpoint string             `json:"endpoint"`
+	Saved    time.Time          `json:"saved"`
+	Values   map[string]float64 `json:"values"`
+}
+
+// DefaultBaselineDir is where baselines are saved unless --baseline-dir
+// says otherwise.
+func DefaultBaselineDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "met", "baselines")
+}
+
+func baselinePath(dir, name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid baseline name %q", name)
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// saveBaseline scrapes the endpoint once and writes every series that
+// passes the model's filters to dir/name.json.
+func saveBaseline(m model, dir, name string) (string, error) {
+	path, err := baselinePath(dir, name)
+	if err != nil {
+		return "", err
+	}
+	fams, err := m.scrape()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	m = updateMetrics(m, fams, now)
+	b := baseline{Endpoint: m.endpoint, Saved: now, Values: make(map[string]float64, len(m.metricsList))}
+	for _, md := range m.metricsList {
+		// JSON can't represent NaN or Inf, and they make useless baselines anyway.
+		if math.IsNaN(md.Value) || math.IsInf(md.Value, 0) {
+			continue
+		}
+		b.Values[md.key] = md.Value
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, data, 0o644)
+}
+
+func loadBaseline(dir, name string) (baseline, error) {
+	var b baseline
+	path, err := baselinePath(dir, name)
+	if err != nil {
+		return b, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return b, err
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		return b, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return b, nil
+}
+
+func listBaselines(w io.Writer, dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	table := newPlainTable(w, []string{"Name", "Endpoint", "Saved", "Series"})
+	for _, p := range paths {
+		name := strings.TrimSuffix(filepath.Base(p), ".json")
+		b, err := loadBaseline(dir, name)
+		if err != nil {
+			return err
+		}
+		table.Append([]string{name, b.Endpoint, b.Saved.Format(time.DateTime), strconv.Itoa(len(b.Values))})
+	}
+	table.Render()
+	return nil
+}
+
+// formatBaselineChange renders the percentage change of v against the
+// baseline value for key.
+func (m model) formatBaselineChange(key string, v float64) string {
+	base, ok := m.baseline.Values[key]
+	if !ok {
+		return "new"
+	}
+	if base == 0 {
+		if v == 0 {
+			return "0.0%"
+		}
+		return "--"
+	}
+	pct := (v - base) / math.Abs(base) * 100
+	if pct > 0 {
+		return m.theme.positive.Render(fmt.Sprintf("+%.1f%%", pct))
+	} else if pct < 0 {
+		return m.theme.negative.Render(fmt.Sprintf("%.1f%%", pct))
+	}
+	return "0.0%"
+}
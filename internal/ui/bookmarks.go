@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// isBookmarked reports whether the series with key is bookmarked. Bookmarks
+// are saved to the config file per endpoint, and pinned to the top of the
+// table whenever that endpoint is scraped again.
+func (m model) isBookmarked(key string) bool {
+	return slices.Contains(m.bookmarks[m.endpoint], key)
+}
+
+// pinBookmarks wraps less to order bookmarked series first.
+func (m model) pinBookmarks(less func(a, b metricData) bool) func(a, b metricData) bool {
+	if len(m.bookmarks[m.endpoint]) == 0 {
+		return less
+	}
+	return func(a, b metricData) bool {
+		if ba, bb := m.isBookmarked(a.key), m.isBookmarked(b.key); ba != bb {
+			return ba
+		}
+		return less(a, b)
+	}
+}
+
+// toggleBookmark bookmarks or unbookmarks the selected series, saving the
+// change to the config file. The map is copied rather than changed in
+// place, as models copied for tabs share it.
+func (m model) toggleBookmark() model {
+	if m.selected < 0 || m.selected >= len(m.metricsList) {
+		return m
+	}
+	md := m.metricsList[m.selected]
+	if md.synthetic {
+		m.status = "Expressions can't be bookmarked"
+		return m
+	}
+	if m.configPath == "" {
+		m.status = "No config file to save bookmarks to"
+		return m
+	}
+	keys := slices.Clone(m.bookmarks[m.endpoint])
+	if i := slices.Index(keys, md.key); i >= 0 {
+		keys = slices.Delete(keys, i, i+1)
+	} else {
+		keys = append(keys, md.key)
+	}
+	err := updateConfig(m.configPath, func(cfg *config) {
+		if cfg.Bookmarks == nil {
+			cfg.Bookmarks = make(map[string][]string)
+		}
+		if len(keys) == 0 {
+			delete(cfg.Bookmarks, m.endpoint)
+		} else {
+			cfg.Bookmarks[m.endpoint] = keys
+		}
+	})
+	if err != nil {
+		m.status = fmt.Sprintf("Saving bookmark failed: %v", err)
+		return m
+	}
+	m.bookmarks = maps.Clone(m.bookmarks)
+	if m.bookmarks == nil {
+		m.bookmarks = make(map[string][]string)
+	}
+	m.bookmarks[m.endpoint] = keys
+	if m.isBookmarked(md.key) {
+		m.status = fmt.Sprintf("Bookmarked %s", md.key)
+	} else {
+		m.status = fmt.Sprintf("Removed the bookmark on %s", md.key)
+	}
+	m.sortMetrics(m.order())
+	m.enforcePageBounds()
+	return m
+}
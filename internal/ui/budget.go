@@ -0,0 +1,219 @@
+package ui
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jaxxstorm/met/internal/scrape"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// budgetSeries cuts a scrape of more series than --series-budget down,
+// rather than every series being tracked and drawn, which can lock up the
+// terminal. --over-budget picks how: a stable sample of the series, one row
+// per metric summing its series, or everything anyway, and by default met
+// asks, sampling until an answer is picked. It sets the sample rate
+// applyScrape keeps series at and returns the series to apply.
+func (m model) budgetSeries(series []scrapedSeries) (model, []scrapedSeries) {
+	m.exposed = len(series)
+	rate := 0.0
+	if m.overBudget() {
+		switch m.budgetMode {
+		case "aggregate":
+			series = aggregateFamilies(series)
+		case "ask":
+			if !m.budgetAsked && m.picker == nil && m.prompt == nil {
+				m = m.budgetPicker()
+				m.budgetAsked = true
+			}
+			rate = float64(m.seriesBudget) / float64(len(series))
+		case "sample":
+			rate = float64(m.seriesBudget) / float64(len(series))
+		}
+	}
+	if (rate == 0) != (m.sampleRate == 0) {
+		// Series left out of the sample, or kept in it, need checking
+		// again.
+		m.rejected = nil
+	}
+	m.sampleRate = rate
+	return m, series
+}
+
+// overBudget reports whether the endpoint exposed more series than the
+// budget on its last scrape.
+func (m model) overBudget() bool {
+	return m.seriesBudget > 0 && m.exposed > m.seriesBudget
+}
+
+// sampled reports whether the series key is in the sample kept while over
+// budget. Series are picked by a hash of their key, so the same ones are
+// kept from one scrape to the next.
+func (m model) sampled(key string) bool {
+	if m.sampleRate == 0 {
+		return true
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return float64(h.Sum64()) < m.sampleRate*math.MaxUint64
+}
+
+// budgetPicker asks what to do about an endpoint over budget.
+func (m model) budgetPicker() model {
+	sample := fmt.Sprintf("sample: show about %d of the %d series", m.seriesBudget, m.exposed)
+	aggregate := "aggregate: one row per metric, summing its series"
+	all := fmt.Sprintf("all: show every one of the %d series", m.exposed)
+	m.picker = &picker{
+		title: fmt.Sprintf("%s exposes %d series, over the budget of %d", m.endpoint, m.exposed, m.seriesBudget),
+		items: []string{sample, aggregate, all},
+		choose: func(m model, item string) (model, tea.Cmd) {
+			mode, _, _ := strings.Cut(item, ":")
+			m.budgetMode = mode
+			// Everything left out is checked again on the next
+			// scrape, and what the sample leaves out dropped now.
+			m.rejected = nil
+			switch mode {
+			case "sample":
+				m = m.dropUnsampled()
+				m.status = fmt.Sprintf("Showing a sample of about %d of the %d series", m.seriesBudget, m.exposed)
+			case "aggregate":
+				m.status = "Summing each metric's series into one row from the next scrape"
+			default:
+				m.status = fmt.Sprintf("Showing all %d series from the next scrape", m.exposed)
+			}
+			return m, nil
+		},
+	}
+	return m
+}
+
+// dropUnsampled removes the series shown that the sample leaves out.
+func (m model) dropUnsampled() model {
+	if m.sampleRate == 0 {
+		m.sampleRate = float64(m.seriesBudget) / float64(m.exposed)
+	}
+	list := make([]metricData, 0, len(m.metricsList))
+	index := make(map[string]int, len(m.metricsList))
+	for _, md := range m.metricsList {
+		if md.synthetic || m.sampled(md.key) {
+			index[md.key] = len(list)
+			list = append(list, md)
+		}
+	}
+	m.metricsList, m.metricsIndex = list, index
+	if m.selected >= m.visibleLen() {
+		m.selected = max(m.visibleLen()-1, 0)
+	}
+	m.enforcePageBounds()
+	return m
+}
+
+// renderBudget warns, under the table, that the endpoint is over budget and
+// what's being done about it.
+func (m model) renderBudget() string {
+	if !m.overBudget() {
+		return ""
+	}
+	var doing string
+	switch {
+	case m.sampleRate > 0:
+		doing = fmt.Sprintf("showing a %.0f%% sample", m.sampleRate*100)
+	case m.budgetMode == "aggregate":
+		doing = "showing a row per metric"
+	default:
+		doing = "showing every series"
+	}
+	return m.theme.status.Render(fmt.Sprintf("%d series exposed, over the budget of %d: %s, press W to change", m.exposed, m.seriesBudget, doing)) + "\n"
+}
+
+// aggregateFamilies collapses the series of every metric with more than
+// one into a single series, sum(name), summing their values.
+func aggregateFamilies(series []scrapedSeries) []scrapedSeries {
+	byFamily := make(map[*dto.MetricFamily]int)
+	for _, ss := range series {
+		byFamily[ss.mf]++
+	}
+	out := make([]scrapedSeries, 0, len(byFamily))
+	for _, ss := range series {
+		if byFamily[ss.mf] == 1 {
+			out = append(out, ss)
+		}
+	}
+	for mf, n := range byFamily {
+		if n == 1 {
+			continue
+		}
+		pm := mergeFamily(mf)
+		out = append(out, scrapedSeries{
+			key: "sum(" + mf.GetName() + ")",
+			mf:  mf,
+			pm:  pm,
+			raw: scrape.RawValue(mf, pm),
+		})
+	}
+	return out
+}
+
+// mergeFamily sums the series of mf into one. Histograms keep their buckets
+// when every series has the same classic ones.
+func mergeFamily(mf *dto.MetricFamily) *dto.Metric {
+	var sum, sampleSum float64
+	var count uint64
+	for _, pm := range mf.Metric {
+		switch mf.GetType() {
+		case dto.MetricType_SUMMARY:
+			sampleSum += pm.GetSummary().GetSampleSum()
+			count += pm.GetSummary().GetSampleCount()
+		case dto.MetricType_HISTOGRAM:
+			sampleSum += pm.GetHistogram().GetSampleSum()
+			count += pm.GetHistogram().GetSampleCount()
+		default:
+			sum += scrape.RawValue(mf, pm)
+		}
+	}
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		return &dto.Metric{Counter: &dto.Counter{Value: &sum}}
+	case dto.MetricType_GAUGE:
+		return &dto.Metric{Gauge: &dto.Gauge{Value: &sum}}
+	case dto.MetricType_SUMMARY:
+		return &dto.Metric{Summary: &dto.Summary{SampleSum: &sampleSum, SampleCount: &count}}
+	case dto.MetricType_HISTOGRAM:
+		return &dto.Metric{Histogram: &dto.Histogram{SampleSum: &sampleSum, SampleCount: &count, Bucket: sumBuckets(mf.Metric)}}
+	}
+	return &dto.Metric{Untyped: &dto.Untyped{Value: &sum}}
+}
+
+// sumBuckets sums the classic buckets of histogram series, or returns nil if
+// their bounds differ.
+func sumBuckets(ms []*dto.Metric) []*dto.Bucket {
+	counts := make(map[float64]uint64)
+	var bounds []float64
+	for i, pm := range ms {
+		buckets := pm.GetHistogram().GetBucket()
+		if i > 0 && len(buckets) != len(bounds) {
+			return nil
+		}
+		for _, b := range buckets {
+			ub := b.GetUpperBound()
+			if _, ok := counts[ub]; !ok {
+				if i > 0 {
+					return nil
+				}
+				bounds = append(bounds, ub)
+			}
+			counts[ub] += b.GetCumulativeCount()
+		}
+	}
+	sort.Float64s(bounds)
+	out := make([]*dto.Bucket, len(bounds))
+	for i, ub := range bounds {
+		c := counts[ub]
+		out[i] = &dto.Bucket{UpperBound: &ub, CumulativeCount: &c}
+	}
+	return out
+}
@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+type CardinalityCmd struct {
+	Limit int `help:"Maximum number of rows in each table, 0 for all" default:"20" short:"n"`
+}
+
+type labelCardinality struct {
+	name   string
+	values map[string]struct{}
+	series int
+}
+
+// runCardinality scrapes once and reports how many series each metric name
+// has and how many distinct values each label takes, largest first, to help
+// track down cardinality explosions.
+func runCardinality(w io.Writer, m model, limit int) error {
+	fams, err := m.scrape()
+	if err != nil {
+		return err
+	}
+	fams = m.filterFamilies(fams)
+
+	perMetric := make(map[string]int)
+	labels := make(map[string]*labelCardinality)
+	total := 0
+	for name, mf := range fams {
+		typ := strings.ToLower(mf.GetType().String())
+		forEachSeries(map[string]*dto.MetricFamily{name: mf}, func(_ string, lbls [][2]string, _ float64) {
+			total++
+			perMetric[name+"\x00"+typ]++
+			for _, l := range lbls {
+				lc, ok := labels[l[0]]
+				if !ok {
+					lc = &labelCardinality{name: l[0], values: make(map[string]struct{})}
+					labels[l[0]] = lc
+				}
+				lc.values[l[1]] = struct{}{}
+				lc.series++
+			}
+		})
+	}
+
+	fmt.Fprintf(w, "%d series across %d metrics from %s\n\n", total, len(fams), m.endpoint)
+
+	type metricCount struct {
+		name, typ string
+		series    int
+	}
+	metrics := make([]metricCount, 0, len(perMetric))
+	for k, n := range perMetric {
+		name, typ, _ := strings.Cut(k, "\x00")
+		metrics = append(metrics, metricCount{name, typ, n})
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].series == metrics[j].series {
+			return metrics[i].name < metrics[j].name
+		}
+		return metrics[i].series > metrics[j].series
+	})
+	table := newPlainTable(w, []string{"Metric", "Type", "Series", "% of Total"})
+	for i, mc := range metrics {
+		if limit > 0 && i >= limit {
+			break
+		}
+		table.Append([]string{
+			mc.name,
+			mc.typ,
+			strconv.Itoa(mc.series),
+			fmt.Sprintf("%.1f%%", float64(mc.series)/float64(total)*100),
+		})
+	}
+	table.Render()
+	fmt.Fprintln(w)
+
+	lcs := make([]*labelCardinality, 0, len(labels))
+	for _, lc := range labels {
+		lcs = append(lcs, lc)
+	}
+	sort.Slice(lcs, func(i, j int) bool {
+		if len(lcs[i].values) == len(lcs[j].values) {
+			return lcs[i].name < lcs[j].name
+		}
+		return len(lcs[i].values) > len(lcs[j].values)
+	})
+	table = newPlainTable(w, []string{"Label", "Distinct Values", "Series", "Examples"})
+	for i, lc := range lcs {
+		if limit > 0 && i >= limit {
+			break
+		}
+		table.Append([]string{lc.name, strconv.Itoa(len(lc.values)), strconv.Itoa(lc.series), exampleValues(lc.values, 3)})
+	}
+	table.Render()
+	return nil
+}
+
+func exampleValues(values map[string]struct{}, n int) string {
+	all := make([]string, 0, len(values))
+	for v := range values {
+		all = append(all, strconv.Quote(v))
+	}
+	sort.Strings(all)
+	if len(all) > n {
+		return strings.Join(all[:n], ", ") + ", ..."
+	}
+	return strings.Join(all, ", ")
+}
@@ -0,0 +1,186 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+type CheckCmd struct {
+	Assert []string `help:"Assertion to check, e.g. 'up == 1' or 'rate(http_errors_total) < 5' (repeatable)" required:"" sep:"none"`
+}
+
+// assertion compares two expressions, as in
+//
+//	queue_depth < 1000
+//
+// Either side can be anything --expr takes: numbers, selectors summing the
+// series they match, rate() and delta(), and arithmetic.
+type assertion struct {
+	source   string
+	op       string
+	lhs, rhs exprNode
+}
+
+// comparisons are the operators assertions can use, longest first so that
+// <= isn't read as <.
+var comparisons = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func parseAssertion(input string) (*assertion, error) {
+	p := &exprParser{selectorParser{input: strings.TrimSpace(input)}}
+	lhs, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	var op string
+	for _, c := range comparisons {
+		if p.consume(c) {
+			op = c
+			break
+		}
+	}
+	if op == "" {
+		return nil, p.errorf("expected one of %s", strings.Join(comparisons, " "))
+	}
+	rhs, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.done() {
+		return nil, p.errorf("unexpected trailing input")
+	}
+	return &assertion{source: p.input, op: op, lhs: lhs, rhs: rhs}, nil
+}
+
+// holds reports whether the assertion holds for a scrape, and the value of
+// its left-hand side.
+func (a *assertion) holds(fams map[string]*dto.MetricFamily, elapsed float64) (bool, float64) {
+	l, r := a.lhs.eval(fams, elapsed), a.rhs.eval(fams, elapsed)
+	switch a.op {
+	case "==":
+		return l == r, l
+	case "!=":
+		return l != r, l
+	case "<=":
+		return l <= r, l
+	case ">=":
+		return l >= r, l
+	case "<":
+		return l < r, l
+	}
+	return l > r, l
+}
+
+// seriesNodes lists the selectors an expression reads.
+func seriesNodes(n exprNode) []*seriesNode {
+	switch n := n.(type) {
+	case *seriesNode:
+		return []*seriesNode{n}
+	case negNode:
+		return seriesNodes(n.x)
+	case binaryNode:
+		return append(seriesNodes(n.lhs), seriesNodes(n.rhs)...)
+	}
+	return nil
+}
+
+// unmatched returns the first selector of the assertion that matches no
+// series, which would otherwise count as 0 and might pass unnoticed.
+func (a *assertion) unmatched(fams map[string]*dto.MetricFamily) string {
+	for _, n := range append(seriesNodes(a.lhs), seriesNodes(a.rhs)...) {
+		found := false
+		forEachSample(fams, func(name string, lbls []*dto.LabelPair, _ float64) {
+			found = found || n.sel.matches(name, lbls)
+		})
+		if !found {
+			return n.sel.source
+		}
+	}
+	return ""
+}
+
+// checkScrape scrapes m's endpoint, adding the up metric Prometheus would:
+// 1 if the scrape succeeded, 0 if it didn't. A failed scrape is reported
+// to w rather than stopping the check, so up == 1 can fail like any other
+// assertion.
+func checkScrape(w io.Writer, m model) (map[string]*dto.MetricFamily, time.Time) {
+	at := time.Now()
+	fams, err := m.scrape()
+	up := 1.0
+	if err != nil {
+		fmt.Fprintf(w, "Scraping %s failed: %v\n\n", m.endpoint, err)
+		fams, up = nil, 0
+	}
+	if _, ok := fams["up"]; !ok {
+		if fams == nil {
+			fams = make(map[string]*dto.MetricFamily)
+		}
+		fams["up"] = &dto.MetricFamily{
+			Name:   proto.String("up"),
+			Type:   dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: proto.Float64(up)}}},
+		}
+	}
+	return fams, at
+}
+
+// runCheck scrapes m's endpoint and prints whether each assertion holds. If
+// any use rate() or delta(), it scrapes again after m's interval to measure
+// them. It returns how many assertions failed.
+func runCheck(w io.Writer, m model, inputs []string) (int, error) {
+	asserts := make([]*assertion, len(inputs))
+	rates := false
+	for i, in := range inputs {
+		a, err := parseAssertion(in)
+		if err != nil {
+			return 0, fmt.Errorf("parsing assertion %q: %w", in, err)
+		}
+		asserts[i] = a
+		for _, n := range append(seriesNodes(a.lhs), seriesNodes(a.rhs)...) {
+			rates = rates || n.fn != ""
+		}
+	}
+
+	fams, at := checkScrape(w, m)
+	var elapsed float64
+	if rates {
+		// The first scrape only primes rate() and delta().
+		for _, a := range asserts {
+			a.holds(fams, 0)
+		}
+		time.Sleep(m.interval)
+		var next time.Time
+		fams, next = checkScrape(w, m)
+		elapsed = next.Sub(at).Seconds()
+	}
+
+	failed := 0
+	table := newPlainTable(w, []string{"Assertion", "Value", "Result"})
+	for _, a := range asserts {
+		ok, v := a.holds(fams, elapsed)
+		value, result := formatFloat(v), "ok"
+		if sel := a.unmatched(fams); sel != "" {
+			ok, value = false, "--"
+			result = "FAILED: no series match " + sel
+		} else if !ok {
+			result = "FAILED"
+		}
+		if !ok {
+			failed++
+		}
+		table.Append([]string{a.source, value, result})
+	}
+	table.Render()
+	if failed > 0 {
+		fmt.Fprintf(w, "\n%d of %d assertions failed against %s\n", failed, len(asserts), m.endpoint)
+	} else {
+		fmt.Fprintf(w, "\nAll %d assertions passed against %s\n", len(asserts), m.endpoint)
+	}
+	return failed, nil
+}
@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// labelValue returns the value md has for the --color-by label. Series
+// with the same value, such as the same code or instance, get the same
+// color from the theme's overlay palette, in the table and the graphs, so
+// 2xx and 5xx series stand apart.
+func (m model) labelValue(md metricData) (string, bool) {
+	if m.colorBy == "" || md.synthetic {
+		return "", false
+	}
+	for _, lp := range md.labelPairs {
+		if lp.GetName() == m.colorBy {
+			return lp.GetValue(), true
+		}
+	}
+	return "", false
+}
+
+// assignLabelColors gives values of the --color-by label seen for the first
+// time the next colors of the palette, in order, so a value keeps its color
+// for the whole session.
+func (m model) assignLabelColors() model {
+	var fresh []string
+	for _, md := range m.metricsList {
+		if v, ok := m.labelValue(md); ok {
+			if _, seen := m.labelColors[v]; !seen && !slices.Contains(fresh, v) {
+				fresh = append(fresh, v)
+			}
+		}
+	}
+	if len(fresh) == 0 {
+		return m
+	}
+	sort.Strings(fresh)
+	colors := maps.Clone(m.labelColors)
+	if colors == nil {
+		colors = make(map[string]int, len(fresh))
+	}
+	for _, v := range fresh {
+		colors[v] = len(colors)
+	}
+	m.labelColors = colors
+	return m
+}
+
+// labelColor returns the palette index of md's --color-by value, if it has
+// one.
+func (m model) labelColor(md metricData) (int, bool) {
+	v, ok := m.labelValue(md)
+	if !ok {
+		return 0, false
+	}
+	i, ok := m.labelColors[v]
+	return i, ok
+}
+
+// colorByPicker picks the label to color series by, from those of the
+// series shown, or stops coloring.
+func (m model) colorByPicker() model {
+	seen := make(map[string]bool)
+	var names []string
+	for _, md := range m.metricsList {
+		for _, lp := range md.labelPairs {
+			if !seen[lp.GetName()] {
+				seen[lp.GetName()] = true
+				names = append(names, lp.GetName())
+			}
+		}
+	}
+	sort.Strings(names)
+	if m.colorBy != "" {
+		names = append(names, "stop coloring by "+m.colorBy)
+	}
+	if len(names) == 0 {
+		m.status = "None of the series shown have labels to color by"
+		return m
+	}
+	m.picker = &picker{
+		title: "Color series by the value of a label",
+		items: names,
+		choose: func(m model, item string) (model, tea.Cmd) {
+			if strings.HasPrefix(item, "stop coloring by ") {
+				item = ""
+			}
+			return m.setColorBy(item), nil
+		},
+	}
+	return m
+}
+
+// setColorBy colors series by the label name, or stops coloring them if
+// it's empty.
+func (m model) setColorBy(name string) model {
+	m.colorBy = name
+	m.labelColors = nil
+	m = m.assignLabelColors()
+	if name == "" {
+		m.status = "No longer coloring series by a label"
+	} else {
+		m.status = fmt.Sprintf("Coloring series by %s", name)
+	}
+	return m
+}
@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/jaxxstorm/met/internal/scrape"
+)
+
+// config is the optional JSON configuration file. Everything in it is
+// optional; command line flags take precedence where both exist.
+type config struct {
+	Theme  string                 `json:"theme,omitempty"`
+	Themes map[string]themeConfig `json:"themes,omitempty"`
+
+	RelabelConfigs []scrape.RelabelConfig  `json:"relabel_configs,omitempty"`
+	Presets        map[string]filterPreset `json:"presets,omitempty"`
+	// Bookmarks holds the keys of the series bookmarked on each endpoint.
+	Bookmarks map[string][]string `json:"bookmarks,omitempty"`
+}
+
+// DefaultConfigPath is the config file read unless --config says
+// otherwise.
+func DefaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "met", "config.json")
+}
+
+// loadConfig reads the config file at path. A missing file is not an error
+// and yields an empty config.
+func loadConfig(path string) (config, error) {
+	var cfg config
+	if path == "" {
+		return cfg, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// updateConfig applies change to the config file at path, keeping the rest
+// of the file's settings.
+func updateConfig(path string, change func(*config)) error {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	change(&cfg)
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
@@ -0,0 +1,12 @@
+package ui
+
+type ConsulCmd struct {
+	Service     string   `help:"Consul service whose instances to scrape" required:""`
+	Tag         []string `help:"Only scrape instances with this tag (repeatable, ANDed)"`
+	Addr        string   `help:"Address of the Consul HTTP API" default:"http://127.0.0.1:8500" env:"CONSUL_HTTP_ADDR"`
+	Token       string   `help:"Consul ACL token" env:"CONSUL_HTTP_TOKEN"`
+	Datacenter  string   `help:"Consul datacenter to query, instead of the agent's own" short:"d"`
+	PassingOnly bool     `help:"Only scrape instances whose health checks are all passing"`
+	Scheme      string   `help:"Scheme to scrape instances with" enum:"http,https" default:"http"`
+	MetricsPath string   `help:"Path to scrape on every instance" default:"/metrics"`
+}
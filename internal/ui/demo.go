@@ -0,0 +1,5 @@
+package ui
+
+type DemoCmd struct {
+	Listen string `help:"Address the demo exporter listens on" default:"127.0.0.1:0"`
+}
@@ -0,0 +1,179 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// capture is every series' value at one moment, taken with A or B to see
+// what changed in between, e.g. while running a single request.
+type capture struct {
+	at     time.Time
+	series map[string]metricData
+}
+
+func (m model) capture(at time.Time) *capture {
+	c := &capture{at: at, series: make(map[string]metricData, len(m.metricsList))}
+	for _, md := range m.metricsList {
+		c.series[md.key] = md
+	}
+	return c
+}
+
+// seriesChange is a series whose value differs between captures A and B.
+type seriesChange struct {
+	md   metricData
+	a, b float64
+	// inA and inB are false for series missing from that capture.
+	inA, inB bool
+}
+
+func (c seriesChange) delta() float64 {
+	return c.b - c.a
+}
+
+// diffView lists the series that changed between captures A and B, largest
+// change first.
+type diffView struct {
+	cursor int
+}
+
+// captureA takes capture A, discarding any earlier B.
+func (m model) captureA() model {
+	m.capB = nil
+	m.capA = m.capture(time.Now())
+	m.status = fmt.Sprintf("Captured A at %s, press B to compare", m.capA.at.Format(time.TimeOnly))
+	return m
+}
+
+// captureB takes capture B and shows what changed since A.
+func (m model) captureB() model {
+	if m.capA == nil {
+		m.status = "Press A to capture the values to compare against first"
+		return m
+	}
+	m.capB = m.capture(time.Now())
+	m.diff = &diffView{}
+	m.status = ""
+	return m
+}
+
+// seriesChanges compares captures A and B, ordered by the size of the
+// change. Series that appeared or went away count as changing from or to
+// zero.
+func seriesChanges(a, b *capture) []seriesChange {
+	var changes []seriesChange
+	for key, md := range b.series {
+		c := seriesChange{md: md, b: md.Value, inB: true}
+		if amd, ok := a.series[key]; ok {
+			c.a, c.inA = amd.Value, true
+		}
+		if c.inA && (c.a == c.b || math.IsNaN(c.a) && math.IsNaN(c.b)) {
+			continue
+		}
+		changes = append(changes, c)
+	}
+	for key, md := range a.series {
+		if _, ok := b.series[key]; !ok {
+			changes = append(changes, seriesChange{md: md, a: md.Value, inA: true})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		di, dj := math.Abs(changes[i].delta()), math.Abs(changes[j].delta())
+		if di != dj {
+			return di > dj
+		}
+		return changes[i].md.key < changes[j].md.key
+	})
+	return changes
+}
+
+func (m model) updateDiff(msg tea.KeyMsg) (model, tea.Cmd) {
+	d := *m.diff
+	n := len(seriesChanges(m.capA, m.capB))
+	switch msg.String() {
+	case "esc", "backspace", "q", "ctrl+c", "B":
+		m.diff = nil
+		return m, nil
+	case "A":
+		m.diff = nil
+		return m.captureA(), nil
+	case "up", "k":
+		if d.cursor > 0 {
+			d.cursor--
+		}
+	case "down", "j":
+		if d.cursor < n-1 {
+			d.cursor++
+		}
+	case "enter":
+		changes := seriesChanges(m.capA, m.capB)
+		if d.cursor >= len(changes) {
+			break
+		}
+		m.diff = nil
+		if idx, ok := m.metricsIndex[changes[d.cursor].md.key]; ok && idx < m.visibleLen() {
+			m.selected = idx
+			m.enforcePageBounds()
+		}
+		return m, nil
+	}
+	m.diff = &d
+	return m, nil
+}
+
+// renderDiff shows the changes between captures A and B in place of the
+// table.
+func (m model) renderDiff() string {
+	a, b := m.capA, m.capB
+	changes := seriesChanges(a, b)
+	var sb strings.Builder
+	sb.WriteString(m.theme.title.Render(fmt.Sprintf("Changes between A (%s) and B (%s), %s apart",
+		a.at.Format(time.TimeOnly), b.at.Format(time.TimeOnly), b.at.Sub(a.at).Truncate(time.Millisecond))) + "\n\n")
+	if len(changes) == 0 {
+		sb.WriteString("No series changed.\n")
+		return sb.String()
+	}
+
+	var tsb strings.Builder
+	table := newPlainTable(&tsb, []string{"Key", "A", "B", "Change", "%"})
+	m.layout.configure(table)
+	cursor := min(m.diff.cursor, len(changes)-1)
+	start := 0
+	if cursor >= m.pageSize {
+		start = cursor - m.pageSize + 1
+	}
+	for i := start; i < len(changes) && i < start+m.pageSize; i++ {
+		c := changes[i]
+		format := m.formatter(c.md)
+		av, bv, pct := "--", "--", "--"
+		if c.inA {
+			av = format(c.a)
+		}
+		if c.inB {
+			bv = format(c.b)
+		}
+		switch {
+		case !c.inA:
+			pct = "new"
+		case !c.inB:
+			pct = "gone"
+		case c.a != 0:
+			pct = fmt.Sprintf("%+.1f%%", c.delta()/math.Abs(c.a)*100)
+		}
+		mark := " "
+		if i == cursor {
+			mark = ">"
+		}
+		table.Append([]string{mark + " " + c.md.key, av, bv, m.theme.delta(c.delta(), format), pct})
+	}
+	table.Render()
+	sb.WriteString(tsb.String())
+	sb.WriteString(fmt.Sprintf("\n%d of %d series changed\n", len(changes), len(b.series)))
+	return sb.String()
+}
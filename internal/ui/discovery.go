@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jaxxstorm/met/internal/scrape"
+)
+
+// discoveryMsg carries the targets found by a discoverer.
+type discoveryMsg struct {
+	targets []scrape.Target
+	err     error
+	// reload is set for discoveries run on SIGHUP, outside the usual
+	// schedule, which don't schedule the next one.
+	reload bool
+}
+
+func watchDiscoveryCmd(d scrape.Discoverer, interval time.Duration) tea.Cmd {
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		targets, err := d.Discover()
+		return discoveryMsg{targets: targets, err: err}
+	})
+}
+
+// itemPicker lets the user choose which of a list of endpoints, such as
+// Docker containers, to scrape before the tabs start.
+type itemPicker struct {
+	title  string
+	items  []string
+	chosen []bool
+	cursor int
+	done   bool
+}
+
+func (p itemPicker) Init() tea.Cmd {
+	return nil
+}
+
+func (p itemPicker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+	switch key.String() {
+	case "ctrl+c", "q", "esc":
+		return p, tea.Quit
+	case "enter":
+		p.done = true
+		return p, tea.Quit
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(p.items)-1 {
+			p.cursor++
+		}
+	case " ", "x":
+		p.chosen = append([]bool(nil), p.chosen...)
+		p.chosen[p.cursor] = !p.chosen[p.cursor]
+	case "a":
+		all := true
+		for _, c := range p.chosen {
+			all = all && c
+		}
+		p.chosen = make([]bool, len(p.items))
+		for i := range p.chosen {
+			p.chosen[i] = !all
+		}
+	}
+	return p, nil
+}
+
+func (p itemPicker) View() string {
+	if p.done {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(p.title + "\n\n")
+	for i, item := range p.items {
+		cursor := " "
+		if i == p.cursor {
+			cursor = ">"
+		}
+		check := "[ ]"
+		if p.chosen[i] {
+			check = "[x]"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s %s\n", cursor, check, item))
+	}
+	sb.WriteString("\n(↑/↓ to move, space to select, a to select all, Enter to start, q to quit)\n")
+	return sb.String()
+}
+
+// pickItems lets the user choose from items, returning the indexes of
+// those chosen, or none if they quit instead.
+func pickItems(title string, items []string) ([]int, error) {
+	res, err := tea.NewProgram(itemPicker{title: title, items: items, chosen: make([]bool, len(items))}).Run()
+	if err != nil {
+		return nil, err
+	}
+	p := res.(itemPicker)
+	if !p.done {
+		return nil, nil
+	}
+	var picked []int
+	for i, c := range p.chosen {
+		if c {
+			picked = append(picked, i)
+		}
+	}
+	return picked, nil
+}
@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/jaxxstorm/met/internal/scrape"
+)
+
+type DockerCmd struct {
+	Host      string   `help:"Docker daemon to list containers from" default:"unix:///var/run/docker.sock" env:"DOCKER_HOST"`
+	Container []string `help:"Scrape this container, by name, instead of picking from a list (repeatable)"`
+	All       bool     `help:"Scrape every container with a metrics port instead of picking from a list"`
+}
+
+// selectDockerTargets decides which targets to scrape: every one with
+// --all, those on the containers named with --container, or the ones
+// picked from a list.
+func selectDockerTargets(d *scrape.Docker, cmd DockerCmd) error {
+	if cmd.All {
+		return nil
+	}
+	if len(cmd.Container) > 0 {
+		d.Containers = make(map[string]bool, len(cmd.Container))
+		for _, n := range cmd.Container {
+			d.Containers[n] = true
+		}
+		return nil
+	}
+	targets, err := d.Targets()
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no running containers have TCP ports or metrics.port labels")
+	}
+	items := make([]string, len(targets))
+	for i, t := range targets {
+		items[i] = fmt.Sprintf("%s (%s) %s", t.Container, t.Image, t.Endpoint)
+	}
+	picked, err := pickItems("Containers to scrape", items)
+	if err != nil {
+		return err
+	}
+	if len(picked) == 0 {
+		return fmt.Errorf("no containers picked")
+	}
+	d.Selected = make(map[string]bool)
+	for _, i := range picked {
+		d.Selected[targets[i].Key()] = true
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// drillDown narrows the series of one metric name like a pivot table: they
+// are grouped by one label at a time, picked with ←/→, and Enter narrows to
+// the selected group before grouping by the next label.
+type drillDown struct {
+	name string
+	// path holds the label values narrowed to so far, outermost first.
+	path []labelFilter
+	// label is the index, into the remaining labels, of the one being
+	// grouped by.
+	label  int
+	cursor int
+	// parents holds the label and cursor of every level in path, to
+	// return to them on the way back out.
+	parents [][2]int
+}
+
+// drillGroup is the series sharing one value of the label being grouped by.
+type drillGroup struct {
+	value  string
+	series []metricData
+}
+
+// startDrill drills into the selected series' metric name.
+func (m model) startDrill() model {
+	if m.selected < 0 || m.selected >= len(m.metricsList) {
+		return m
+	}
+	md := m.metricsList[m.selected]
+	if md.synthetic {
+		m.status = "Expressions can't be drilled into"
+		return m
+	}
+	m.drill = &drillDown{name: md.name}
+	return m
+}
+
+// drillSeries returns the series of the drilled into name that match the
+// path so far.
+func (m model) drillSeries() []metricData {
+	var out []metricData
+	for _, md := range m.metricsList {
+		if md.name != m.drill.name || md.synthetic {
+			continue
+		}
+		ok := true
+		for _, lf := range m.drill.path {
+			if labelValue(md, lf.name) != lf.value {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			out = append(out, md)
+		}
+	}
+	return out
+}
+
+// drillLabels lists the labels of series that haven't been narrowed on yet.
+func (m model) drillLabels(series []metricData) []string {
+	used := make(map[string]bool, len(m.drill.path))
+	for _, lf := range m.drill.path {
+		used[lf.name] = true
+	}
+	seen := make(map[string]bool)
+	var labels []string
+	for _, md := range series {
+		for _, lp := range md.labelPairs {
+			if name := lp.GetName(); !used[name] && !seen[name] {
+				seen[name] = true
+				labels = append(labels, name)
+			}
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// drillGroups groups series by their value of label, ordered by value. With
+// no label left to group by, each series is its own group.
+func drillGroups(series []metricData, label string) []drillGroup {
+	if label == "" {
+		groups := make([]drillGroup, len(series))
+		for i, md := range series {
+			groups[i] = drillGroup{value: "{" + md.labels + "}", series: []metricData{md}}
+		}
+		return groups
+	}
+	byValue := make(map[string]int)
+	var groups []drillGroup
+	for _, md := range series {
+		v := labelValue(md, label)
+		i, ok := byValue[v]
+		if !ok {
+			i = len(groups)
+			byValue[v] = i
+			groups = append(groups, drillGroup{value: v})
+		}
+		groups[i].series = append(groups[i].series, md)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].value < groups[j].value })
+	return groups
+}
+
+func labelValue(md metricData, name string) string {
+	for _, lp := range md.labelPairs {
+		if lp.GetName() == name {
+			return lp.GetValue()
+		}
+	}
+	return ""
+}
+
+// drillState returns the current level's series, remaining labels, the label
+// being grouped by ("" once none are left) and its groups.
+func (m model) drillState() ([]metricData, []string, string, []drillGroup) {
+	series := m.drillSeries()
+	labels := m.drillLabels(series)
+	var label string
+	if len(labels) > 0 {
+		label = labels[min(m.drill.label, len(labels)-1)]
+	}
+	return series, labels, label, drillGroups(series, label)
+}
+
+func (m model) updateDrill(msg tea.KeyMsg) (model, tea.Cmd) {
+	d := *m.drill
+	_, labels, label, groups := m.drillState()
+	d.cursor = min(d.cursor, max(len(groups)-1, 0))
+	switch msg.String() {
+	case "esc", "backspace", "q":
+		if len(d.path) == 0 {
+			m.drill = nil
+			return m, nil
+		}
+		last := d.parents[len(d.parents)-1]
+		d.label, d.cursor = last[0], last[1]
+		d.path = d.path[:len(d.path)-1]
+		d.parents = d.parents[:len(d.parents)-1]
+	case "ctrl+c":
+		m.drill = nil
+		return m, nil
+	case "left", "h":
+		if len(labels) > 0 {
+			d.label = (min(d.label, len(labels)-1) + len(labels) - 1) % len(labels)
+			d.cursor = 0
+		}
+	case "right", "l":
+		if len(labels) > 0 {
+			d.label = (min(d.label, len(labels)-1) + 1) % len(labels)
+			d.cursor = 0
+		}
+	case "up", "k":
+		if d.cursor > 0 {
+			d.cursor--
+		}
+	case "down", "j":
+		if d.cursor < len(groups)-1 {
+			d.cursor++
+		}
+	case "enter":
+		if len(groups) == 0 {
+			break
+		}
+		g := groups[d.cursor]
+		if label == "" || len(g.series) == 1 {
+			// Down to a single series, so select it in the table.
+			m.drill = nil
+			if idx, ok := m.metricsIndex[g.series[0].key]; ok && idx < m.visibleLen() {
+				m.selected = idx
+				m.enforcePageBounds()
+			}
+			return m, nil
+		}
+		// Clipped so earlier copies of the model keep their own path.
+		d.parents = append(slices.Clip(d.parents), [2]int{d.label, d.cursor})
+		d.path = append(slices.Clip(d.path), labelFilter{name: label, value: g.value})
+		d.label, d.cursor = 0, 0
+	}
+	m.drill = &d
+	return m, nil
+}
+
+// renderDrill shows the drill-down in place of the table.
+func (m model) renderDrill() string {
+	d := m.drill
+	series, labels, label, groups := m.drillState()
+	var sb strings.Builder
+	lbls := make([]string, len(d.path))
+	for i, lf := range d.path {
+		lbls[i] = fmt.Sprintf("%s=%q", lf.name, lf.value)
+	}
+	sb.WriteString(m.theme.title.Render(fmt.Sprintf("Drilling into %s{%s} (%d series)", d.name, strings.Join(lbls, ","), len(series))) + "\n\n")
+
+	if len(labels) > 0 {
+		parts := make([]string, len(labels))
+		for i, l := range labels {
+			if l == label {
+				l = m.theme.title.Render("[" + l + "]")
+			}
+			parts[i] = l
+		}
+		sb.WriteString("Group by: " + strings.Join(parts, "  ") + "\n\n")
+	}
+
+	header := []string{"Series", "Value", "Rate/s"}
+	if label != "" {
+		header = []string{label, "Series", "Value", "Rate/s"}
+	}
+	var tsb strings.Builder
+	table := newPlainTable(&tsb, header)
+	m.layout.configure(table)
+	start := 0
+	if d.cursor >= m.pageSize {
+		start = d.cursor - m.pageSize + 1
+	}
+	for i := start; i < len(groups) && i < start+m.pageSize; i++ {
+		g := groups[i]
+		var value, rate float64
+		for _, md := range g.series {
+			value += md.Value
+			rate += md.Rate
+		}
+		format := m.formatter(g.series[0])
+		rateStr := "--"
+		if g.series[0].Counter {
+			rateStr = format(rate)
+		} else if !g.series[0].synthetic {
+			rateStr = arrowed(rate, format)
+		}
+		cursor := " "
+		if i == d.cursor {
+			cursor = ">"
+		}
+		name := g.value
+		if label != "" && name == "" {
+			name = "(none)"
+		}
+		row := []string{cursor + " " + name, format(value), rateStr}
+		if label != "" {
+			row = []string{cursor + " " + name, fmt.Sprint(len(g.series)), format(value), rateStr}
+		}
+		table.Append(row)
+	}
+	table.Render()
+	sb.WriteString(tsb.String())
+	if label != "" {
+		sb.WriteString(fmt.Sprintf("\n%d values of %s\n", len(groups), label))
+	}
+	return sb.String()
+}
@@ -0,0 +1,287 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jaxxstorm/met/internal/scrape"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// watchExpr is a named expression such as
+//
+//	error_ratio = rate(http_errors_total) / rate(http_requests_total)
+//
+// that is evaluated on every scrape and shown as a synthetic row.
+type watchExpr struct {
+	name   string
+	source string
+	root   exprNode
+}
+
+// exprNode is a node in an expression tree. Nodes that reference series
+// keep the previous scrape's sum so they can compute rate() and delta().
+type exprNode interface {
+	eval(families map[string]*dto.MetricFamily, elapsed float64) float64
+	// promQL renders the node as PromQL, taking rates over rng.
+	promQL(rng string) string
+}
+
+type numberNode float64
+
+func (n numberNode) eval(map[string]*dto.MetricFamily, float64) float64 {
+	return float64(n)
+}
+
+type negNode struct {
+	x exprNode
+}
+
+func (n negNode) eval(fams map[string]*dto.MetricFamily, elapsed float64) float64 {
+	return -n.x.eval(fams, elapsed)
+}
+
+type binaryNode struct {
+	op       byte
+	lhs, rhs exprNode
+}
+
+func (n binaryNode) eval(fams map[string]*dto.MetricFamily, elapsed float64) float64 {
+	l, r := n.lhs.eval(fams, elapsed), n.rhs.eval(fams, elapsed)
+	switch n.op {
+	case '+':
+		return l + r
+	case '-':
+		return l - r
+	case '*':
+		return l * r
+	}
+	if r == 0 {
+		return math.NaN()
+	}
+	return l / r
+}
+
+// seriesNode sums every sample matching sel. With fn set to "rate" or
+// "delta" it returns the per-second or per-scrape change of that sum,
+// treating a decrease as a counter reset.
+type seriesNode struct {
+	fn   string
+	sel  selector
+	prev float64
+	seen bool
+}
+
+func (n *seriesNode) eval(fams map[string]*dto.MetricFamily, elapsed float64) float64 {
+	var sum float64
+	forEachSample(fams, func(name string, lbls []*dto.LabelPair, v float64) {
+		if n.sel.matches(name, lbls) {
+			sum += v
+		}
+	})
+	if n.fn == "" {
+		return sum
+	}
+	prev, seen := n.prev, n.seen
+	n.prev, n.seen = sum, true
+	if !seen {
+		return 0
+	}
+	inc := sum - prev
+	if inc < 0 {
+		inc = sum
+	}
+	if n.fn == "delta" {
+		return inc
+	}
+	if elapsed <= 0 {
+		return 0
+	}
+	return inc / elapsed
+}
+
+// cloneExprs copies exprs with their rate() and delta() state, so models
+// copied for tabs each keep their own previous sums.
+func cloneExprs(exprs []*watchExpr) []*watchExpr {
+	out := make([]*watchExpr, len(exprs))
+	for i, ex := range exprs {
+		out[i] = &watchExpr{name: ex.name, source: ex.source, root: cloneNode(ex.root)}
+	}
+	return out
+}
+
+func cloneNode(n exprNode) exprNode {
+	switch n := n.(type) {
+	case *seriesNode:
+		c := *n
+		return &c
+	case negNode:
+		return negNode{x: cloneNode(n.x)}
+	case binaryNode:
+		return binaryNode{op: n.op, lhs: cloneNode(n.lhs), rhs: cloneNode(n.rhs)}
+	}
+	return n
+}
+
+// forEachSample visits every sample in a scrape. Histograms and summaries
+// are also exposed under their _sum and _count names so they can be used
+// in expressions the same way as in PromQL.
+func forEachSample(fams map[string]*dto.MetricFamily, fn func(name string, lbls []*dto.LabelPair, v float64)) {
+	for name, mf := range fams {
+		for _, pm := range mf.Metric {
+			fn(name, pm.Label, scrape.RawValue(mf, pm))
+			switch mf.GetType() {
+			case dto.MetricType_HISTOGRAM:
+				fn(name+"_sum", pm.Label, pm.GetHistogram().GetSampleSum())
+				fn(name+"_count", pm.Label, float64(pm.GetHistogram().GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				fn(name+"_sum", pm.Label, pm.GetSummary().GetSampleSum())
+				fn(name+"_count", pm.Label, float64(pm.GetSummary().GetSampleCount()))
+			}
+		}
+	}
+}
+
+// parseWatchExpr parses "name = expression". Expressions support numbers,
+// + - * / and parentheses, bare selectors (the sum of matching series), and
+// the rate() and delta() functions.
+func parseWatchExpr(input string) (*watchExpr, error) {
+	p := &exprParser{selectorParser{input: strings.TrimSpace(input)}}
+	name := p.ident()
+	if name == "" {
+		return nil, p.errorf("expected expression name")
+	}
+	p.skipSpace()
+	if !p.consume("=") {
+		return nil, p.errorf("expected '=' after expression name")
+	}
+	p.skipSpace()
+	source := p.input[p.pos:]
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.done() {
+		return nil, p.errorf("unexpected trailing input")
+	}
+	return &watchExpr{name: name, source: source, root: root}, nil
+}
+
+type exprParser struct {
+	selectorParser
+}
+
+func (p *exprParser) expr() (exprNode, error) {
+	lhs, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if p.done() || (p.input[p.pos] != '+' && p.input[p.pos] != '-') {
+			return lhs, nil
+		}
+		op := p.input[p.pos]
+		p.pos++
+		rhs, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		lhs = binaryNode{op: op, lhs: lhs, rhs: rhs}
+	}
+}
+
+func (p *exprParser) term() (exprNode, error) {
+	lhs, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if p.done() || (p.input[p.pos] != '*' && p.input[p.pos] != '/') {
+			return lhs, nil
+		}
+		op := p.input[p.pos]
+		p.pos++
+		rhs, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = binaryNode{op: op, lhs: lhs, rhs: rhs}
+	}
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	p.skipSpace()
+	if p.consume("-") {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{x}, nil
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+	p.skipSpace()
+	if p.done() {
+		return nil, p.errorf("unexpected end of expression")
+	}
+	c := p.input[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.consume(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return x, nil
+	case c == '.' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for !p.done() && strings.IndexByte("0123456789.eE", p.input[p.pos]) >= 0 {
+			p.pos++
+			// An exponent can be signed, as in 1e-3.
+			if c := p.input[p.pos-1]; (c == 'e' || c == 'E') && !p.done() && (p.input[p.pos] == '+' || p.input[p.pos] == '-') {
+				p.pos++
+			}
+		}
+		f, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q: %w", p.input[start:p.pos], err)
+		}
+		return numberNode(f), nil
+	}
+
+	start := p.pos
+	fn := p.ident()
+	p.skipSpace()
+	if (fn == "rate" || fn == "delta") && p.consume("(") {
+		p.skipSpace()
+		selStart := p.pos
+		sel, err := p.selector()
+		if err != nil {
+			return nil, err
+		}
+		sel.source = strings.TrimSpace(p.input[selStart:p.pos])
+		p.skipSpace()
+		if !p.consume(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return &seriesNode{fn: fn, sel: sel}, nil
+	}
+	p.pos = start
+	sel, err := p.selector()
+	if err != nil {
+		return nil, err
+	}
+	sel.source = strings.TrimSpace(p.input[start:p.pos])
+	return &seriesNode{sel: sel}, nil
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jaxxstorm/met/pkg/met"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// counterFamilies makes a scrape of a requests_total counter with a series
+// per code.
+func counterFamilies(byCode map[string]float64) map[string]*dto.MetricFamily {
+	mf := &dto.MetricFamily{Name: proto.String("requests_total"), Type: dto.MetricType_COUNTER.Enum()}
+	for code, v := range byCode {
+		mf.Metric = append(mf.Metric, &dto.Metric{
+			Label:   []*dto.LabelPair{met.LabelPair("code", code)},
+			Counter: &dto.Counter{Value: proto.Float64(v)},
+		})
+	}
+	return map[string]*dto.MetricFamily{"requests_total": mf}
+}
+
+func TestParseWatchExpr(t *testing.T) {
+	fams := counterFamilies(map[string]float64{"200": 6, "500": 2})
+	tests := []struct {
+		input string
+		name  string
+		want  float64
+	}{
+		{"x = 1 + 2 * 3", "x", 7},
+		{"x = (1 + 2) * 3", "x", 9},
+		{"x = 2 * 3 + 1", "x", 7},
+		{"x = 10 - 4 - 3", "x", 3},
+		{"x = 8 / 2 / 2", "x", 2},
+		{"x = -2 * 3 + 1", "x", -5},
+		{"x = 1 - -1", "x", 2},
+		{"x = 1.5e2", "x", 150},
+		{"x = 1e-3", "x", 0.001},
+		{"x = 2.5E+6", "x", 2.5e6},
+		{"x = 1e-3 * 1000", "x", 1},
+		{"x = 2e+1 - 1", "x", 19},
+		{"x = 3 - 1e1", "x", -7},
+		{"total = requests_total", "total", 8},
+		{`errors = requests_total{code=~"5.."}`, "errors", 2},
+		{`ratio = requests_total{code="500"} / requests_total`, "ratio", 0.25},
+		{`pct = 100 * requests_total{code="500"} / requests_total`, "pct", 25},
+		{"x=requests_total*2", "x", 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ex, err := parseWatchExpr(tt.input)
+			if err != nil {
+				t.Fatalf("parseWatchExpr(%q) failed: %v", tt.input, err)
+			}
+			if ex.name != tt.name {
+				t.Errorf("name = %q, want %q", ex.name, tt.name)
+			}
+			if got := ex.root.eval(fams, 1); got != tt.want {
+				t.Errorf("eval = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWatchExprDivideByZero(t *testing.T) {
+	ex, err := parseWatchExpr("x = 1 / 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ex.root.eval(nil, 1); !math.IsNaN(got) {
+		t.Errorf("eval = %v, want NaN", got)
+	}
+}
+
+func TestParseWatchExprErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"x",
+		"= 1",
+		"x = ",
+		"x = 1 +",
+		"x = (1 + 2",
+		"x = rate(requests_total",
+		"x = 1 2",
+		"x = 1..2",
+		"x = 1e-",
+	}
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			if _, err := parseWatchExpr(input); err == nil {
+				t.Errorf("parseWatchExpr(%q) succeeded, want an error", input)
+			}
+		})
+	}
+}
+
+func TestWatchExprRate(t *testing.T) {
+	// Scrapes 5s apart, with the counter reset between the third and
+	// fourth.
+	scrapes := []float64{10, 15, 25, 3, 8}
+	tests := []struct {
+		input string
+		want  []float64
+	}{
+		{"x = rate(requests_total)", []float64{0, 1, 2, 0.6, 1}},
+		{"x = delta(requests_total)", []float64{0, 5, 10, 3, 5}},
+		{"x = requests_total", []float64{10, 15, 25, 3, 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ex, err := parseWatchExpr(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, v := range scrapes {
+				got := ex.root.eval(counterFamilies(map[string]float64{"200": v}), 5)
+				if math.Abs(got-tt.want[i]) > 1e-9 {
+					t.Errorf("scrape %d: eval = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCloneExprs(t *testing.T) {
+	ex, err := parseWatchExpr("x = rate(requests_total)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex.root.eval(counterFamilies(map[string]float64{"200": 10}), 1)
+	clone := cloneExprs([]*watchExpr{ex})[0]
+	ex.root.eval(counterFamilies(map[string]float64{"200": 100}), 1)
+	if got := clone.root.eval(counterFamilies(map[string]float64{"200": 12}), 1); got != 2 {
+		t.Errorf("clone's rate = %v, want 2 from its own previous sum", got)
+	}
+}
@@ -0,0 +1,158 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// filterKind is which of the model's filters a prompt edits.
+type filterKind int
+
+const (
+	filterInclude filterKind = iota
+	filterExclude
+	filterLabel
+)
+
+var filterKindNames = map[filterKind]string{
+	filterInclude: "include",
+	filterExclude: "exclude",
+	filterLabel:   "label",
+}
+
+func (k filterKind) String() string {
+	return filterKindNames[k]
+}
+
+// toggleFilter adds value to the filters of kind, or removes it if it's
+// already there.
+func toggleFilter(p filterPreset, kind filterKind, value string) (filterPreset, bool) {
+	list := &p.Include
+	switch kind {
+	case filterExclude:
+		list = &p.Exclude
+	case filterLabel:
+		list = &p.Labels
+	}
+	// Copied, as the preset shares its slices with the model.
+	values := slices.Clone(*list)
+	i := slices.Index(values, value)
+	added := i < 0
+	if added {
+		values = append(values, value)
+	} else {
+		values = slices.Delete(values, i, i+1)
+	}
+	*list = values
+	return p, added
+}
+
+// filterPrompt asks for a filter of kind to add, or an existing one to
+// remove. Rows that no longer match go straight away, and newly matching
+// series appear with the next scrape.
+func (m model) filterPrompt(kind filterKind) model {
+	label := map[filterKind]string{
+		filterInclude: "Include metrics containing",
+		filterExclude: "Exclude metrics containing",
+		filterLabel:   "Show only series with label (name=value)",
+	}[kind]
+	m.prompt = &prompt{
+		label: label + ", or an existing filter to remove it",
+		submit: func(m model, value string) (model, tea.Cmd) {
+			if value == "" {
+				return m, nil
+			}
+			return m.toggleFilterValue(kind, value), nil
+		},
+	}
+	if kind == filterLabel {
+		m.prompt.complete = completeLabelFilter
+	}
+	return m
+}
+
+// toggleFilterValue adds value to the filters of kind, or removes it if it's
+// already there.
+func (m model) toggleFilterValue(kind filterKind, value string) model {
+	if kind == filterLabel {
+		if _, err := parseLabelFilters([]string{value}); err != nil {
+			m.status = err.Error()
+			return m
+		}
+	}
+	p, added := toggleFilter(m.currentPreset(), kind, value)
+	nm, err := m.applyPreset(p)
+	if err != nil {
+		m.status = fmt.Sprintf("Changing filters failed: %v", err)
+		return m
+	}
+	if added {
+		nm.status = fmt.Sprintf("Added %s filter %q", kind, value)
+	} else {
+		nm.status = fmt.Sprintf("Removed %s filter %q", kind, value)
+	}
+	return nm
+}
+
+// completeLabelFilter completes label names, and then the values of the
+// named label, from the series being shown, along with the label filters
+// already set so they're easy to remove.
+func completeLabelFilter(m model, value string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	add := func(s string) {
+		if strings.HasPrefix(s, value) && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	for _, lf := range m.labelFilters {
+		add(lf.name + "=" + lf.value)
+	}
+	name, _, named := strings.Cut(value, "=")
+	for _, md := range m.metricsList {
+		for _, lp := range md.labelPairs {
+			switch {
+			case !named:
+				add(lp.GetName() + "=")
+			case lp.GetName() == name:
+				add(lp.GetName() + "=" + lp.GetValue())
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// renderFilters describes the active filters, if there are any.
+func (m model) renderFilters() string {
+	var parts []string
+	if len(m.includes) > 0 {
+		parts = append(parts, "include "+strings.Join(m.includes, ", "))
+	}
+	if len(m.excludes) > 0 {
+		parts = append(parts, "exclude "+strings.Join(m.excludes, ", "))
+	}
+	if len(m.labelFilters) > 0 {
+		lbls := make([]string, len(m.labelFilters))
+		for i, lf := range m.labelFilters {
+			lbls[i] = lf.name + "=" + lf.value
+		}
+		parts = append(parts, "label "+strings.Join(lbls, ", "))
+	}
+	if len(m.selectors) > 0 {
+		sels := make([]string, len(m.selectors))
+		for i, sel := range m.selectors {
+			sels[i] = sel.source
+		}
+		parts = append(parts, "select "+strings.Join(sels, ", "))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "Filters: " + strings.Join(parts, " · ") + "\n"
+}
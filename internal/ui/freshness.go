@@ -0,0 +1,40 @@
+package ui
+
+import "time"
+
+// frozenScrapes is how many scrape intervals a sample's exposed timestamp
+// can go without advancing before the series is flagged as frozen, as
+// happens with exporters serving a cached or wedged value.
+const frozenScrapes = 3
+
+// isFrozen reports whether md's samples carry a timestamp that has stopped
+// advancing.
+func (m model) isFrozen(md metricData) bool {
+	if md.sampleAt.IsZero() || md.stale {
+		return false
+	}
+	return m.lastScrape.Sub(md.stampMovedAt) >= frozenScrapes*m.interval
+}
+
+// ageCell shows how old md's latest sample was when it was scraped, going by
+// its exposed timestamp.
+func (m model) ageCell(md metricData) string {
+	if md.sampleAt.IsZero() {
+		return "--"
+	}
+	age := formatSeconds(max(m.lastScrape.Sub(md.sampleAt), 0).Seconds())
+	if m.isFrozen(md) {
+		return m.theme.negative.Render(age)
+	}
+	return age
+}
+
+// observeTimestamp records the timestamp exposed with a sample, if it has
+// one, noting when it last moved.
+func (md *metricData) observeTimestamp(ms int64, at time.Time) {
+	stamp := time.UnixMilli(ms)
+	if !stamp.Equal(md.sampleAt) {
+		md.sampleAt = stamp
+		md.stampMovedAt = at
+	}
+}
@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"gopkg.in/yaml.v3"
+)
+
+type GetCmd struct {
+	Output string `help:"Output format: table, json or yaml" enum:"table,json,yaml" default:"table" short:"o"`
+}
+
+// sample is a single series as printed by met get.
+type sample struct {
+	Name   string            `json:"name" yaml:"name"`
+	Labels map[string]string `json:"labels" yaml:"labels"`
+	Type   string            `json:"type" yaml:"type"`
+	Value  sampleValue       `json:"value" yaml:"value"`
+}
+
+// sampleValue is a float that encodes NaN and ±Inf, which JSON has no
+// numbers for, as the strings Prometheus uses.
+type sampleValue float64
+
+func (v sampleValue) MarshalJSON() ([]byte, error) {
+	f := float64(v)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return json.Marshal(formatFloat(f))
+	}
+	return []byte(strconv.FormatFloat(f, 'g', -1, 64)), nil
+}
+
+// runGet scrapes once and prints every series passing the filters, with
+// histograms and summaries expanded the way Prometheus stores them.
+func runGet(w io.Writer, m model, output string) error {
+	fams, err := m.scrape()
+	if err != nil {
+		return err
+	}
+	samples := collectSamples(m.filterFamilies(fams))
+
+	switch output {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(samples)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(samples); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	table := newPlainTable(w, []string{"Series", "Type", "Value"})
+	for _, s := range samples {
+		table.Append([]string{s.key(), s.Type, formatFloat(float64(s.Value))})
+	}
+	table.Render()
+	return nil
+}
+
+// collectSamples expands fams into samples, sorted by key.
+func collectSamples(fams map[string]*dto.MetricFamily) []sample {
+	samples := []sample{}
+	for name, mf := range fams {
+		typ := strings.ToLower(mf.GetType().String())
+		forEachSeries(map[string]*dto.MetricFamily{name: mf}, func(n string, lbls [][2]string, v float64) {
+			s := sample{Name: n, Labels: make(map[string]string, len(lbls)), Type: typ, Value: sampleValue(v)}
+			for _, l := range lbls {
+				s.Labels[l[0]] = l[1]
+			}
+			samples = append(samples, s)
+		})
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].key() < samples[j].key()
+	})
+	return samples
+}
+
+// key renders the sample as name{labels}, with labels sorted by name.
+func (s sample) key() string {
+	names := make([]string, 0, len(s.Labels))
+	for n := range s.Labels {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, n := range names {
+		parts[i] = n + `="` + labelValueEscaper.Replace(s.Labels[n]) + `"`
+	}
+	return s.Name + "{" + strings.Join(parts, ",") + "}"
+}
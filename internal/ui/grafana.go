@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// grafanaRateInterval is the range exported rates are taken over, which
+// Grafana sizes to the data source's scrape interval.
+const grafanaRateInterval = "$__rate_interval"
+
+// grafanaUnit maps a series' unit onto a Grafana unit id, for the values
+// its PromQL returns.
+func grafanaUnit(md metricData) string {
+	switch {
+	case md.unit == unitSeconds && (md.bucketBounds != nil || !md.Counter):
+		return "s"
+	case md.unit == unitBytes && md.Counter:
+		return "Bps"
+	case md.unit == unitBytes:
+		return "bytes"
+	}
+	return "short"
+}
+
+// grafanaDashboard builds a dashboard with a single time series panel
+// graphing the selected series' metric, narrowed by the current filters.
+// The panel can be imported on its own dashboard, or copied out of it.
+func (m model) grafanaDashboard(md metricData) ([]byte, error) {
+	title := md.name
+	if md.bucketBounds != nil {
+		title = fmt.Sprintf("%s %s", md.name, quantileName(m.quantile))
+	}
+	panel := map[string]any{
+		"type":       "timeseries",
+		"title":      title,
+		"datasource": map[string]any{"type": "prometheus", "uid": "${datasource}"},
+		"gridPos":    map[string]any{"h": 8, "w": 12, "x": 0, "y": 0},
+		"targets": []any{map[string]any{
+			"refId":        "A",
+			"expr":         m.seriesPromQL(md, grafanaRateInterval),
+			"legendFormat": "__auto",
+		}},
+		"fieldConfig": map[string]any{
+			"defaults":  map[string]any{"unit": grafanaUnit(md)},
+			"overrides": []any{},
+		},
+	}
+	dashboard := map[string]any{
+		"title":         "met: " + md.name,
+		"schemaVersion": 39,
+		"time":          map[string]any{"from": "now-1h", "to": "now"},
+		"panels":        []any{panel},
+		"templating": map[string]any{"list": []any{map[string]any{
+			"name":  "datasource",
+			"label": "Data source",
+			"type":  "datasource",
+			"query": "prometheus",
+		}}},
+	}
+	return json.MarshalIndent(dashboard, "", "  ")
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// grafanaExportCmd writes a Grafana dashboard for md next to snapshots, or
+// copies it to the clipboard.
+func (m model) grafanaExportCmd(md metricData, at time.Time) tea.Cmd {
+	b, err := m.grafanaDashboard(md)
+	if err != nil {
+		return func() tea.Msg { return statusMsg(fmt.Sprintf("Grafana export failed: %v", err)) }
+	}
+	name := fmt.Sprintf("met-grafana-%s-%s.json", unsafeFileChars.ReplaceAllString(md.name, "_"), at.Format("20060102-150405"))
+	return saveCmd(string(b)+"\n", filepath.Join(m.snapshotDir, name), "Grafana dashboard", m.snapshotClipboard)
+}
@@ -0,0 +1,236 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultGraphWidth is how many columns graphs are plotted across
+	// before the terminal's width is known.
+	defaultGraphWidth = 70
+	minGraphWidth     = 20
+	// timeTickSpacing is roughly how many columns apart the time axis
+	// is labelled.
+	timeTickSpacing = 20
+	// autoLogRatio is how many times larger the largest plotted value must
+	// be than the smallest for the auto scale to switch to log.
+	autoLogRatio = 1000
+)
+
+// graphMode selects what the graph plots for the selected series.
+type graphMode int
+
+const (
+	// graphCumulative plots the values as scraped, with counters
+	// accumulated across resets.
+	graphCumulative graphMode = iota
+	// graphDelta plots the change between consecutive scrapes.
+	graphDelta
+	// graphRate plots the change per second between consecutive scrapes.
+	graphRate
+	// graphQuantile plots a histogram's estimated --quantile over the
+	// observations made between consecutive scrapes.
+	graphQuantile
+	numGraphModes
+)
+
+func (g graphMode) String() string {
+	switch g {
+	case graphDelta:
+		return "delta"
+	case graphRate:
+		return "rate/s"
+	case graphQuantile:
+		return "quantile"
+	}
+	return "cumulative"
+}
+
+// graphScale selects the graph's y-axis scale.
+type graphScale int
+
+const (
+	scaleLinear graphScale = iota
+	scaleLog
+	// scaleAuto is log when the values span several orders of magnitude,
+	// and linear otherwise.
+	scaleAuto
+	numGraphScales
+)
+
+var graphScaleNames = map[string]graphScale{
+	"linear": scaleLinear,
+	"log":    scaleLog,
+	"auto":   scaleAuto,
+}
+
+func (s graphScale) String() string {
+	for name, v := range graphScaleNames {
+		if v == s {
+			return name
+		}
+	}
+	return "linear"
+}
+
+func parseGraphScale(s string) (graphScale, error) {
+	gs, ok := graphScaleNames[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown graph scale %q, want linear, log or auto", s)
+	}
+	return gs, nil
+}
+
+// isLog reports whether points are plotted on a log scale.
+func (s graphScale) isLog(points []float64) bool {
+	switch s {
+	case scaleLog:
+		return true
+	case scaleAuto:
+		lo, hi := minMax(points)
+		return lo > 0 && hi/lo >= autoLogRatio
+	}
+	return false
+}
+
+// log10Points returns the base 10 logarithm of each point. Zero and
+// negative values can't be plotted on a log scale, so become gaps.
+func log10Points(points []float64) []float64 {
+	out := make([]float64, len(points))
+	for i, v := range points {
+		out[i] = math.NaN()
+		if v > 0 {
+			out[i] = math.Log10(v)
+		}
+	}
+	return out
+}
+
+// relabelAxis rewrites the y-axis labels of a graph plotted by asciigraph
+// with its axis at column axis, passing each label's value through label.
+// It returns the graph and the new column of its axis.
+func relabelAxis(graph string, axis int, label func(float64) string) (string, int) {
+	lines := strings.Split(graph, "\n")
+	labels := make([]string, len(lines))
+	width := 0
+	for i, line := range lines {
+		r := []rune(line)
+		if len(r) <= axis {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(string(r[:axis])), 64)
+		if err != nil {
+			continue
+		}
+		labels[i] = label(v)
+		width = max(width, len(labels[i]))
+	}
+	for i, line := range lines {
+		r := []rune(line)
+		if len(r) <= axis {
+			continue
+		}
+		lines[i] = fmt.Sprintf("%*s %s", width, labels[i], string(r[axis:]))
+	}
+	return strings.Join(lines, "\n"), width + 1
+}
+
+// points returns the values to plot for md in mode g. Derivative modes have
+// one point fewer than the history.
+func (g graphMode) points(md metricData) []float64 {
+	if g == graphCumulative {
+		return md.History
+	}
+	var out []float64
+	for i := 1; i < len(md.History); i++ {
+		d := md.History[i] - md.History[i-1]
+		if g == graphRate {
+			dt := md.HistoryAt[i].Sub(md.HistoryAt[i-1]).Seconds()
+			if dt <= 0 {
+				continue
+			}
+			d /= dt
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// graphModeName names the graph mode, spelling out the quantile.
+func (m model) graphModeName() string {
+	if m.graphMode == graphQuantile {
+		return quantileName(m.quantile)
+	}
+	return m.graphMode.String()
+}
+
+// graphPoints returns the values to plot for md in the current graph mode.
+func (m model) graphPoints(md metricData) []float64 {
+	if m.graphMode == graphQuantile {
+		return quantileTrend(md, m.quantile)
+	}
+	return m.graphMode.points(md)
+}
+
+// minMax returns the smallest and largest of vs, ignoring NaNs.
+func minMax(vs []float64) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range vs {
+		if math.IsNaN(v) {
+			continue
+		}
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	return lo, hi
+}
+
+// graphTimes returns the scrape times of the n points graphPoints returned
+// for md, which are its most recent.
+func graphTimes(md metricData, n int) []time.Time {
+	if n > len(md.HistoryAt) {
+		n = len(md.HistoryAt)
+	}
+	return md.HistoryAt[len(md.HistoryAt)-n:]
+}
+
+// axisColumn returns the column of the y-axis in a graph plotted by
+// asciigraph, which is as far in as its widest value label.
+func axisColumn(graph string) int {
+	line, _, _ := strings.Cut(graph, "\n")
+	for i, r := range []rune(line) {
+		if r == '┤' || r == '┼' {
+			return i
+		}
+	}
+	return 0
+}
+
+// renderTimeAxis draws an x-axis under a graph whose y-axis is at column
+// axis and which is plotted across width columns, with ticks spaced evenly
+// from the first point to the last, each labelled with the time of the
+// point above it.
+func renderTimeAxis(times []time.Time, axis, width int) string {
+	if len(times) == 0 || width < 1 {
+		return ""
+	}
+	line := []rune(strings.Repeat(" ", axis) + "└" + strings.Repeat("─", width-1))
+	labels := []rune(strings.Repeat(" ", max(axis+width, len(time.TimeOnly))))
+	ticks := max((width-1)/timeTickSpacing, 1)
+	for i := 0; i <= ticks; i++ {
+		x := int(math.Round(float64(i) * float64(width-1) / float64(ticks)))
+		idx := int(math.Round(float64(x) * float64(len(times)-1) / float64(max(width-1, 1))))
+		if x > 0 {
+			line[axis+x] = '┴'
+		}
+		label := times[idx].Format(time.TimeOnly)
+		// Centred under the tick, but kept within the graph's width.
+		start := max(min(axis+x-len(label)/2, len(labels)-len(label)), 0)
+		copy(labels[start:], []rune(label))
+	}
+	return string(line) + "\n" + strings.TrimRight(string(labels), " ")
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/met/internal/scrape"
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+func (m *model) recordHealth(s scrape.Stats) {
+	m.health = append(m.health, s)
+	if len(m.health) > maxHistory {
+		m.health = m.health[len(m.health)-maxHistory:]
+	}
+}
+
+// renderHealthBar summarizes the last scrape, with a sparkline of recent
+// scrape durations and a mark per recent scrape: . for success, x for a
+// failure.
+func (m model) renderHealthBar() string {
+	if len(m.health) == 0 {
+		return ""
+	}
+	last := m.health[len(m.health)-1]
+	var slowest time.Duration
+	for _, s := range m.health {
+		slowest = max(slowest, s.Duration)
+	}
+	var spark, results strings.Builder
+	for _, s := range m.health {
+		idx := 0
+		if slowest > 0 {
+			idx = int(float64(s.Duration) / float64(slowest) * float64(len(sparkBlocks)-1))
+		}
+		spark.WriteRune(sparkBlocks[idx])
+		if s.Err != nil {
+			results.WriteString(m.theme.negative.Render("x"))
+		} else {
+			results.WriteString(".")
+		}
+	}
+	bar := fmt.Sprintf("Last scrape: %s  %s %s", strings.Join(statsSummary(last), " · "), spark.String(), results.String())
+	if mem := m.renderMemory(); mem != "" {
+		bar += "  " + mem
+	}
+	return bar
+}
+
+// statsSummary describes a scrape as its duration, size, series and status.
+func statsSummary(s scrape.Stats) []string {
+	parts := []string{formatSeconds(s.Duration.Seconds())}
+	if s.Bytes >= 0 {
+		parts = append(parts, formatBytes(float64(s.Bytes)))
+	}
+	if s.Err == nil {
+		parts = append(parts, fmt.Sprintf("%d series", s.Series))
+	}
+	if s.Status != 0 {
+		parts = append(parts, fmt.Sprintf("HTTP %d", s.Status))
+	} else if s.Err != nil {
+		parts = append(parts, "failed")
+	}
+	return parts
+}
+
+// renderHealthPanel lists recent scrapes, newest first.
+func (m model) renderHealthPanel() string {
+	var sb strings.Builder
+	table := newPlainTable(&sb, []string{"Time", "Duration", "Size", "Series", "Status"})
+	for i := len(m.health) - 1; i >= 0; i-- {
+		s := m.health[i]
+		size, series, status := "--", "--", "--"
+		if s.Bytes >= 0 {
+			size = formatBytes(float64(s.Bytes))
+		}
+		if s.Err == nil {
+			series = fmt.Sprint(s.Series)
+		}
+		switch {
+		case s.Status != 0:
+			status = fmt.Sprint(s.Status)
+		case s.Err != nil:
+			status = "failed"
+		default:
+			status = "ok"
+		}
+		table.Append([]string{s.At.Format(time.TimeOnly), formatSeconds(s.Duration.Seconds()), size, series, status})
+	}
+	table.Render()
+	return "Scrape health\n" + sb.String()
+}
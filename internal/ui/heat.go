@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"math"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// heatSigmas is how many standard deviations from its recent mean a
+	// value must be to be graded hottest.
+	heatSigmas = 3
+	// minHeatHistory is how many earlier values a series needs before it's
+	// graded against its own history.
+	minHeatHistory = 3
+	// minHeatSpread is the smallest standard deviation assumed, as a
+	// fraction of the mean.
+	minHeatSpread = 0.1
+)
+
+// heatScore grades v, the latest value of md plotted in mode, from 0 for
+// unremarkable to 1 for an outlier. With --heat-threshold, that's how close
+// v is to the threshold; otherwise it's how far v is from the series'
+// recent values.
+func (m model) heatScore(md metricData, mode graphMode, v float64) float64 {
+	if m.heatMax > 0 {
+		return math.Min(math.Abs(v)/m.heatMax, 1)
+	}
+	points := mode.points(md)
+	if len(points) <= minHeatHistory {
+		return 0
+	}
+	prev := points[:len(points)-1]
+	var sum float64
+	for _, p := range prev {
+		sum += p
+	}
+	mean := sum / float64(len(prev))
+	var sq float64
+	for _, p := range prev {
+		sq += (p - mean) * (p - mean)
+	}
+	// A steady series would otherwise turn red at the smallest wobble, so
+	// the spread is at least a tenth of the mean.
+	sd := math.Max(math.Sqrt(sq/float64(len(prev))), math.Abs(mean)*minHeatSpread)
+	if sd == 0 {
+		if v == mean {
+			return 0
+		}
+		return 1
+	}
+	return math.Min(math.Abs(v-mean)/(heatSigmas*sd), 1)
+}
+
+// heatCell renders text in the theme's heat color for score.
+func (t theme) heatCell(score float64, text string) string {
+	if len(t.heat) == 0 {
+		return text
+	}
+	i := min(int(score*float64(len(t.heat))), len(t.heat)-1)
+	return t.heat[i].Render(text)
+}
+
+// signed formats v with an explicit sign for increases.
+func signed(v float64, format func(float64) string) string {
+	if v > 0 {
+		return "+" + format(v)
+	}
+	return format(v)
+}
+
+// arrowed renders a gauge's change with an arrow rather than a sign, so it
+// isn't mistaken for a counter's increase.
+func arrowed(v float64, format func(float64) string) string {
+	switch {
+	case v > 0:
+		return "↑" + format(v)
+	case v < 0:
+		return "↓" + format(-v)
+	}
+	return format(0)
+}
+
+func heatStyles(colors []string) []lipgloss.Style {
+	styles := make([]lipgloss.Style, len(colors))
+	for i, c := range colors {
+		styles[i] = style(c)
+	}
+	return styles
+}
@@ -0,0 +1,160 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// heatShades are the cell characters used for increasing bucket increments.
+var heatShades = []rune(" ░▒▓█")
+
+// histogramBuckets returns a histogram's upper bounds and cumulative counts,
+// adding the +Inf bucket from the sample count if it isn't exposed.
+func histogramBuckets(h *dto.Histogram) ([]float64, []float64) {
+	if isNativeHistogram(h) {
+		return nativeBuckets(h)
+	}
+	var bounds, counts []float64
+	for _, b := range h.GetBucket() {
+		bounds = append(bounds, b.GetUpperBound())
+		counts = append(counts, float64(b.GetCumulativeCount()))
+	}
+	if len(bounds) == 0 || !math.IsInf(bounds[len(bounds)-1], 1) {
+		bounds = append(bounds, math.Inf(1))
+		counts = append(counts, float64(h.GetSampleCount()))
+	}
+	return bounds, counts
+}
+
+// recordBuckets appends how many observations fell into each of a
+// histogram's buckets since the previous scrape, elapsed seconds ago, to
+// md's bucket history.
+func (md *metricData) recordBuckets(h *dto.Histogram, elapsed float64) {
+	bounds, counts := histogramBuckets(h)
+	md.native = isNativeHistogram(h)
+	if md.native && md.bucketBounds != nil && !slices.Equal(bounds, md.bucketBounds) {
+		bounds, counts = md.mergeBuckets(bounds, counts)
+	}
+	if len(bounds) != len(md.bucketBounds) {
+		// The bucket layout changed, so earlier increments no longer
+		// line up with the rows.
+		md.bucketBounds = bounds
+		md.prevBuckets = counts
+		md.bucketHistory = nil
+		return
+	}
+	incs := make([]float64, len(counts))
+	var prevCum float64
+	for i, c := range counts {
+		cum := c - md.prevBuckets[i]
+		if cum < 0 {
+			// Counter reset: everything counted since is new.
+			cum = c
+		}
+		incs[i] = math.Max(cum-prevCum, 0)
+		prevCum = cum
+	}
+	md.prevBuckets = counts
+	md.bucketElapsed = elapsed
+	md.bucketHistory = append(md.bucketHistory, incs)
+	if len(md.bucketHistory) > maxHistory {
+		md.bucketHistory = md.bucketHistory[len(md.bucketHistory)-maxHistory:]
+	}
+}
+
+// bucketEmpty reports whether nothing was observed into bucket i.
+func bucketEmpty(history [][]float64, i int) bool {
+	for _, incs := range history {
+		if incs[i] > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// renderHeatmap draws the selected histogram's bucket increments over
+// recent scrapes: one row per bucket, largest bound at the top, and one
+// column per scrape, shaded relative to the busiest cell.
+func (m model) renderHeatmap() string {
+	if m.selected < 0 || m.selected >= len(m.metricsList) {
+		return ""
+	}
+	md := m.metricsList[m.selected]
+	if md.bucketBounds == nil {
+		return "(heatmap is only available for histograms)"
+	}
+	if len(md.bucketHistory) == 0 {
+		return "(waiting for a second scrape)"
+	}
+	var peak float64
+	for _, incs := range md.bucketHistory {
+		for _, v := range incs {
+			peak = math.Max(peak, v)
+		}
+	}
+	// Native histograms can have a lot of buckets, most of them empty
+	// for a while, so only the range observed into is drawn.
+	lo, hi := 0, len(md.bucketBounds)-1
+	if md.native {
+		for lo < hi && bucketEmpty(md.bucketHistory, lo) {
+			lo++
+		}
+		for hi > lo && bucketEmpty(md.bucketHistory, hi) {
+			hi--
+		}
+	}
+	labels := make([]string, len(md.bucketBounds))
+	width := 0
+	for i := lo; i <= hi; i++ {
+		labels[i] = md.bucketLabel(md.bucketBounds[i])
+		width = max(width, len(labels[i]))
+	}
+
+	kind := "bucket"
+	if md.native {
+		kind = "native bucket"
+	}
+	// Each row ends with where observations are landing now: the rate
+	// into the bucket over the last scrape, and its share of them all.
+	latest := md.bucketHistory[len(md.bucketHistory)-1]
+	var total float64
+	for _, v := range latest {
+		total += v
+	}
+	rates := make([]string, len(md.bucketBounds))
+	rateWidth := len("rate/s")
+	for i := lo; i <= hi; i++ {
+		rate := 0.0
+		if md.bucketElapsed > 0 {
+			rate = latest[i] / md.bucketElapsed
+		}
+		rates[i] = formatRaw(rate)
+		rateWidth = max(rateWidth, len(rates[i]))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s{%s} %s increments per scrape (peak %s)\n", md.name, md.labels, kind, formatRaw(peak)))
+	sb.WriteString(fmt.Sprintf("%*s  %*s  %*s  share\n", width, "", 2*len(md.bucketHistory), "", rateWidth, "rate/s"))
+	for i := hi; i >= lo; i-- {
+		sb.WriteString(fmt.Sprintf("%*s │", width, labels[i]))
+		for _, incs := range md.bucketHistory {
+			shade := heatShades[0]
+			if peak > 0 && incs[i] > 0 {
+				idx := 1 + int(incs[i]/peak*float64(len(heatShades)-2)+0.5)
+				shade = heatShades[min(idx, len(heatShades)-1)]
+			}
+			sb.WriteString(strings.Repeat(string(shade), 2))
+		}
+		share := "    -"
+		if total > 0 {
+			share = fmt.Sprintf("%4.0f%%", latest[i]/total*100)
+		}
+		sb.WriteString(fmt.Sprintf("  %*s  %s\n", rateWidth, rates[i], share))
+	}
+	sb.WriteString(strings.Repeat(" ", width) + " └" + strings.Repeat("─", 2*len(md.bucketHistory)) + "> time")
+	return sb.String()
+}
@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// hookTimeout is how long an --on-scrape or --on-error command may run
+// before it's killed.
+const hookTimeout = 30 * time.Second
+
+// hookEvent is the scrape result passed to hooks as JSON on stdin.
+type hookEvent struct {
+	Event    string    `json:"event"`
+	Endpoint string    `json:"endpoint"`
+	Time     time.Time `json:"time"`
+	Duration float64   `json:"duration_seconds"`
+	// Bytes and Status are omitted for sources without a response body or
+	// that aren't HTTP.
+	Bytes   *int64   `json:"bytes,omitempty"`
+	Status  int      `json:"status,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	Samples []sample `json:"samples,omitempty"`
+}
+
+// newHookEvent describes a scrape for a hook. Successful scrapes include
+// every sample that passes the filters.
+func (m model) newHookEvent(msg metricsMsg) hookEvent {
+	ev := hookEvent{
+		Event:    "scrape",
+		Endpoint: m.endpoint,
+		Time:     msg.at,
+		Duration: msg.stats.Duration.Seconds(),
+		Status:   msg.stats.Status,
+	}
+	if msg.stats.Bytes >= 0 {
+		ev.Bytes = &msg.stats.Bytes
+	}
+	if msg.err != nil {
+		ev.Event = "error"
+		ev.Error = msg.err.Error()
+		return ev
+	}
+	ev.Samples = collectSamples(m.filterFamilies(msg.families))
+	return ev
+}
+
+// hookCmd runs command with the shell, writing ev to its stdin. Output is
+// discarded; a failure is reported in the status line.
+func hookCmd(command string, ev hookEvent) tea.Cmd {
+	return func() tea.Msg {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return statusMsg(fmt.Sprintf("Hook failed: %v", err))
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+		defer cancel()
+		cmd := shellCommand(ctx, command)
+		cmd.Stdin = bytes.NewReader(append(data, '\n'))
+		cmd.Env = append(os.Environ(), "MET_EVENT="+ev.Event, "MET_ENDPOINT="+ev.Endpoint)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			if out := strings.TrimSpace(string(out)); out != "" {
+				err = fmt.Errorf("%w: %s", err, out)
+			}
+			return statusMsg(fmt.Sprintf("Hook %q failed: %v", command, err))
+		}
+		return nil
+	}
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// scrapeHook returns the command for whichever hook msg triggers, or nil if
+// that hook isn't set.
+func (m model) scrapeHook(msg metricsMsg) tea.Cmd {
+	command := m.onScrape
+	if msg.err != nil {
+		command = m.onError
+	}
+	if command == "" {
+		return nil
+	}
+	return hookCmd(command, m.newHookEvent(msg))
+}
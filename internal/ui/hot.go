@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jaxxstorm/met/internal/scrape"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// hotTickMsg is sent every --hot-interval, between the regular scrapes, to
+// refresh the hot series for high-resolution graphs of the few series being
+// watched closely.
+type hotTickMsg time.Time
+
+// hotMetricsMsg is the result of a scrape taken to refresh the hot series.
+// Endpoints can't be asked for a subset of their series, so it has
+// everything, but only the hot series are updated from it. Each refresh
+// costs the endpoint as much as a regular scrape, which is why
+// --hot-interval defaults to a second rather than anything shorter.
+type hotMetricsMsg metricsMsg
+
+func hotTickCmd(interval time.Duration) tea.Cmd {
+	return tea.Tick(interval, func(t time.Time) tea.Msg {
+		return hotTickMsg(t)
+	})
+}
+
+func fetchHotCmd(src scrape.Source, relabel []scrape.RelabelRule) tea.Cmd {
+	fetch := fetchMetricsCmd(src, relabel)
+	return func() tea.Msg {
+		return hotMetricsMsg(fetch().(metricsMsg))
+	}
+}
+
+// startHot starts refreshing hot series every --hot-interval, if there are
+// any and they aren't being refreshed already. The refreshes stop by
+// themselves once no series are hot.
+func (m model) startHot() (model, tea.Cmd) {
+	if m.hotTicking || m.hotInterval <= 0 || !m.hasHot() {
+		return m, nil
+	}
+	m.hotTicking = true
+	return m, hotTickCmd(m.hotInterval)
+}
+
+// hasHot reports whether any series are, or could become, hot.
+func (m model) hasHot() bool {
+	return len(m.hot) > 0 || len(m.hotSelectors) > 0
+}
+
+func (m model) isHot(name string, lbls []*dto.LabelPair, key string) bool {
+	if _, ok := m.hot[key]; ok {
+		return true
+	}
+	for _, sel := range m.hotSelectors {
+		if sel.matches(name, lbls) {
+			return true
+		}
+	}
+	return false
+}
+
+// toggleHot marks or unmarks the selected series as hot. The set is copied
+// rather than changed in place, as models copied for tabs share it.
+func (m model) toggleHot() model {
+	if m.selected < 0 || m.selected >= len(m.metricsList) {
+		return m
+	}
+	md := m.metricsList[m.selected]
+	if md.synthetic {
+		m.status = "Expressions can't be made hot"
+		return m
+	}
+	hot := make(map[string]struct{}, len(m.hot)+1)
+	for k := range m.hot {
+		hot[k] = struct{}{}
+	}
+	if _, ok := hot[md.key]; ok {
+		delete(hot, md.key)
+		m.status = fmt.Sprintf("%s is no longer hot", md.key)
+	} else {
+		hot[md.key] = struct{}{}
+		m.status = fmt.Sprintf("Refreshing %s every %s", md.key, m.hotInterval)
+	}
+	m.hot = hot
+	return m
+}
+
+// updateHot refreshes the hot series that are already shown from a hot
+// scrape. New series only appear with the regular scrape.
+func (m model) updateHot(series []scrapedSeries, at time.Time) model {
+	restarted := m.observeRestart(series, at)
+	for _, ss := range series {
+		idx, ok := m.metricsIndex[ss.key]
+		if !ok || !m.isHot(ss.mf.GetName(), ss.pm.Label, ss.key) {
+			continue
+		}
+		m.metricsList[idx].checkSample(ss.mf, ss.raw, at, restarted)
+		m.metricsList[idx].observe(ss.mf, ss.pm, ss.raw, at)
+		m.metricsList[idx].sum = ss.sum
+	}
+	return m
+}
@@ -0,0 +1,270 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/met/internal/store"
+)
+
+// ImportCmd is met import, which reads series exported from somewhere met
+// can't reach, such as an air-gapped Prometheus, and shows them in the TUI
+// to browse and graph without scraping anything. It reads OpenMetrics
+// files, as backfilled with promtool tsdb create-blocks-from openmetrics,
+// and the output of promtool tsdb dump, which has a line per sample:
+//
+//	{__name__="up", instance="localhost:9090", job="prometheus"} 1 1700000000000
+type ImportCmd struct {
+	Path string `arg:"" help:"OpenMetrics file, or the output of promtool tsdb dump, to browse" type:"existingfile"`
+}
+
+// importedSeries is a series read from an import, with its samples.
+type importedSeries struct {
+	sample store.Sample
+	at     []time.Time
+	values []float64
+}
+
+// counterSuffixes mark the samples of counters, histograms and summaries
+// where there's no TYPE line to say so, as in a tsdb dump.
+var counterSuffixes = []string{"_total", "_count", "_sum", "_bucket"}
+
+// readImport reads the samples of an import, and lines them up into at most
+// maxHistory scrapes spread evenly from its first sample to its last. Each
+// scrape holds every series' latest sample by then, as an instant query
+// would, as series are rarely sampled at the same moments.
+func readImport(r io.Reader) ([]store.Scrape, error) {
+	types := make(map[string]string)
+	series := make(map[string]*importedSeries)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || text == "# EOF" {
+			continue
+		}
+		if strings.HasPrefix(text, "#") {
+			if f := strings.Fields(text); len(f) == 4 && f[1] == "TYPE" {
+				types[f[2]] = f[3]
+			}
+			continue
+		}
+		name, labels, v, at, err := parseImportLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		key := sampleKey(name, labels)
+		s, ok := series[key]
+		if !ok {
+			s = &importedSeries{sample: store.Sample{Name: name, Labels: labels, Counter: isImportedCounter(name, types)}}
+			series[key] = s
+		}
+		s.at = append(s.at, at)
+		s.values = append(s.values, v)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(series) == 0 {
+		return nil, fmt.Errorf("no samples found")
+	}
+
+	var first, last time.Time
+	distinct := make(map[time.Time]bool)
+	for _, s := range series {
+		sort.Sort(byTime{s})
+		if first.IsZero() || s.at[0].Before(first) {
+			first = s.at[0]
+		}
+		if end := s.at[len(s.at)-1]; end.After(last) {
+			last = end
+		}
+		for _, at := range s.at {
+			if len(distinct) > maxHistory {
+				break
+			}
+			distinct[at] = true
+		}
+	}
+	steps := min(len(distinct), maxHistory)
+	scrapes := make([]store.Scrape, steps)
+	for i := range scrapes {
+		at := last
+		if steps > 1 {
+			at = first.Add(time.Duration(float64(last.Sub(first)) * float64(i) / float64(steps-1)))
+		}
+		scrapes[i].At = at
+	}
+	for _, s := range series {
+		j := 0
+		for i := range scrapes {
+			for j < len(s.at) && !s.at[j].After(scrapes[i].At) {
+				j++
+			}
+			if j == 0 {
+				continue
+			}
+			sample := s.sample
+			sample.Value = s.values[j-1]
+			scrapes[i].Samples = append(scrapes[i].Samples, sample)
+		}
+	}
+	return scrapes, nil
+}
+
+// byTime sorts a series' samples by time.
+type byTime struct{ s *importedSeries }
+
+func (b byTime) Len() int           { return len(b.s.at) }
+func (b byTime) Less(i, j int) bool { return b.s.at[i].Before(b.s.at[j]) }
+func (b byTime) Swap(i, j int) {
+	b.s.at[i], b.s.at[j] = b.s.at[j], b.s.at[i]
+	b.s.values[i], b.s.values[j] = b.s.values[j], b.s.values[i]
+}
+
+// isImportedCounter reports whether the series name is of a counter, by
+// the TYPE line of its family if there was one and its suffix otherwise.
+func isImportedCounter(name string, types map[string]string) bool {
+	for _, suffix := range append([]string{""}, counterSuffixes...) {
+		family, ok := strings.CutSuffix(name, suffix)
+		if !ok {
+			continue
+		}
+		switch types[family] {
+		case "counter":
+			return suffix == "" || suffix == "_total"
+		case "histogram", "summary":
+			return suffix != "" && suffix != "_total"
+		case "":
+		default:
+			return false
+		}
+	}
+	if len(types) > 0 {
+		return false
+	}
+	for _, suffix := range counterSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseImportLine parses a sample: a name and labels, in either the
+// exposition format's name{labels} or a dump's {__name__="name",labels},
+// then the value and timestamp. OpenMetrics timestamps are in seconds and
+// dumps' in milliseconds, which are told apart by size. Exemplars are
+// ignored.
+func parseImportLine(text string) (string, map[string]string, float64, time.Time, error) {
+	p := &selectorParser{input: text}
+	name := p.ident()
+	labels := make(map[string]string)
+	if p.consume("{") {
+		for {
+			p.skipSpace()
+			if p.consume("}") {
+				break
+			}
+			label := p.ident()
+			if label == "" {
+				return "", nil, 0, time.Time{}, p.errorf("expected a label name")
+			}
+			p.skipSpace()
+			if !p.consume("=") {
+				return "", nil, 0, time.Time{}, p.errorf("expected '='")
+			}
+			p.skipSpace()
+			value, err := p.quoted()
+			if err != nil {
+				return "", nil, 0, time.Time{}, err
+			}
+			if label == "__name__" {
+				name = value
+			} else {
+				labels[label] = value
+			}
+			p.skipSpace()
+			if p.consume(",") {
+				continue
+			}
+			if !p.consume("}") {
+				return "", nil, 0, time.Time{}, p.errorf("expected ',' or '}'")
+			}
+			break
+		}
+	}
+	if name == "" {
+		return "", nil, 0, time.Time{}, p.errorf("expected a metric name")
+	}
+	rest, _, _ := strings.Cut(p.input[p.pos:], " # ")
+	fields := strings.Fields(rest)
+	if len(fields) != 2 {
+		return "", nil, 0, time.Time{}, fmt.Errorf("expected a value and a timestamp after %s", name)
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", nil, 0, time.Time{}, fmt.Errorf("bad value %q", fields[0])
+	}
+	ts, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return "", nil, 0, time.Time{}, fmt.Errorf("bad timestamp %q", fields[1])
+	}
+	if ts > 1e11 {
+		ts /= 1000
+	}
+	sec, frac := math.Modf(ts)
+	if len(labels) == 0 {
+		labels = nil
+	}
+	return name, labels, v, time.Unix(int64(sec), int64(frac*1e9)), nil
+}
+
+// sampleKey identifies a series by its name and labels.
+func sampleKey(name string, labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for n := range labels {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteString(name)
+	for _, n := range names {
+		sb.WriteString("\xff" + n + "\xff" + labels[n])
+	}
+	return sb.String()
+}
+
+// importModel returns m showing the series imported from path.
+func importModel(m model, path string) (model, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return m, err
+	}
+	defer f.Close()
+	scrapes, err := readImport(f)
+	if err != nil {
+		return m, fmt.Errorf("importing %s: %w", path, err)
+	}
+	m.endpoint = path
+	m.imported = true
+	m.cacheDir = ""
+	for _, sc := range scrapes {
+		m = updateMetrics(m, sc.Families(), sc.At)
+	}
+	m.sortMetrics(m.order())
+	m.initialized = true
+	m.enforcePageBounds()
+	first, last := scrapes[0].At, scrapes[len(scrapes)-1].At
+	m.status = fmt.Sprintf("Imported %d series from %s to %s", len(m.metricsList), first.Format(time.DateTime), last.Format(time.DateTime))
+	return m, nil
+}
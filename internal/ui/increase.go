@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"time"
+)
+
+// increaseWindows are the windows --increase shows how much counters went
+// up over, like a load average, so a short burst, which stands out over 1m
+// but not 15m, can be told from sustained growth.
+var increaseWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+var increaseHeader = []string{"Last 1m", "Last 5m", "Last 15m"}
+
+// increaseStep is how far apart checkpoints are, unless scrapes are
+// further apart than that. History only goes back 30 scrapes, so each
+// counter keeps a checkpoint of its accumulated value every increaseStep to
+// measure the windows from.
+const increaseStep = 10 * time.Second
+
+// checkpoint records md's accumulated value at at, if the last checkpoint
+// was increaseStep or more ago, and drops those too old to be needed.
+func (md *metricData) checkpoint(at time.Time) {
+	if !md.Counter {
+		return
+	}
+	if n := len(md.checkpointAt); n > 0 && at.Sub(md.checkpointAt[n-1]) < increaseStep {
+		return
+	}
+	md.checkpointAt = append(md.checkpointAt, at)
+	md.checkpoints = append(md.checkpoints, md.Total)
+	// The newest checkpoint before the longest window is kept, to
+	// measure it from.
+	oldest := at.Add(-increaseWindows[len(increaseWindows)-1])
+	drop := 0
+	for drop+1 < len(md.checkpointAt) && !md.checkpointAt[drop+1].After(oldest) {
+		drop++
+	}
+	md.checkpointAt = md.checkpointAt[drop:]
+	md.checkpoints = md.checkpoints[drop:]
+}
+
+// increase returns how much md went up in the window up to its last
+// scrape. When met hasn't been watching it for that long, it's the
+// increase so far, and partial is set.
+func (md metricData) increase(window time.Duration) (inc float64, partial bool) {
+	n := len(md.checkpointAt)
+	if n == 0 {
+		return 0, true
+	}
+	start := md.lastSeen.Add(-window)
+	if md.checkpointAt[0].After(start) {
+		return md.Total - md.checkpoints[0], true
+	}
+	// Interpolated between the checkpoints either side of the start.
+	i := 0
+	for i+1 < n && !md.checkpointAt[i+1].After(start) {
+		i++
+	}
+	base := md.checkpoints[i]
+	if i+1 < n {
+		span := md.checkpointAt[i+1].Sub(md.checkpointAt[i])
+		frac := float64(start.Sub(md.checkpointAt[i])) / float64(span)
+		base += (md.checkpoints[i+1] - md.checkpoints[i]) * frac
+	}
+	return md.Total - base, false
+}
+
+// increaseColumns returns md's cells for the --increase columns, with the
+// increases over windows not yet covered greyed out.
+func (m model) increaseColumns(md metricData, format func(float64) string) []string {
+	cols := make([]string, len(increaseWindows))
+	for i, w := range increaseWindows {
+		if !md.Counter || md.synthetic {
+			cols[i] = "--"
+			continue
+		}
+		inc, partial := md.increase(w)
+		cols[i] = format(inc)
+		if partial {
+			cols[i] = m.theme.stale.Render(cols[i])
+		}
+	}
+	return cols
+}
+
+// toggleIncrease shows or hides the increase columns.
+func (m model) toggleIncrease() model {
+	m.increase = !m.increase
+	if m.increase {
+		m.status = "Showing how much counters went up over the last 1, 5 and 15 minutes"
+	} else {
+		m.status = "Hiding the increase columns"
+	}
+	return m
+}
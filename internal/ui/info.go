@@ -0,0 +1,193 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// maxInfoLines is how many info series the panel lists before counting the
+// rest.
+const maxInfoLines = 4
+
+// infoLabels are listed first in the panel, being what's usually wanted.
+var infoLabels = []string{"version", "goversion", "revision", "branch"}
+
+// infoSeries is a series of an info metric, such as build_info: a gauge
+// that's always 1 and carries metadata in its labels, such as versions,
+// revisions and hosts. Rather than taking up table rows, info series are
+// listed in a panel under the title, and their labels can be joined onto
+// the series they describe, as PromQL's group_left does.
+type infoSeries struct {
+	name   string
+	key    string
+	labels []*dto.LabelPair
+}
+
+// isInfoFamily reports whether mf is an info metric: a gauge, or an untyped
+// metric, whose name ends in _info.
+func isInfoFamily(mf *dto.MetricFamily) bool {
+	t := mf.GetType()
+	return strings.HasSuffix(mf.GetName(), "_info") && (t == dto.MetricType_GAUGE || t == dto.MetricType_UNTYPED)
+}
+
+// collectInfo gathers the info series of a scrape, ordered by name and then
+// labels.
+func collectInfo(families map[string]*dto.MetricFamily) []infoSeries {
+	var out []infoSeries
+	for name, mf := range families {
+		if !isInfoFamily(mf) {
+			continue
+		}
+		for _, pm := range mf.Metric {
+			_, key := renderLabels(pm.Label)
+			out = append(out, infoSeries{name: name, key: key, labels: pm.Label})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].name != out[j].name {
+			return out[i].name < out[j].name
+		}
+		return out[i].key < out[j].key
+	})
+	return out
+}
+
+// infoNames lists the info metrics scraped, once each.
+func (m model) infoNames() []string {
+	var names []string
+	for _, s := range m.info {
+		if len(names) == 0 || names[len(names)-1] != s.name {
+			names = append(names, s.name)
+		}
+	}
+	return names
+}
+
+// renderInfoLabels renders labels as name=value pairs, the usual build
+// labels first.
+func renderInfoLabels(labels []*dto.LabelPair) string {
+	sorted := slices.Clone(labels)
+	rank := func(lp *dto.LabelPair) int {
+		if i := slices.Index(infoLabels, lp.GetName()); i >= 0 {
+			return i
+		}
+		return len(infoLabels)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := rank(sorted[i]), rank(sorted[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return sorted[i].GetName() < sorted[j].GetName()
+	})
+	parts := make([]string, len(sorted))
+	for i, lp := range sorted {
+		parts[i] = lp.GetName() + "=" + lp.GetValue()
+	}
+	return strings.Join(parts, " ")
+}
+
+// renderInfo lists the info series scraped, a line each, for the header.
+// It's empty when there are none or they're shown as rows.
+func (m model) renderInfo() string {
+	if m.infoRows || len(m.info) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	for i, s := range m.info {
+		if i == maxInfoLines {
+			sb.WriteString(fmt.Sprintf("… and %d more info series\n", len(m.info)-i))
+			break
+		}
+		line := s.name + "  " + renderInfoLabels(s.labels)
+		if m.width > 0 && ansi.StringWidth(line) > m.width {
+			line = ansi.Truncate(line, m.width, ellipsis())
+		}
+		sb.WriteString(line + "\n")
+	}
+	return m.theme.stale.Render(strings.TrimSuffix(sb.String(), "\n")) + "\n\n"
+}
+
+// joinedLabels renders the labels of the m.join series that md matches,
+// leaving out those md has already. A series matches an info series when
+// every label they share has the same value; of those matching, the one
+// sharing the most labels is used, and if several tie nothing is joined
+// rather than guessing.
+func (m model) joinedLabels(md metricData) string {
+	if m.join == "" || md.synthetic {
+		return ""
+	}
+	own := make(map[string]string, len(md.labelPairs))
+	for _, lp := range md.labelPairs {
+		own[lp.GetName()] = lp.GetValue()
+	}
+	var best []*dto.LabelPair
+	bestShared, tied := -1, false
+	for _, s := range m.info {
+		if s.name != m.join {
+			continue
+		}
+		shared, ok := 0, true
+		for _, lp := range s.labels {
+			if v, has := own[lp.GetName()]; has {
+				if v != lp.GetValue() {
+					ok = false
+					break
+				}
+				shared++
+			}
+		}
+		switch {
+		case !ok:
+		case shared > bestShared:
+			best, bestShared, tied = s.labels, shared, false
+		case shared == bestShared:
+			tied = true
+		}
+	}
+	if best == nil || tied {
+		return ""
+	}
+	var extra []*dto.LabelPair
+	for _, lp := range best {
+		if _, has := own[lp.GetName()]; !has {
+			extra = append(extra, lp)
+		}
+	}
+	return renderInfoLabels(extra)
+}
+
+// joinPicker picks the info metric whose labels are joined onto the series
+// in the table, or stops joining.
+func (m model) joinPicker() model {
+	names := m.infoNames()
+	if len(names) == 0 {
+		m.status = "No *_info metrics were scraped to join labels from"
+		return m
+	}
+	items := slices.Clone(names)
+	if m.join != "" {
+		items = append(items, "stop joining "+m.join)
+	}
+	m.picker = &picker{
+		title: "Join the labels of an info metric onto the series",
+		items: items,
+		choose: func(m model, item string) (model, tea.Cmd) {
+			if strings.HasPrefix(item, "stop joining ") {
+				m.status = "No longer joining the labels of " + m.join
+				m.join = ""
+				return m, nil
+			}
+			m.join = item
+			m.status = "Joining the labels of " + item + " onto the series they match"
+			return m, nil
+		},
+	}
+	return m
+}
@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// processStartMetric is the standard metric exporters expose their start
+// time with, which tells a restart from a counter going backwards.
+const processStartMetric = "process_start_time_seconds"
+
+// issue is a problem with a series spotted while scraping it, listed in
+// the issues panel.
+type issue struct {
+	key    string
+	kind   string
+	detail string
+}
+
+// observeRestart records the process start time exposed in series, scraped
+// at at, reporting whether it changed since the last scrape.
+func (m *model) observeRestart(series []scrapedSeries, at time.Time) bool {
+	for _, ss := range series {
+		if ss.mf.GetName() != processStartMetric {
+			continue
+		}
+		restarted := m.processStart != 0 && ss.raw != m.processStart
+		m.processStart = ss.raw
+		if restarted {
+			m.restarts = append(m.restarts, at)
+			if len(m.restarts) > maxHistory {
+				m.restarts = m.restarts[len(m.restarts)-maxHistory:]
+			}
+		}
+		return restarted
+	}
+	return false
+}
+
+// checkSample looks for problems with raw, the series' next value, before
+// it's observed: counters going backwards other than when the process
+// restarted, and gauges that stop changing.
+func (md *metricData) checkSample(mf *dto.MetricFamily, raw float64, at time.Time, restarted bool) {
+	if len(md.History) == 0 {
+		return
+	}
+	switch {
+	case md.Counter && raw < md.Value:
+		md.resetAt = at
+		md.resetFrom, md.resetTo = md.Value, raw
+		md.resetRestart = restarted
+		md.resets = append(md.resets, at)
+		for len(md.resets) > 0 && md.resets[0].Before(md.HistoryAt[0]) {
+			md.resets = md.resets[1:]
+		}
+	case mf.GetType() == dto.MetricType_GAUGE && raw == md.Value:
+		md.unchanged++
+	default:
+		md.unchanged = 0
+	}
+}
+
+// issues lists the problems with the series shown: NaN and infinite
+// samples, counters that went backwards without a restart, and gauges
+// stuck at one value for --stuck-after scrapes.
+func (m model) issues() []issue {
+	var out []issue
+	for _, md := range m.metricsList {
+		if md.synthetic || m.isMuted(md) {
+			continue
+		}
+		format := m.formatter(md)
+		if v := md.Value; math.IsNaN(v) || math.IsInf(v, 0) {
+			out = append(out, issue{key: md.key, kind: "bad sample", detail: fmt.Sprintf("value is %s", formatFloat(v))})
+		}
+		if !md.resetAt.IsZero() && !md.resetRestart {
+			out = append(out, issue{key: md.key, kind: "counter decreased", detail: fmt.Sprintf("from %s to %s at %s without a process restart",
+				format(md.resetFrom), format(md.resetTo), md.resetAt.Format(time.TimeOnly))})
+		}
+		// Info metrics and start times are constant by design.
+		constant := strings.HasSuffix(md.name, "_info") || md.name == processStartMetric
+		if m.stuckAfter > 0 && md.unchanged >= m.stuckAfter && !constant {
+			out = append(out, issue{key: md.key, kind: "gauge stuck", detail: fmt.Sprintf("at %s for %d scrapes", format(md.Value), md.unchanged)})
+		}
+	}
+	return out
+}
+
+// renderIssuesSummary counts the issues found, pointing at the panel that
+// lists them.
+func (m model) renderIssuesSummary(issues []issue) string {
+	if len(issues) == 0 || m.showIssues {
+		return ""
+	}
+	return m.theme.negative.Render(fmt.Sprintf("%d issues, press I to list them", len(issues))) + "\n"
+}
+
+// renderIssuesPanel lists the issues found.
+func (m model) renderIssuesPanel(issues []issue) string {
+	if len(issues) == 0 {
+		return "Issues\nNone found.\n"
+	}
+	var sb strings.Builder
+	table := newPlainTable(&sb, []string{"Series", "Issue", "Detail"})
+	for _, is := range issues {
+		table.Append([]string{is.key, is.kind, is.detail})
+	}
+	table.Render()
+	return "Issues\n" + sb.String()
+}
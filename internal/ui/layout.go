@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// layout selects how the table of series is rendered.
+type layout int
+
+const (
+	// layoutTable is the bordered table.
+	layoutTable layout = iota
+	// layoutCompact drops the borders, fitting more rows on screen.
+	layoutCompact
+	// layoutWide adds the rate, the range and a sparkline of recent values.
+	layoutWide
+	numLayouts
+)
+
+// sparklineWidth is how many recent values the wide layout's sparkline shows.
+const sparklineWidth = 20
+
+var layoutNames = map[string]layout{
+	"table":   layoutTable,
+	"compact": layoutCompact,
+	"wide":    layoutWide,
+}
+
+func (l layout) String() string {
+	for name, v := range layoutNames {
+		if v == l {
+			return name
+		}
+	}
+	return "table"
+}
+
+// configure styles table for the layout.
+func (l layout) configure(table *tablewriter.Table) {
+	table.SetAutoWrapText(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	if l == layoutCompact {
+		table.SetBorder(false)
+		table.SetHeaderLine(false)
+		table.SetColumnSeparator("")
+		table.SetTablePadding("  ")
+		table.SetNoWhiteSpace(true)
+		return
+	}
+	table.SetBorder(true)
+	table.SetRowSeparator("-")
+	table.SetColumnSeparator("|")
+	table.SetCenterSeparator("+")
+}
+
+// extraHeader returns the headers of the columns the layout adds. The wide
+// layout's range is left to the statistics columns when they're shown.
+func (l layout) extraHeader(topMode, stats bool) []string {
+	if l != layoutWide {
+		return nil
+	}
+	header := []string{"Min", "Max", "Trend"}
+	if stats {
+		header = []string{"Trend"}
+	}
+	if !topMode {
+		// Top mode already has a rate column.
+		header = append([]string{"Rate/s"}, header...)
+	}
+	return header
+}
+
+// extraColumns returns md's cells for the columns the layout adds.
+func (l layout) extraColumns(md metricData, format func(float64) string, topMode, stats bool) []string {
+	if l != layoutWide {
+		return nil
+	}
+	values := windowValues(md)
+	cols := []string{sparkline(values, sparklineWidth)}
+	if !stats {
+		minStr, maxStr := "--", "--"
+		if lo, hi := minMax(values); !math.IsInf(lo, 1) {
+			minStr, maxStr = format(lo), format(hi)
+		}
+		cols = append([]string{minStr, maxStr}, cols...)
+	}
+	if !topMode {
+		rate := "--"
+		if md.Counter {
+			rate = format(md.Rate)
+		} else if !md.synthetic {
+			rate = arrowed(md.Rate, format)
+		}
+		cols = append([]string{rate}, cols...)
+	}
+	return cols
+}
+
+// sparkline draws the last width values of vs, scaled between their
+// minimum and maximum.
+func sparkline(vs []float64, width int) string {
+	if len(vs) > width {
+		vs = vs[len(vs)-width:]
+	}
+	lo, hi := minMax(vs)
+	var sb strings.Builder
+	for _, v := range vs {
+		idx := 0
+		switch {
+		case math.IsNaN(v):
+			sb.WriteRune(' ')
+			continue
+		case hi > lo:
+			idx = int((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+		}
+		sb.WriteRune(sparkBlocks[idx])
+	}
+	return sb.String()
+}
+
+func parseLayout(s string) (layout, error) {
+	l, ok := layoutNames[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown layout %q, want table, compact or wide", s)
+	}
+	return l, nil
+}
@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/jaxxstorm/met/internal/scrape"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	prommodel "github.com/prometheus/common/model"
+)
+
+type LintCmd struct{}
+
+type lintProblem struct {
+	metric  string
+	problem string
+}
+
+// runLint scrapes the endpoint once and prints problems with its
+// exposition, much like promtool check metrics. It returns how many
+// problems were found.
+func runLint(w io.Writer, m model) (int, error) {
+	src, ok := m.source.(scrape.HTTP)
+	if !ok || (src.Format != "" && src.Format != "prometheus") {
+		return 0, fmt.Errorf("lint only supports Prometheus text format endpoints")
+	}
+	b, err := src.Body()
+	if err != nil {
+		return 0, err
+	}
+
+	problems := lintExposition(b)
+	if len(problems) == 0 {
+		fmt.Fprintf(w, "No problems found in %s\n", m.endpoint)
+		return 0, nil
+	}
+	table := newPlainTable(w, []string{"Metric", "Problem"})
+	for _, p := range problems {
+		table.Append([]string{p.metric, p.problem})
+	}
+	table.Render()
+	fmt.Fprintf(w, "\n%d problems found in %s\n", len(problems), m.endpoint)
+	return len(problems), nil
+}
+
+// lintExposition checks a text format exposition for problems the parser
+// tolerates, sorted by metric name. Anything the parser rejects outright,
+// such as label values that aren't valid UTF-8, is reported as the single
+// problem.
+func lintExposition(b []byte) []lintProblem {
+	var parser expfmt.TextParser
+	fams, err := parser.TextToMetricFamilies(bytes.NewReader(b))
+	if err != nil {
+		return []lintProblem{{metric: "-", problem: err.Error()}}
+	}
+
+	// The parser treats a family without a TYPE line as untyped, so look
+	// for the TYPE lines themselves.
+	typed := make(map[string]bool)
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) >= 3 && fields[0] == "#" && fields[1] == "TYPE" {
+			typed[fields[2]] = true
+		}
+	}
+
+	var problems []lintProblem
+	add := func(metric, format string, args ...any) {
+		problems = append(problems, lintProblem{metric: metric, problem: fmt.Sprintf(format, args...)})
+	}
+	for name, mf := range fams {
+		if mf.Help == nil {
+			add(name, "no HELP line")
+		}
+		if !typed[name] {
+			add(name, "no TYPE line")
+		}
+		if mf.GetType() == dto.MetricType_COUNTER && !strings.HasSuffix(name, "_total") {
+			add(name, "counter name doesn't end in _total")
+		}
+		if typ := mf.GetType(); typ != dto.MetricType_COUNTER && typ != dto.MetricType_UNTYPED && strings.HasSuffix(name, "_total") {
+			add(name, "%s name ends in _total, which is reserved for counters", strings.ToLower(mf.GetType().String()))
+		}
+
+		seen := make(map[string]bool)
+		var bucketLayout string
+		for _, pm := range mf.Metric {
+			_, key := renderLabels(pm.Label)
+			series := name + "{" + key + "}"
+			if seen[key] {
+				add(series, "duplicate series")
+			}
+			seen[key] = true
+			for _, lp := range pm.Label {
+				if strings.HasPrefix(lp.GetName(), "__") {
+					add(series, "label %s uses the reserved __ prefix", lp.GetName())
+				}
+				if !prommodel.LabelName(lp.GetName()).IsValidLegacy() {
+					add(series, "label name %q isn't a valid legacy label name", lp.GetName())
+				}
+			}
+			if h := pm.GetHistogram(); h != nil {
+				layout := lintHistogram(series, h, add)
+				if bucketLayout == "" {
+					bucketLayout = layout
+				} else if layout != bucketLayout {
+					add(series, "bucket bounds differ from other series of the histogram")
+				}
+			}
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].metric < problems[j].metric
+	})
+	return problems
+}
+
+// lintHistogram checks a single histogram series and returns its bucket
+// bounds, for comparing against the family's other series.
+func lintHistogram(series string, h *dto.Histogram, add func(string, string, ...any)) string {
+	var bounds []string
+	var prev uint64
+	hasInf := false
+	for i, b := range h.GetBucket() {
+		bounds = append(bounds, formatFloat(b.GetUpperBound()))
+		if b.GetCumulativeCount() < prev && i > 0 {
+			add(series, "bucket le=%s has a lower count than the bucket before it", formatFloat(b.GetUpperBound()))
+		}
+		prev = b.GetCumulativeCount()
+		if math.IsInf(b.GetUpperBound(), 1) {
+			hasInf = true
+			if b.GetCumulativeCount() != h.GetSampleCount() {
+				add(series, "+Inf bucket count %d doesn't match _count %d", b.GetCumulativeCount(), h.GetSampleCount())
+			}
+		}
+	}
+	if !hasInf {
+		add(series, "no +Inf bucket")
+	}
+	return strings.Join(bounds, ",")
+}
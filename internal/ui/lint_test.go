@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLintExposition(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []lintProblem
+	}{
+		{
+			name: "clean",
+			text: `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{code="200"} 10
+# HELP latency_seconds Request latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 0.5
+latency_seconds_count 3
+`,
+		},
+		{
+			name: "missing HELP and TYPE",
+			text: "up 1\n",
+			want: []lintProblem{{"up", "no HELP line"}, {"up", "no TYPE line"}},
+		},
+		{
+			name: "counter without _total",
+			text: "# HELP requests Requests served.\n# TYPE requests counter\nrequests 10\n",
+			want: []lintProblem{{"requests", "counter name doesn't end in _total"}},
+		},
+		{
+			name: "gauge with _total",
+			text: "# HELP queue_total Queued jobs.\n# TYPE queue_total gauge\nqueue_total 3\n",
+			want: []lintProblem{{"queue_total", "gauge name ends in _total, which is reserved for counters"}},
+		},
+		{
+			name: "duplicate series",
+			text: "# HELP up Up.\n# TYPE up gauge\nup{job=\"a\"} 1\nup{job=\"a\"} 0\n",
+			want: []lintProblem{{`up{job="a"}`, "duplicate series"}},
+		},
+		{
+			name: "reserved label",
+			text: "# HELP up Up.\n# TYPE up gauge\nup{__job=\"a\"} 1\n",
+			want: []lintProblem{{`up{__job="a"}`, "label __job uses the reserved __ prefix"}},
+		},
+		{
+			name: "histogram problems",
+			text: `# HELP latency_seconds Request latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{path="/a",le="0.1"} 5
+latency_seconds_bucket{path="/a",le="1"} 4
+latency_seconds_bucket{path="/a",le="+Inf"} 6
+latency_seconds_sum{path="/a"} 1
+latency_seconds_count{path="/a"} 7
+latency_seconds_bucket{path="/b",le="0.5"} 1
+latency_seconds_sum{path="/b"} 1
+latency_seconds_count{path="/b"} 1
+`,
+			want: []lintProblem{
+				{`latency_seconds{path="/a"}`, "bucket le=1 has a lower count than the bucket before it"},
+				{`latency_seconds{path="/a"}`, "+Inf bucket count 6 doesn't match _count 7"},
+				{`latency_seconds{path="/b"}`, "no +Inf bucket"},
+				{`latency_seconds{path="/b"}`, "bucket bounds differ from other series of the histogram"},
+			},
+		},
+		{
+			name: "unparseable",
+			text: "up{job=\"a\" 1\n",
+			want: []lintProblem{{"-", ""}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintExposition([]byte(tt.text))
+			if len(got) != len(tt.want) {
+				t.Fatalf("lintExposition = %v, want %v", got, tt.want)
+			}
+			for _, w := range tt.want {
+				// Problems are only sorted by metric, so look for each.
+				found := slices.ContainsFunc(got, func(p lintProblem) bool {
+					return p.metric == w.metric && strings.Contains(p.problem, w.problem)
+				})
+				if !found {
+					t.Errorf("lintExposition = %v, want %v among them", got, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jaxxstorm/met/internal/scrape"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// logger records what met is doing to --log-file. Without one nothing is
+// logged, as the TUI owns the terminal. It isn't made slog's default, which
+// would also send the log package's fatal errors to the file.
+var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// openLog starts logging at level to path, appending to what's there.
+func openLog(path, level string) (io.Closer, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("bad --log-level %q, want debug, info, warn or error", level)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	logger = slog.New(slog.NewTextHandler(f, &slog.HandlerOptions{Level: lvl}))
+	return f, nil
+}
+
+// logScrape logs a scrape of the model's endpoint: failures as warnings,
+// and the timings of every scrape at debug.
+func (m model) logScrape(s scrape.Stats) {
+	attrs := []any{"endpoint", m.endpoint, "duration", s.Duration}
+	if s.Status != 0 {
+		attrs = append(attrs, "status", s.Status)
+	}
+	switch {
+	case s.ParseFailed:
+		logger.Warn("Parsing the scrape failed", append(attrs, "bytes", s.Bytes, "err", s.Err)...)
+	case s.Err != nil:
+		logger.Warn("Scrape failed", append(attrs, "err", s.Err)...)
+	default:
+		logger.Debug("Scraped", append(attrs, "bytes", s.Bytes, "series", s.Series)...)
+	}
+}
+
+// rejectReason names the filter that rules a new series out, or returns ""
+// if it passes them all.
+func (m model) rejectReason(name string, lbls []*dto.LabelPair) string {
+	switch {
+	case !m.passNameFilters(name):
+		return "include/exclude"
+	case !m.passLabelFilters(lbls):
+		return "labels"
+	case !m.passSelectors(name, lbls):
+		return "select"
+	}
+	return ""
+}
+
+// logStatus logs a new status line, which is where the TUI reports what it
+// did and what went wrong, as a warning if something failed.
+func (m model) logStatus() {
+	level := slog.LevelInfo
+	if strings.Contains(m.status, "failed") {
+		level = slog.LevelWarn
+	}
+	logger.Log(context.Background(), level, m.status, "endpoint", m.endpoint)
+}
+
+// loggedModel logs any panic in the model it wraps, with its stack, before
+// Bubble Tea restores the terminal.
+type loggedModel struct {
+	tea.Model
+}
+
+func (l loggedModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	defer logPanic()
+	m, cmd := l.Model.Update(msg)
+	return loggedModel{m}, cmd
+}
+
+func (l loggedModel) View() string {
+	defer logPanic()
+	return l.Model.View()
+}
+
+func logPanic() {
+	if r := recover(); r != nil {
+		logger.Error("Panic", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+		panic(r)
+	}
+}
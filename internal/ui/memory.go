@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"sort"
+	"time"
+)
+
+// evictedHistory is how many recent points a series keeps when its history
+// is dropped to stay under --max-points, enough for deltas and sparklines
+// to carry on.
+const evictedHistory = 2
+
+// points counts the history points retained for md.
+func (md metricData) points() int {
+	n := len(md.History)
+	for _, row := range md.bucketHistory {
+		n += len(row)
+	}
+	return n
+}
+
+// trimHistory keeps the points retained across all series under
+// --max-points, dropping the history of the series selected least recently
+// first, and notes the heap size for the health bar.
+func (m *model) trimHistory() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	m.heapBytes = ms.HeapAlloc
+
+	m.points = 0
+	for _, md := range m.metricsList {
+		m.points += md.points()
+	}
+	if m.maxPoints <= 0 || m.points <= m.maxPoints {
+		return
+	}
+	order := make([]int, len(m.metricsList))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return m.metricsList[order[i]].selectedAt.Before(m.metricsList[order[j]].selectedAt)
+	})
+	for _, i := range order {
+		if m.points <= m.maxPoints {
+			break
+		}
+		if i == m.selected {
+			continue
+		}
+		md := &m.metricsList[i]
+		before := md.points()
+		// Cloned so the dropped points can be freed.
+		if n := len(md.History); n > evictedHistory {
+			md.History = slices.Clone(md.History[n-evictedHistory:])
+			md.HistoryAt = slices.Clone(md.HistoryAt[n-evictedHistory:])
+		}
+		if n := len(md.scraped); n > evictedHistory {
+			md.scraped = slices.Clone(md.scraped[n-evictedHistory:])
+		}
+		if n := len(md.bucketHistory); n > evictedHistory {
+			md.bucketHistory = slices.Clone(md.bucketHistory[n-evictedHistory:])
+		}
+		m.points -= before - md.points()
+	}
+}
+
+// markSelected records that the selected series was being looked at, so
+// its history is the last to go.
+func (m *model) markSelected(at time.Time) {
+	if m.selected >= 0 && m.selected < len(m.metricsList) {
+		m.metricsList[m.selected].selectedAt = at
+	}
+}
+
+// renderMemory describes the heap in use and the history points retained.
+func (m model) renderMemory() string {
+	if m.heapBytes == 0 {
+		return ""
+	}
+	if m.maxPoints > 0 {
+		return fmt.Sprintf("%s heap, %d/%d points", formatBytes(float64(m.heapBytes)), m.points, m.maxPoints)
+	}
+	return fmt.Sprintf("%s heap, %d points", formatBytes(float64(m.heapBytes)), m.points)
+}
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// isMuted reports whether md is muted: set aside for the rest of the
+// session, sorted after the other series and hidden unless the muted
+// section is expanded. m.muted holds series keys and, to mute every series
+// of a metric, metric names.
+func (m model) isMuted(md metricData) bool {
+	if len(m.muted) == 0 {
+		return false
+	}
+	_, key := m.muted[md.key]
+	_, name := m.muted[md.name]
+	return key || name
+}
+
+// mutedCount is how many of the series are muted.
+func (m model) mutedCount() int {
+	if len(m.muted) == 0 {
+		return 0
+	}
+	n := 0
+	for _, md := range m.metricsList {
+		if m.isMuted(md) {
+			n++
+		}
+	}
+	return n
+}
+
+// sinkMuted wraps less to order muted series last.
+func (m model) sinkMuted(less func(a, b metricData) bool) func(a, b metricData) bool {
+	if len(m.muted) == 0 {
+		return less
+	}
+	return func(a, b metricData) bool {
+		if ma, mb := m.isMuted(a), m.isMuted(b); ma != mb {
+			return mb
+		}
+		return less(a, b)
+	}
+}
+
+// mutedLast reports whether every muted series is after the others, as
+// series that appear later are added at the end of the list.
+func (m model) mutedLast() bool {
+	seen := false
+	for _, md := range m.metricsList {
+		muted := m.isMuted(md)
+		if seen && !muted {
+			return false
+		}
+		seen = seen || muted
+	}
+	return true
+}
+
+// mutePicker asks whether to mute the selected series or every series of
+// its metric, or unmutes it if it's muted already.
+func (m model) mutePicker() model {
+	if m.selected < 0 || m.selected >= len(m.metricsList) {
+		return m
+	}
+	md := m.metricsList[m.selected]
+	if m.isMuted(md) {
+		return m.setMuted(md, false, md.key, md.name)
+	}
+	items := []string{"this series: " + md.key}
+	if !md.synthetic && md.name != md.key {
+		items = append(items, "every "+md.name+" series")
+	}
+	m.picker = &picker{
+		title: "Mute for this session",
+		items: items,
+		choose: func(m model, item string) (model, tea.Cmd) {
+			if item == items[0] {
+				return m.setMuted(md, true, md.key), nil
+			}
+			return m.setMuted(md, true, md.name), nil
+		},
+	}
+	return m
+}
+
+// setMuted mutes or unmutes entries, re-sorting so muted series are last.
+// The set is copied rather than changed in place, as models copied for tabs
+// share it.
+func (m model) setMuted(md metricData, mute bool, entries ...string) model {
+	muted := make(map[string]struct{}, len(m.muted)+1)
+	for k := range m.muted {
+		muted[k] = struct{}{}
+	}
+	for _, e := range entries {
+		if mute {
+			muted[e] = struct{}{}
+		} else {
+			delete(muted, e)
+		}
+	}
+	m.muted = muted
+	switch {
+	case !mute:
+		m.status = fmt.Sprintf("Unmuted %s", md.key)
+	case entries[0] == md.key:
+		m.status = fmt.Sprintf("Muted %s, press U to show muted series", md.key)
+	default:
+		m.status = fmt.Sprintf("Muted every %s series, press U to show muted series", md.name)
+	}
+	at := m.selected
+	m.resortMuted()
+	// The selection stays where the muted series was, rather than following
+	// it out of view.
+	if mute && !m.showMuted {
+		m.selected = min(at, m.visibleLen()-1)
+		m.enforcePageBounds()
+	}
+	return m
+}
+
+// toggleShowMuted expands or collapses the muted series at the end of the
+// table.
+func (m model) toggleShowMuted() model {
+	m.showMuted = !m.showMuted
+	if m.showMuted {
+		m.status = "Showing muted series"
+	} else {
+		m.status = "Hiding muted series"
+	}
+	m.resortMuted()
+	return m
+}
+
+// resortMuted re-sorts the table after the muted set or section changes,
+// keeping the selection on a row that's shown.
+func (m *model) resortMuted() {
+	m.sortMetrics(m.order())
+	if m.selected >= m.visibleLen() {
+		m.selected = m.visibleLen() - 1
+	}
+	if m.selected < 0 && len(m.metricsList) > 0 {
+		m.selected = 0
+	}
+	m.enforcePageBounds()
+}
+
+// renderMuted notes the muted series below the table.
+func (m model) renderMuted() string {
+	n := m.mutedCount()
+	switch {
+	case n == 0:
+		return ""
+	case m.showMuted:
+		return fmt.Sprintf("%d muted series shown last, press U to hide them, X on one to unmute it\n", n)
+	default:
+		return fmt.Sprintf("%d muted series hidden, press U to show them\n", n)
+	}
+}
@@ -0,0 +1,186 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// minKeyWidth is the narrowest the key column is shortened to, however
+// narrow the terminal.
+const minKeyWidth = 16
+
+// fitWidth works out how to fit a table of header and rows into width
+// columns: how many columns to keep, dropping the optional ones (all but
+// the key and value) from the right, and then how wide keys can be, or 0
+// if they fit as they are.
+func (l layout) fitWidth(header []string, rows [][]string, width int) (cols, keyWidth int) {
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = ansi.StringWidth(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], ansi.StringWidth(cell))
+		}
+	}
+	cols = len(widths)
+	for cols > 2 && l.tableWidth(widths[:cols]) > width {
+		cols--
+	}
+	if over := l.tableWidth(widths[:cols]) - width; over > 0 {
+		keyWidth = max(widths[0]-over, minKeyWidth)
+	}
+	return cols, keyWidth
+}
+
+// tableWidth is how wide the table renders with columns of widths.
+func (l layout) tableWidth(widths []int) int {
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	if l == layoutCompact {
+		return total + 2*(len(widths)-1)
+	}
+	// "| " before each column, " " after, and the closing "|".
+	return total + 3*len(widths) + 1
+}
+
+// keyScrollStep is how many cells ← and → scroll keys by.
+const keyScrollStep = 8
+
+// keyOverflow is how far the widest key on the page can be scrolled, or 0
+// if the keys fit.
+func (m model) keyOverflow() int {
+	if m.width <= 0 {
+		return 0
+	}
+	rows, _ := m.pageRows()
+	_, keyWidth := m.layout.fitWidth(m.tableHeader(), rows, m.width)
+	if keyWidth == 0 {
+		return 0
+	}
+	over := 0
+	for _, row := range rows {
+		over = max(over, ansi.StringWidth(row[0])-keyWidth)
+	}
+	return over
+}
+
+// scrollKey fits a key cell, which starts with the selection cursor, into
+// width cells, scrolled left by offset cells, up to where its end shows.
+// The cursor stays put, and an ellipsis marks each end that's cut.
+func scrollKey(cell string, width, offset int) string {
+	cursor, key := cell[:2], cell[2:]
+	width -= 2
+	offset = min(offset, ansi.StringWidth(key)-width)
+	if offset <= 0 {
+		return ansi.Truncate(cell, width+2, ellipsis())
+	}
+	e := ellipsis()
+	rest := dropCells(key, offset+ansi.StringWidth(e))
+	return cursor + e + ansi.Truncate(rest, width-ansi.StringWidth(e), e)
+}
+
+// dropCells drops the first n cells of s, keeping any escape sequences
+// that style what's left. A wide character cut in half leaves a space.
+func dropCells(s string, n int) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '[' {
+			j := i + 2
+			for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e) {
+				j++
+			}
+			j = min(j+1, len(s))
+			sb.WriteString(s[i:j])
+			i = j
+			continue
+		}
+		if n <= 0 {
+			sb.WriteString(s[i:])
+			break
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		n -= ansi.StringWidth(string(r))
+		if n < 0 {
+			sb.WriteString(" ")
+		}
+		i += size
+	}
+	return sb.String()
+}
+
+// renderDetail shows the full key and the dropped cells of the selected
+// series when the table had to be narrowed to fit.
+func renderDetail(header, row []string, cols int, truncated bool) string {
+	if cols >= len(header) && !truncated {
+		return ""
+	}
+	parts := []string{strings.TrimSpace(row[0])}
+	for i := cols; i < len(header); i++ {
+		parts = append(parts, fmt.Sprintf("%s: %s", header[i], row[i]))
+	}
+	return strings.Join(parts, " · ") + "\n"
+}
+
+// asciiOnly is set when the terminal can't show Unicode, with --ascii or
+// when the locale isn't UTF-8.
+var asciiOnly bool
+
+// unicodeTerminal guesses whether the terminal can show the box-drawing
+// and block characters the views use. The classic Windows console can't,
+// unlike Windows Terminal; elsewhere it's down to the locale, assuming
+// Unicode if none is set.
+func unicodeTerminal() bool {
+	if runtime.GOOS == "windows" {
+		return os.Getenv("WT_SESSION") != "" || os.Getenv("TERM_PROGRAM") != ""
+	}
+	for _, env := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(env); v != "" {
+			v = strings.ToLower(v)
+			return strings.Contains(v, "utf-8") || strings.Contains(v, "utf8")
+		}
+	}
+	return true
+}
+
+// ellipsis marks where a shortened key was cut. It's spelled out in ASCII
+// up front, as swapping it afterwards would misalign the table.
+func ellipsis() string {
+	if asciiOnly {
+		return "..."
+	}
+	return "…"
+}
+
+// asciiReplacer swaps the non-ASCII characters the views use for the
+// nearest ASCII ones.
+var asciiReplacer = strings.NewReplacer(
+	"▁", "_", "▂", ".", "▃", "-", "▄", "~", "▅", "=", "▆", "+", "▇", "*", "█", "#",
+	"░", ".", "▒", ":", "▓", "*", "■", "#",
+	"─", "-", "│", "|", "└", "+", "┤", "|", "┼", "+", "┴", "+",
+	"╭", "+", "╮", "+", "╰", "+", "╯", "+", "╴", "-", "╶", "-",
+	"↑", "^", "↓", "v", "←", "<", "→", ">", "▸", ">", "▾", "v",
+	"·", "-", "…", "...", "µ", "u",
+)
+
+// asciiModel renders the views of the model it wraps in plain ASCII.
+type asciiModel struct {
+	tea.Model
+}
+
+func (a asciiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m, cmd := a.Model.Update(msg)
+	return asciiModel{m}, cmd
+}
+
+func (a asciiModel) View() string {
+	return asciiReplacer.Replace(a.Model.View())
+}
@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"math"
+	"slices"
+	"strconv"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// maxNativeBuckets caps the buckets kept for a native histogram as more of
+// them are observed into, matching client_golang's default limit.
+const maxNativeBuckets = 160
+
+// isNativeHistogram reports whether h is a native histogram. Histograms
+// exposing both kinds of buckets are treated as classic ones.
+func isNativeHistogram(h *dto.Histogram) bool {
+	return h.Schema != nil && len(h.GetBucket()) == 0
+}
+
+// nativeBucket is a bucket of a native histogram, by index.
+type nativeBucket struct {
+	index int
+	count float64
+}
+
+// expandSpans decodes the buckets of one sign of a native histogram. Integer
+// histograms have deltas between consecutive counts, float histograms have
+// absolute counts.
+func expandSpans(spans []*dto.BucketSpan, deltas []int64, counts []float64) []nativeBucket {
+	var out []nativeBucket
+	idx, n := 0, 0
+	var cur int64
+	for _, sp := range spans {
+		// The first offset is the starting index, later ones the gap
+		// after the previous span.
+		idx += int(sp.GetOffset())
+		for range sp.GetLength() {
+			var c float64
+			switch {
+			case n < len(deltas):
+				cur += deltas[n]
+				c = float64(cur)
+			case n < len(counts):
+				c = counts[n]
+			}
+			out = append(out, nativeBucket{index: idx, count: c})
+			idx++
+			n++
+		}
+	}
+	return out
+}
+
+// nativeBuckets returns a native histogram's buckets as the upper bounds and
+// cumulative counts of a classic histogram, ending with the +Inf bucket,
+// for the heatmap and quantiles. With schema s, positive bucket i covers
+// (base^(i-1), base^i] for a base of 2^(2^-s), negative buckets mirror
+// them, and a zero bucket covers [-threshold, threshold]. Only the buckets
+// that have been observed into are exposed, as spans of consecutive
+// indexes with their counts delta-encoded.
+func nativeBuckets(h *dto.Histogram) ([]float64, []float64) {
+	schema := float64(h.GetSchema())
+	upper := func(i int) float64 {
+		return math.Exp2(float64(i) * math.Exp2(-schema))
+	}
+	var bounds, counts []float64
+	var cum float64
+	neg := expandSpans(h.GetNegativeSpan(), h.GetNegativeDelta(), h.GetNegativeCount())
+	for _, b := range slices.Backward(neg) {
+		cum += b.count
+		bounds = append(bounds, -upper(b.index-1))
+		counts = append(counts, cum)
+	}
+	cum += max(float64(h.GetZeroCount()), h.GetZeroCountFloat())
+	bounds = append(bounds, h.GetZeroThreshold())
+	counts = append(counts, cum)
+	for _, b := range expandSpans(h.GetPositiveSpan(), h.GetPositiveDelta(), h.GetPositiveCount()) {
+		cum += b.count
+		bounds = append(bounds, upper(b.index))
+		counts = append(counts, cum)
+	}
+	bounds = append(bounds, math.Inf(1))
+	counts = append(counts, max(float64(h.GetSampleCount()), h.GetSampleCountFloat()))
+	return bounds, counts
+}
+
+// mergeBuckets widens the bucket layout of md, a native histogram, to also
+// take in bounds, as buckets appear when first observed into. Earlier
+// increments are kept, as zeros in the new buckets. It returns the new
+// layout with counts, the cumulative counts, spread over it. If the layout
+// would grow past maxNativeBuckets, as it would if the schema changed, the
+// layout is dropped instead to start over.
+func (md *metricData) mergeBuckets(bounds, counts []float64) ([]float64, []float64) {
+	union := slices.Concat(md.bucketBounds, bounds)
+	slices.Sort(union)
+	union = slices.Compact(union)
+	if len(union) > maxNativeBuckets {
+		md.bucketBounds = nil
+		return bounds, counts
+	}
+	md.prevBuckets = spreadCumulative(md.bucketBounds, md.prevBuckets, union)
+	history := make([][]float64, len(md.bucketHistory))
+	for i, incs := range md.bucketHistory {
+		history[i] = make([]float64, len(union))
+		for j, b := range md.bucketBounds {
+			k, _ := slices.BinarySearch(union, b)
+			history[i][k] = incs[j]
+		}
+	}
+	md.bucketHistory = history
+	md.bucketBounds = union
+	return union, spreadCumulative(bounds, counts, union)
+}
+
+// spreadCumulative maps cumulative counts at bounds onto the wider layout
+// union. A bound missing from bounds had nothing observed into it, so its
+// count is that of the bound below it.
+func spreadCumulative(bounds, counts, union []float64) []float64 {
+	out := make([]float64, len(union))
+	j := 0
+	var cum float64
+	for i, b := range union {
+		for j < len(bounds) && bounds[j] <= b {
+			cum = counts[j]
+			j++
+		}
+		out[i] = cum
+	}
+	return out
+}
+
+// bucketLabel labels a bucket by its upper bound. Native bounds are powers
+// of irrational bases, so they're shortened.
+func (md metricData) bucketLabel(b float64) string {
+	if md.native && !math.IsInf(b, 0) {
+		return "le=" + strconv.FormatFloat(b, 'g', 4, 64)
+	}
+	return "le=" + formatFloat(b)
+}
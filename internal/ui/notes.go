@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jaxxstorm/met/internal/store"
+)
+
+// notePrompt asks for the selected series' note, starting from the one it
+// has, if any. Notes are short observations such as "spiked right after
+// the deploy", shown under the table when the series is selected and kept
+// with the data in snapshots, CSV exports and stored sessions, so what was
+// noticed during an incident isn't lost.
+func (m model) notePrompt() model {
+	if m.selected < 0 || m.selected >= len(m.metricsList) {
+		return m
+	}
+	key := m.metricsList[m.selected].key
+	m.prompt = &prompt{
+		label: fmt.Sprintf("Note on %s (empty to remove)", key),
+		value: m.notes[key],
+		submit: func(m model, note string) (model, tea.Cmd) {
+			return m.setNote(key, note)
+		},
+	}
+	return m
+}
+
+// setNote sets or, when note is empty, removes the note on the series with
+// key, saving the session's notes to the store when recording to one. The
+// map is copied rather than changed in place, as models copied for tabs
+// share it.
+func (m model) setNote(key, note string) (model, tea.Cmd) {
+	note = strings.TrimSpace(note)
+	if note == m.notes[key] {
+		return m, nil
+	}
+	notes := maps.Clone(m.notes)
+	if notes == nil {
+		notes = make(map[string]string)
+	}
+	if note == "" {
+		delete(notes, key)
+		m.status = fmt.Sprintf("Removed the note on %s", key)
+	} else {
+		notes[key] = note
+		m.status = fmt.Sprintf("Noted %s", key)
+	}
+	m.notes = notes
+	if m.store == nil {
+		return m, nil
+	}
+	return m, saveNotesCmd(m.store, m.session, notes)
+}
+
+// renderNote shows the note on the selected series, if it has one.
+func (m model) renderNote() string {
+	if m.selected < 0 || m.selected >= len(m.metricsList) {
+		return ""
+	}
+	note, ok := m.notes[m.metricsList[m.selected].key]
+	if !ok {
+		return ""
+	}
+	return m.theme.stale.Render("Note: "+note) + "\n"
+}
+
+// renderNotes lists every note of the session under a heading, for
+// snapshots.
+func (m model) renderNotes() string {
+	if len(m.notes) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(m.notes))
+	for key := range m.notes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	sb.WriteString("\n## Notes\n\n")
+	for _, key := range keys {
+		sb.WriteString(fmt.Sprintf("- `%s`: %s\n", key, m.notes[key]))
+	}
+	return sb.String()
+}
+
+func saveNotesCmd(s *store.Store, session uint64, notes map[string]string) tea.Cmd {
+	return func() tea.Msg {
+		if err := s.SetNotes(session, notes); err != nil {
+			return statusMsg(fmt.Sprintf("Storing notes failed: %v", err))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newSeriesHighlight is how long a series that appeared mid-session stays
+// highlighted.
+const newSeriesHighlight = time.Minute
+
+// trackNew records every series in a scrape, reporting the ones that
+// haven't been seen before. Series are tracked before filtering, so
+// changing filters doesn't make existing series look new. Nothing is
+// reported for the first scrape.
+func (m *model) trackNew(series []scrapedSeries) []string {
+	first := m.known == nil
+	if first {
+		m.known = make(map[string]struct{}, len(series))
+	}
+	var added []string
+	for _, ss := range series {
+		if _, ok := m.known[ss.key]; ok {
+			continue
+		}
+		m.known[ss.key] = struct{}{}
+		if !first {
+			added = append(added, ss.key)
+		}
+	}
+	return added
+}
+
+// isNew reports whether the series appeared mid-session recently enough to
+// be highlighted.
+func (md metricData) isNew(now time.Time) bool {
+	return !md.appeared.IsZero() && now.Sub(md.appeared) < newSeriesHighlight
+}
+
+// notifyCmd alerts on newly appeared series with the terminal bell or a
+// desktop notification.
+func notifyCmd(how string, keys []string) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		switch how {
+		case "bell":
+			_, err = os.Stderr.WriteString("\a")
+		case "desktop":
+			err = desktopNotify("met: new series", strings.Join(keys, "\n"))
+		}
+		if err != nil {
+			return statusMsg(fmt.Sprintf("Notification failed: %v", err))
+		}
+		return nil
+	}
+}
+
+func desktopNotify(title, body string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", fmt.Sprintf("display notification %q with title %q", body, title))
+	case "windows":
+		return fmt.Errorf("desktop notifications aren't supported on windows")
+	default:
+		cmd = exec.Command("notify-send", title, body)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", cmd.Path, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
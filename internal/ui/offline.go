@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// scrapeCacheHeader starts a cached scrape, followed by its families, each
+// a delimited protobuf message as met agent sends them.
+type scrapeCacheHeader struct {
+	Endpoint string    `json:"endpoint"`
+	At       time.Time `json:"at"`
+	Families int       `json:"families"`
+}
+
+// cachedMsg carries a scrape read back from the cache.
+type cachedMsg struct {
+	families map[string]*dto.MetricFamily
+	at       time.Time
+	err      error
+}
+
+// defaultCacheDir is met's directory in the user's cache directory, or empty
+// if there isn't one.
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "met")
+}
+
+// cachePath is the file endpoint's scrapes are cached in.
+func cachePath(dir, endpoint string) string {
+	h := fnv.New64a()
+	h.Write([]byte(endpoint))
+	return filepath.Join(dir, fmt.Sprintf("%016x.scrape", h.Sum64()))
+}
+
+// saveScrapeCache replaces the cached scrape of endpoint, so if it's down
+// when met next starts, the values from this run are shown until it's back.
+// It's written to a temporary file first, so a scrape is never read
+// half-written.
+func saveScrapeCache(dir, endpoint string, families map[string]*dto.MetricFamily, at time.Time) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".scrape-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	w := bufio.NewWriter(f)
+	header, err := json.Marshal(scrapeCacheHeader{Endpoint: endpoint, At: at, Families: len(families)})
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.Write(append(header, '\n'))
+	for _, mf := range families {
+		if _, err := protodelim.MarshalTo(w, mf); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), cachePath(dir, endpoint))
+}
+
+// loadScrapeCache reads back the cached scrape of endpoint.
+func loadScrapeCache(dir, endpoint string) (map[string]*dto.MetricFamily, time.Time, error) {
+	f, err := os.Open(cachePath(dir, endpoint))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var header scrapeCacheHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, time.Time{}, fmt.Errorf("%s isn't a cached scrape", f.Name())
+	}
+	if header.Endpoint != endpoint {
+		// Another endpoint's hash collided with this one's.
+		return nil, time.Time{}, fs.ErrNotExist
+	}
+	families := make(map[string]*dto.MetricFamily, header.Families)
+	for range header.Families {
+		mf := &dto.MetricFamily{}
+		if err := (protodelim.UnmarshalOptions{MaxSize: -1}).UnmarshalFrom(r, mf); err != nil {
+			return nil, time.Time{}, fmt.Errorf("reading %s: %w", f.Name(), err)
+		}
+		families[mf.GetName()] = mf
+	}
+	return families, header.At, nil
+}
+
+func saveCacheCmd(dir, endpoint string, families map[string]*dto.MetricFamily, at time.Time) tea.Cmd {
+	return func() tea.Msg {
+		if err := saveScrapeCache(dir, endpoint, families, at); err != nil {
+			return statusMsg(fmt.Sprintf("Caching scrape failed: %v", err))
+		}
+		return nil
+	}
+}
+
+func loadCacheCmd(dir, endpoint string) tea.Cmd {
+	return func() tea.Msg {
+		families, at, err := loadScrapeCache(dir, endpoint)
+		return cachedMsg{families: families, at: at, err: err}
+	}
+}
+
+// showCached shows a scrape read back from the cache, unless the endpoint
+// has been scraped since it was asked for.
+func (m model) showCached(msg cachedMsg) model {
+	if msg.err != nil {
+		if !errors.Is(msg.err, fs.ErrNotExist) {
+			m.status = fmt.Sprintf("Reading the cached scrape failed: %v", msg.err)
+		}
+		return m
+	}
+	if m.initialized {
+		return m
+	}
+	m, series := m.budgetSeries(prepareSeries(msg.families))
+	m = applyScrape(m, series, msg.families, msg.at)
+	m.sortMetrics(m.order())
+	m.initialized = true
+	m.cachedAt = msg.at
+	m.enforcePageBounds()
+	return m
+}
+
+// dropCached forgets the values read back from the cache once the endpoint
+// can be scraped again, so counters don't appear to have jumped by all
+// they've counted since.
+func (m model) dropCached() model {
+	m.metricsList = nil
+	m.metricsIndex = nil
+	m.lastScrape = time.Time{}
+	m.processStart = 0
+	m.initialized = false
+	m.cachedAt = time.Time{}
+	m.selected, m.pageStart = 0, 0
+	return m
+}
+
+// renderOffline explains, above the table, that the endpoint can't be
+// scraped and that the values shown, greyed out, are the last ones that
+// were, rather than the error taking over the screen.
+func (m model) renderOffline() string {
+	if m.err == nil {
+		return ""
+	}
+	from := "scraped"
+	if !m.cachedAt.IsZero() {
+		from = "cached by an earlier run"
+	}
+	age := time.Since(m.lastScrape).Round(time.Second)
+	return m.theme.negative.Render(fmt.Sprintf("Error: %v", m.err)) + "\n" +
+		m.theme.stale.Render(fmt.Sprintf("Showing the last values, %s at %s (%s ago)", from, m.lastScrape.Format(time.DateTime), age)) + "\n\n"
+}
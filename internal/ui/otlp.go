@@ -0,0 +1,5 @@
+package ui
+
+type OtlpCmd struct {
+	Listen string `help:"Address to receive OTLP/HTTP metrics on" default:":4318"`
+}
@@ -0,0 +1,164 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guptarohit/asciigraph"
+)
+
+// maxOverlay is how many series can be pinned to the overlaid graph.
+const maxOverlay = 6
+
+// toggleOverlay pins the selected series to the overlaid graph, or unpins
+// it. The slice is copied rather than changed in place, as models copied
+// for tabs share it.
+func (m model) toggleOverlay() model {
+	if m.selected < 0 || m.selected >= len(m.metricsList) {
+		return m
+	}
+	key := m.metricsList[m.selected].key
+	if i := slices.Index(m.overlay, key); i >= 0 {
+		m.overlay = slices.Delete(slices.Clone(m.overlay), i, i+1)
+		m.status = fmt.Sprintf("Unpinned %s from the graph", key)
+		return m
+	}
+	if len(m.overlay) >= maxOverlay {
+		m.status = fmt.Sprintf("At most %d series can be overlaid, press o on one to unpin it", maxOverlay)
+		return m
+	}
+	m.overlay = append(slices.Clone(m.overlay), key)
+	m.status = fmt.Sprintf("Pinned %s to the graph, %d series overlaid", key, len(m.overlay))
+	return m
+}
+
+// overlaySeries lists the series to overlay: those pinned that are still
+// there, followed by the selected series if it isn't one of them.
+func (m model) overlaySeries() []metricData {
+	var out []metricData
+	for _, key := range m.overlay {
+		if idx, ok := m.metricsIndex[key]; ok && idx < len(m.metricsList) {
+			out = append(out, m.metricsList[idx])
+		}
+	}
+	if m.selected >= 0 && m.selected < len(m.metricsList) {
+		if md := m.metricsList[m.selected]; !slices.Contains(m.overlay, md.key) && len(out) < maxOverlay {
+			out = append(out, md)
+		}
+	}
+	return out
+}
+
+// renderOverlay graphs the pinned series and the selected one on the same
+// axes, in the theme's overlay colors, with a legend.
+func (m model) renderOverlay() string {
+	var series []metricData
+	var data [][]float64
+	for _, md := range m.overlaySeries() {
+		if m.graphMode == graphQuantile && md.bucketBounds == nil {
+			continue
+		}
+		points := m.graphPoints(md)
+		if lo, _ := minMax(points); !math.IsInf(lo, 1) {
+			series = append(series, md)
+			data = append(data, points)
+		}
+	}
+	if len(data) == 0 {
+		return "(no data)"
+	}
+	// Series with shorter histories are padded at the start, so the
+	// latest points line up on the right.
+	n := 0
+	for _, points := range data {
+		n = max(n, len(points))
+	}
+	var all []float64
+	for i, points := range data {
+		data[i] = append(slices.Repeat([]float64{math.NaN()}, n-len(points)), points...)
+		all = append(all, points...)
+	}
+	scale := "linear"
+	if m.graphScale.isLog(all) {
+		scale = "log"
+		for i := range data {
+			data[i] = log10Points(data[i])
+		}
+	}
+	// With --color-by, series share the color of their label's value
+	// rather than each having their own.
+	palette := make([]int, len(series))
+	colors := make([]asciigraph.AnsiColor, len(data))
+	for i := range colors {
+		palette[i] = i
+		if c, ok := m.labelColor(series[i]); ok {
+			palette[i] = c
+		}
+		colors[i] = m.theme.overlayColor(palette[i])
+	}
+	format := m.formatter(series[0])
+	plot := func(width int) (string, int) {
+		graph := asciigraph.PlotMany(data, asciigraph.Height(12), asciigraph.Width(width), asciigraph.SeriesColors(colors...))
+		axis := axisColumn(graph)
+		if scale == "log" {
+			return relabelAxis(graph, axis, func(v float64) string { return format(math.Pow(10, v)) })
+		}
+		return graph, axis
+	}
+	width := defaultGraphWidth
+	graph, axis := plot(width)
+	if m.width > 0 {
+		width = max(m.width-axis-1, minGraphWidth)
+		graph, axis = plot(width)
+	}
+	longest := series[0]
+	for _, md := range series {
+		if len(md.HistoryAt) > len(longest.HistoryAt) {
+			longest = md
+		}
+	}
+	indent := strings.Repeat(" ", axis)
+	var sb strings.Builder
+	sb.WriteString(graph + "\n")
+	sb.WriteString(renderTimeAxis(graphTimes(longest, n), axis, width) + "\n")
+	sb.WriteString(indent + fmt.Sprintf("%d series [%s, %s scale]", len(series), m.graphModeName(), scale))
+	for i, md := range series {
+		current := math.NaN()
+		for j := len(data[i]) - 1; j >= 0 && math.IsNaN(current); j-- {
+			current = data[i][j]
+		}
+		if scale == "log" {
+			current = math.Pow(10, current)
+		}
+		pinned := ""
+		if !slices.Contains(m.overlay, md.key) {
+			pinned = " (selected)"
+		}
+		sb.WriteString("\n" + indent + m.theme.overlayStyle(palette[i]).Render("■") + " " + md.key + pinned + " · " + m.formatter(md)(current))
+	}
+	return sb.String()
+}
+
+// overlayColor is the color of the ith overlaid series. asciigraph only
+// takes ANSI color numbers, so others are left uncolored.
+func (t theme) overlayColor(i int) asciigraph.AnsiColor {
+	if len(t.overlay) == 0 {
+		return asciigraph.Default
+	}
+	n, err := strconv.Atoi(t.overlay[i%len(t.overlay)])
+	if err != nil || n < 0 || n > 255 {
+		return asciigraph.Default
+	}
+	return asciigraph.AnsiColor(n)
+}
+
+func (t theme) overlayStyle(i int) lipgloss.Style {
+	if len(t.overlay) == 0 {
+		return style("")
+	}
+	return style(t.overlay[i%len(t.overlay)])
+}
@@ -0,0 +1,373 @@
+package ui
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"math"
+)
+
+// paletteCommand is a command the palette, opened with :, can run. They're
+// typed vim-style, such as ":sort rate desc" or ":interval 500ms", for
+// things that don't deserve a key of their own, and registered in
+// paletteCommands.
+type paletteCommand struct {
+	name  string
+	usage string
+	help  string
+	run   func(m model, args []string) (model, tea.Cmd, error)
+	// complete, if set, lists the completions of the command's last
+	// argument.
+	complete func(m model, arg string) []string
+}
+
+// paletteCommands is filled in by init, as the help command lists them.
+var paletteCommands []paletteCommand
+
+func init() {
+	paletteCommands = []paletteCommand{
+		{name: "sort", usage: "sort key|rate|value|delta [asc|desc]", help: "order the table",
+			run: runSort, complete: completeWords(sortOrders...)},
+		{name: "filter", usage: "filter TEXT", help: "add or remove an include filter",
+			run: filterCommand(filterInclude)},
+		{name: "exclude", usage: "exclude TEXT", help: "add or remove an exclude filter",
+			run: filterCommand(filterExclude)},
+		{name: "label", usage: "label NAME=VALUE", help: "add or remove a label filter",
+			run: filterCommand(filterLabel), complete: completeLabelFilter},
+		{name: "interval", usage: "interval DURATION", help: "change how often the endpoint is scraped",
+			run: runInterval},
+		{name: "top", usage: "top N", help: "show the N series with the highest rates, or 0 for all",
+			run: runTop},
+		{name: "export", usage: "export csv|markdown [PATH]", help: "save the series shown to a file",
+			run: runExport, complete: completeWords("csv", "markdown")},
+		{name: "color-by", usage: "color-by [LABEL]", help: "color series by a label's value, picked from a list without one",
+			run: runColorBy, complete: completeLabelNames},
+		{name: "increase", usage: "increase", help: "show or hide how much counters went up over the last 1, 5 and 15 minutes",
+			run: runIncrease},
+		{name: "help", usage: "help", help: "list the commands",
+			run: runHelp},
+	}
+}
+
+// findCommand looks up a command by name, or by a prefix only it has.
+func findCommand(name string) (paletteCommand, bool) {
+	var found []paletteCommand
+	for _, c := range paletteCommands {
+		if c.name == name {
+			return c, true
+		}
+		if strings.HasPrefix(c.name, name) {
+			found = append(found, c)
+		}
+	}
+	if len(found) == 1 {
+		return found[0], true
+	}
+	return paletteCommand{}, false
+}
+
+// palettePrompt opens the palette, starting with value.
+func (m model) palettePrompt(value string) model {
+	m.prompt = &prompt{
+		label:    "Command (:help lists them)",
+		value:    value,
+		submit:   runPalette,
+		complete: completePalette,
+	}
+	return m
+}
+
+// runPalette runs the command line typed into the palette.
+func runPalette(m model, line string) (model, tea.Cmd) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return m, nil
+	}
+	c, ok := findCommand(fields[0])
+	if !ok {
+		m.status = fmt.Sprintf("Unknown command %q, :help lists them", fields[0])
+		return m, nil
+	}
+	nm, cmd, err := c.run(m, fields[1:])
+	if err != nil {
+		m.status = fmt.Sprintf(":%s: %v (usage: %s)", c.name, err, c.usage)
+		return m, nil
+	}
+	return nm, cmd
+}
+
+// completePalette completes command names, and then the arguments of
+// commands that know theirs.
+func completePalette(m model, value string) []string {
+	name, arg, hasArgs := strings.Cut(value, " ")
+	if !hasArgs {
+		var out []string
+		for _, c := range paletteCommands {
+			if strings.HasPrefix(c.name, name) {
+				out = append(out, c.name+" ")
+			}
+		}
+		return out
+	}
+	c, ok := findCommand(name)
+	if !ok || c.complete == nil {
+		return nil
+	}
+	// Only the last argument is completed, keeping those before it.
+	before := ""
+	if i := strings.LastIndexByte(arg, ' '); i >= 0 {
+		before, arg = arg[:i+1], arg[i+1:]
+	}
+	var out []string
+	for _, s := range c.complete(m, arg) {
+		out = append(out, c.name+" "+before+s)
+	}
+	return out
+}
+
+func completeWords(words ...string) func(m model, arg string) []string {
+	return func(m model, arg string) []string {
+		var out []string
+		for _, w := range words {
+			if strings.HasPrefix(w, arg) {
+				out = append(out, w)
+			}
+		}
+		return out
+	}
+}
+
+// sortOrders are the orders :sort knows, with "asc" and "desc" to complete
+// after them.
+var sortOrders = []string{"key", "rate", "value", "delta", "asc", "desc"}
+
+// order is how the table is sorted: by rate in top mode, otherwise as set
+// with :sort, by key to begin with.
+func (m model) order() func(a, b metricData) bool {
+	if m.topN > 0 {
+		return byRate
+	}
+	var less func(a, b metricData) bool
+	switch m.sortBy {
+	case "rate":
+		less = func(a, b metricData) bool { return math.Abs(a.Rate) < math.Abs(b.Rate) }
+	case "value":
+		less = func(a, b metricData) bool { return a.Value < b.Value }
+	case "delta":
+		less = func(a, b metricData) bool { return a.Delta < b.Delta }
+	default:
+		if !m.sortDesc {
+			return byKey
+		}
+		return func(a, b metricData) bool { return byKey(b, a) }
+	}
+	// Ties fall back to key order, so rows don't shuffle on every scrape.
+	return func(a, b metricData) bool {
+		switch {
+		case less(a, b):
+			return !m.sortDesc
+		case less(b, a):
+			return m.sortDesc
+		}
+		return byKey(a, b)
+	}
+}
+
+func runSort(m model, args []string) (model, tea.Cmd, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return m, nil, errors.New("expected an order")
+	}
+	by := args[0]
+	if !slices.Contains(sortOrders[:4], by) {
+		return m, nil, fmt.Errorf("unknown order %q", by)
+	}
+	// Keys read best A to Z, the rest largest first.
+	desc := by != "key"
+	if len(args) == 2 {
+		switch args[1] {
+		case "asc":
+			desc = false
+		case "desc":
+			desc = true
+		default:
+			return m, nil, fmt.Errorf("expected asc or desc, not %q", args[1])
+		}
+	}
+	if m.topN > 0 {
+		return m, nil, errors.New("top mode always sorts by rate, :top 0 turns it off")
+	}
+	m.sortBy, m.sortDesc = by, desc
+	if by == "key" && !desc {
+		m.sortBy = ""
+	}
+	m.sortMetrics(m.order())
+	m.enforcePageBounds()
+	direction := "ascending"
+	if desc {
+		direction = "descending"
+	}
+	m.status = fmt.Sprintf("Sorting by %s, %s", by, direction)
+	return m, nil, nil
+}
+
+// filterCommand toggles a filter of kind, as i, x and l do.
+func filterCommand(kind filterKind) func(m model, args []string) (model, tea.Cmd, error) {
+	return func(m model, args []string) (model, tea.Cmd, error) {
+		if len(args) != 1 {
+			return m, nil, errors.New("expected one filter")
+		}
+		return m.toggleFilterValue(kind, args[0]), nil, nil
+	}
+}
+
+// runInterval changes the scrape interval, from the next scrape after the
+// one already scheduled.
+func runInterval(m model, args []string) (model, tea.Cmd, error) {
+	if len(args) != 1 {
+		return m, nil, errors.New("expected a duration")
+	}
+	d, err := time.ParseDuration(args[0])
+	if err != nil {
+		return m, nil, err
+	}
+	if d <= 0 {
+		return m, nil, errors.New("the interval must be positive")
+	}
+	m.interval = d
+	m.status = fmt.Sprintf("Scraping every %s", d)
+	return m, nil, nil
+}
+
+func runTop(m model, args []string) (model, tea.Cmd, error) {
+	if len(args) != 1 {
+		return m, nil, errors.New("expected a number of series")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 {
+		return m, nil, fmt.Errorf("%q isn't a number of series", args[0])
+	}
+	m.topN = n
+	m.sortMetrics(m.order())
+	if m.selected >= m.visibleLen() {
+		m.selected = m.visibleLen() - 1
+	}
+	m.enforcePageBounds()
+	if n == 0 {
+		m.status = "Showing every series"
+	} else {
+		m.status = fmt.Sprintf("Showing the top %d series by rate", n)
+	}
+	return m, nil, nil
+}
+
+// runExport saves the series shown, all of them rather than just the page,
+// as CSV, or the table as a markdown snapshot as S does. Files go to
+// --snapshot-dir unless a path is given.
+func runExport(m model, args []string) (model, tea.Cmd, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return m, nil, errors.New("expected a format")
+	}
+	now := time.Now()
+	path := ""
+	if len(args) == 2 {
+		path = args[1]
+	}
+	switch args[0] {
+	case "csv":
+		content, err := m.exportCSV()
+		if err != nil {
+			return m, nil, err
+		}
+		if path == "" {
+			path = filepath.Join(m.snapshotDir, fmt.Sprintf("met-export-%s.csv", now.Format("20060102-150405")))
+		}
+		return m, saveCmd(content, path, "Export", false), nil
+	case "markdown":
+		if path == "" {
+			return m, snapshotCmd(m.snapshot(now), m.snapshotDir, m.snapshotClipboard, now), nil
+		}
+		return m, saveCmd(m.snapshot(now), path, "Snapshot", false), nil
+	}
+	return m, nil, fmt.Errorf("unknown format %q", args[0])
+}
+
+// exportCSV renders the series shown as CSV, a row each with raw values.
+func (m model) exportCSV() (string, error) {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	w.Write([]string{"name", "labels", "type", "value", "delta", "total", "rate", "note"})
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	for _, md := range m.metricsList[:max(m.visibleLen(), 0)] {
+		kind := "gauge"
+		switch {
+		case md.synthetic:
+			kind = "expression"
+		case md.Counter:
+			kind = "counter"
+		}
+		w.Write([]string{md.name, md.labels, kind, format(md.Value), format(md.Delta),
+			format(md.Current()), format(md.Rate), m.notes[md.key]})
+	}
+	w.Flush()
+	return sb.String(), w.Error()
+}
+
+func runColorBy(m model, args []string) (model, tea.Cmd, error) {
+	switch len(args) {
+	case 0:
+		return m.colorByPicker(), nil, nil
+	case 1:
+		return m.setColorBy(args[0]), nil, nil
+	}
+	return m, nil, errors.New("expected a label name")
+}
+
+func runIncrease(m model, args []string) (model, tea.Cmd, error) {
+	if len(args) != 0 {
+		return m, nil, errors.New("increase takes no arguments")
+	}
+	return m.toggleIncrease(), nil, nil
+}
+
+// completeLabelNames completes the names of the labels of the series shown.
+func completeLabelNames(m model, arg string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, md := range m.metricsList {
+		for _, lp := range md.labelPairs {
+			if name := lp.GetName(); strings.HasPrefix(name, arg) && !seen[name] {
+				seen[name] = true
+				out = append(out, name)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// runHelp lists the commands, opening the palette on the one chosen.
+func runHelp(m model, args []string) (model, tea.Cmd, error) {
+	items := make([]string, len(paletteCommands))
+	for i, c := range paletteCommands {
+		items[i] = fmt.Sprintf(":%-38s %s", c.usage, c.help)
+	}
+	m.picker = &picker{
+		title: "Commands",
+		items: items,
+		choose: func(m model, item string) (model, tea.Cmd) {
+			name, _, _ := strings.Cut(strings.TrimPrefix(item, ":"), " ")
+			return m.palettePrompt(name + " "), nil
+		},
+	}
+	return m, nil, nil
+}
@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// filterPreset is a named set of filters saved in the config file.
+type filterPreset struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	Labels  []string `json:"labels,omitempty"`
+	Select  []string `json:"select,omitempty"`
+}
+
+// parseLabelFilters parses name=value label filters.
+func parseLabelFilters(raw []string) ([]labelFilter, error) {
+	var out []labelFilter
+	for _, lf := range raw {
+		name, value, ok := strings.Cut(lf, "=")
+		if !ok {
+			return nil, fmt.Errorf("bad label filter %q, want name=value", lf)
+		}
+		out = append(out, labelFilter{name, value})
+	}
+	return out, nil
+}
+
+// currentPreset captures the model's filters as a preset.
+func (m model) currentPreset() filterPreset {
+	p := filterPreset{Include: m.includes, Exclude: m.excludes}
+	for _, lf := range m.labelFilters {
+		p.Labels = append(p.Labels, lf.name+"="+lf.value)
+	}
+	for _, sel := range m.selectors {
+		p.Select = append(p.Select, sel.source)
+	}
+	return p
+}
+
+// applyPreset replaces the model's filters with the preset's, dropping rows
+// that no longer match straight away. Newly matching series appear on the
+// next scrape.
+func (m model) applyPreset(p filterPreset) (model, error) {
+	labelFilters, err := parseLabelFilters(p.Labels)
+	if err != nil {
+		return m, err
+	}
+	var selectors []selector
+	for _, raw := range p.Select {
+		sel, err := parseSelector(raw)
+		if err != nil {
+			return m, fmt.Errorf("bad selector %q: %w", raw, err)
+		}
+		selectors = append(selectors, sel)
+	}
+	m.includes = p.Include
+	m.excludes = p.Exclude
+	m.labelFilters = labelFilters
+	m.selectors = selectors
+	return m.refilter(), nil
+}
+
+// refilter drops rows that don't pass the current filters.
+func (m model) refilter() model {
+	kept := make([]metricData, 0, len(m.metricsList))
+	index := make(map[string]int, len(m.metricsList))
+	for _, md := range m.metricsList {
+		if !md.synthetic && (!m.passNameFilters(md.name) ||
+			!m.passLabelFilters(md.labelPairs) ||
+			!m.passSelectors(md.name, md.labelPairs)) {
+			continue
+		}
+		index[md.key] = len(kept)
+		kept = append(kept, md)
+	}
+	m.metricsList = kept
+	m.metricsIndex = index
+	// Series rejected by the old filters may pass the new ones.
+	m.rejected = nil
+	if m.selected >= m.visibleLen() {
+		m.selected = m.visibleLen() - 1
+	}
+	if m.selected < 0 {
+		m.selected = 0
+	}
+	m.enforcePageBounds()
+	return m
+}
+
+// savePreset stores p under name in the config file at path, keeping the
+// rest of the file's settings.
+func savePreset(path, name string, p filterPreset) error {
+	if path == "" {
+		return fmt.Errorf("no config file to save presets to")
+	}
+	return updateConfig(path, func(cfg *config) {
+		if cfg.Presets == nil {
+			cfg.Presets = make(map[string]filterPreset)
+		}
+		cfg.Presets[name] = p
+	})
+}
+
+// savePresetPrompt asks for a name and saves the current filters under it.
+func (m model) savePresetPrompt() model {
+	m.prompt = &prompt{
+		label: "Save filters as preset",
+		submit: func(m model, name string) (model, tea.Cmd) {
+			if name == "" {
+				return m, nil
+			}
+			p := m.currentPreset()
+			if err := savePreset(m.configPath, name, p); err != nil {
+				m.status = fmt.Sprintf("Saving preset failed: %v", err)
+				return m, nil
+			}
+			if m.presets == nil {
+				m.presets = make(map[string]filterPreset)
+			}
+			m.presets[name] = p
+			m.status = fmt.Sprintf("Saved preset %q to %s", name, m.configPath)
+			return m, nil
+		},
+	}
+	return m
+}
+
+// presetPicker lists the saved presets to switch to.
+func (m model) presetPicker() model {
+	names := make([]string, 0, len(m.presets))
+	for name := range m.presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	m.picker = &picker{
+		title: "Filter presets",
+		items: names,
+		choose: func(m model, name string) (model, tea.Cmd) {
+			nm, err := m.applyPreset(m.presets[name])
+			if err != nil {
+				m.status = fmt.Sprintf("Applying preset %q failed: %v", name, err)
+				return m, nil
+			}
+			nm.status = fmt.Sprintf("Applied preset %q", name)
+			return nm, nil
+		},
+	}
+	return m
+}
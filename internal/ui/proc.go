@@ -0,0 +1,6 @@
+package ui
+
+type ProcCmd struct {
+	PID     int  `help:"ID of the process to watch" required:""`
+	Collect bool `help:"Always use the built-in collector rather than looking for the process's own metrics endpoint"`
+}
@@ -0,0 +1,170 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// prompt reads a line of text, such as a preset name, in place of the help
+// line. Enter submits it and Esc cancels.
+type prompt struct {
+	label  string
+	value  string
+	submit func(m model, value string) (model, tea.Cmd)
+	// complete, if set, lists the values Tab can complete value to.
+	complete func(m model, value string) []string
+	// cycle holds the completions Tab is stepping through, once the value
+	// can't be extended any further.
+	cycle []string
+}
+
+// maxCompletions is how many completions are listed under a prompt.
+const maxCompletions = 8
+
+// picker lets the user choose one of a list of items with the arrow keys
+// and Enter, or cancel with Esc.
+type picker struct {
+	title  string
+	items  []string
+	cursor int
+	choose func(m model, item string) (model, tea.Cmd)
+}
+
+// capturingInput reports whether keys should go to a prompt, picker,
+// drill-down, diff or tree rather than being treated as commands.
+func (m model) capturingInput() bool {
+	return m.prompt != nil || m.picker != nil || m.drill != nil || m.diff != nil || m.tree != nil
+}
+
+func (m model) updatePrompt(msg tea.KeyMsg) (model, tea.Cmd) {
+	p := *m.prompt
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.prompt = nil
+		return m, nil
+	case tea.KeyEnter:
+		m.prompt = nil
+		return p.submit(m, strings.TrimSpace(p.value))
+	case tea.KeyTab:
+		p = p.completeValue(m)
+		m.prompt = &p
+		return m, nil
+	case tea.KeyBackspace:
+		if r := []rune(p.value); len(r) > 0 {
+			p.value = string(r[:len(r)-1])
+		}
+	case tea.KeySpace:
+		p.value += " "
+	case tea.KeyRunes:
+		p.value += string(msg.Runes)
+	}
+	p.cycle = nil
+	m.prompt = &p
+	return m, nil
+}
+
+// completeValue extends the prompt's value to the longest prefix its
+// completions share, or steps to the next completion if it can't be
+// extended.
+func (p prompt) completeValue(m model) prompt {
+	if p.complete == nil {
+		return p
+	}
+	if p.cycle == nil {
+		matches := p.complete(m, p.value)
+		if len(matches) == 0 {
+			return p
+		}
+		if prefix := commonPrefix(matches); len(prefix) > len(p.value) {
+			p.value = prefix
+			return p
+		}
+		p.cycle = matches
+	}
+	i := slices.Index(p.cycle, p.value)
+	p.value = p.cycle[(i+1)%len(p.cycle)]
+	return p
+}
+
+// commonPrefix is the longest prefix of whole runes that every one of ss
+// starts with, so completing a value never stops partway through a
+// multi-byte character.
+func commonPrefix(ss []string) string {
+	prefix := ss[0]
+	for _, s := range ss[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
+
+func (m model) updatePicker(msg tea.KeyMsg) (model, tea.Cmd) {
+	p := *m.picker
+	switch msg.String() {
+	case "esc", "ctrl+c", "q":
+		m.picker = nil
+		return m, nil
+	case "enter":
+		m.picker = nil
+		if len(p.items) == 0 {
+			return m, nil
+		}
+		return p.choose(m, p.items[p.cursor])
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(p.items)-1 {
+			p.cursor++
+		}
+	}
+	m.picker = &p
+	return m, nil
+}
+
+func (m model) renderPrompt() string {
+	p := m.prompt
+	if p.complete == nil {
+		return fmt.Sprintf("%s: %s█\n(Enter to confirm, Esc to cancel)\n", p.label, p.value)
+	}
+	matches := p.cycle
+	if matches == nil {
+		matches = p.complete(m, p.value)
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s: %s█\n", p.label, p.value))
+	if len(matches) > 0 {
+		shown := matches[:min(len(matches), maxCompletions)]
+		sb.WriteString("  " + strings.Join(shown, "  "))
+		if more := len(matches) - len(shown); more > 0 {
+			sb.WriteString(fmt.Sprintf("  (%d more)", more))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("(Tab to complete, Enter to confirm, Esc to cancel)\n")
+	return sb.String()
+}
+
+func (m model) renderPicker() string {
+	var sb strings.Builder
+	sb.WriteString(m.picker.title + "\n")
+	if len(m.picker.items) == 0 {
+		sb.WriteString("  (none)\n")
+	}
+	for i, item := range m.picker.items {
+		cursor := " "
+		if i == m.picker.cursor {
+			cursor = ">"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s\n", cursor, item))
+	}
+	sb.WriteString("(↑/↓ to move, Enter to choose, Esc to cancel)\n")
+	return sb.String()
+}
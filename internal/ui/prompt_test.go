@@ -0,0 +1,33 @@
+package ui
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCommonPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		ss   []string
+		want string
+	}{
+		{"one", []string{"handler"}, "handler"},
+		{"shared", []string{"/api/users", "/api/orders"}, "/api/"},
+		{"none", []string{"get", "post"}, ""},
+		{"one is a prefix of the other", []string{"code", "code_class"}, "code"},
+		// é and è share their first byte, which mustn't be kept on its own.
+		{"multi-byte runes", []string{"café", "cafè"}, "caf"},
+		{"shared multi-byte runes", []string{"zürich-1", "zürich-2"}, "zürich-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := commonPrefix(tt.ss)
+			if got != tt.want {
+				t.Errorf("commonPrefix(%q) = %q, want %q", tt.ss, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("commonPrefix(%q) = %q, which isn't valid UTF-8", tt.ss, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (lm labelMatcher) String() string {
+	return lm.name + lm.typ.String() + `"` + labelValueEscaper.Replace(lm.value) + `"`
+}
+
+// promQL renders the selector, with the metric name outside the braces
+// when it's matched exactly.
+func (s selector) promQL() string {
+	var name string
+	var matchers []string
+	for _, lm := range s.matchers {
+		if lm.name == "__name__" && lm.typ == matchEqual && name == "" {
+			name = lm.value
+			continue
+		}
+		matchers = append(matchers, lm.String())
+	}
+	if len(matchers) == 0 {
+		return name
+	}
+	return name + "{" + strings.Join(matchers, ",") + "}"
+}
+
+func (n numberNode) promQL(string) string {
+	return strconv.FormatFloat(float64(n), 'g', -1, 64)
+}
+
+func (n negNode) promQL(rng string) string {
+	return "-" + promQLOperand(n.x, rng)
+}
+
+func (n binaryNode) promQL(rng string) string {
+	return promQLOperand(n.lhs, rng) + " " + string(n.op) + " " + promQLOperand(n.rhs, rng)
+}
+
+// promQL sums the matching series, as the expression does. delta() is the
+// change between scrapes, which is closest to increase() over the range.
+func (n *seriesNode) promQL(rng string) string {
+	sel := n.sel.promQL()
+	switch n.fn {
+	case "rate":
+		return fmt.Sprintf("sum(rate(%s[%s]))", sel, rng)
+	case "delta":
+		return fmt.Sprintf("sum(increase(%s[%s]))", sel, rng)
+	}
+	return "sum(" + sel + ")"
+}
+
+// promQLOperand parenthesizes binary expressions, since the tree doesn't
+// keep the parentheses they were parsed with.
+func promQLOperand(x exprNode, rng string) string {
+	if _, ok := x.(binaryNode); ok {
+		return "(" + x.promQL(rng) + ")"
+	}
+	return x.promQL(rng)
+}
+
+// filterSelector selects md's metric, narrowed by the --labels filters and
+// the matchers of the first --select selector md matches.
+func (m model) filterSelector(md metricData) selector {
+	sel := selector{matchers: []labelMatcher{{name: "__name__", typ: matchEqual, value: md.name}}}
+	for _, lf := range m.labelFilters {
+		sel.matchers = append(sel.matchers, labelMatcher{name: lf.name, typ: matchEqual, value: lf.value})
+	}
+	for _, s := range m.selectors {
+		if !s.matches(md.name, md.labelPairs) {
+			continue
+		}
+		for _, lm := range s.matchers {
+			if lm.name != "__name__" {
+				sel.matchers = append(sel.matchers, lm)
+			}
+		}
+		break
+	}
+	return sel
+}
+
+// seriesPromQL returns the PromQL that graphs md's metric the way met does,
+// narrowed by the current filters: the rate of counters, and the --quantile
+// of histograms. Rates are taken over rng.
+func (m model) seriesPromQL(md metricData, rng string) string {
+	if md.synthetic {
+		for _, ex := range m.exprs {
+			if ex.name == md.name {
+				return ex.root.promQL(rng)
+			}
+		}
+	}
+	return m.wrapPromQL(md, m.filterSelector(md), rng)
+}
+
+// wrapPromQL applies what met graphs for md to sel: the rate of counters,
+// and the --quantile of histograms.
+func (m model) wrapPromQL(md metricData, sel selector, rng string) string {
+	switch {
+	case md.native:
+		return fmt.Sprintf("histogram_quantile(%s, sum(rate(%s[%s])))",
+			strconv.FormatFloat(m.quantile, 'g', -1, 64), sel.promQL(), rng)
+	case md.bucketBounds != nil:
+		sel.matchers[0].value += "_bucket"
+		return fmt.Sprintf("histogram_quantile(%s, sum by (le) (rate(%s[%s])))",
+			strconv.FormatFloat(m.quantile, 'g', -1, 64), sel.promQL(), rng)
+	case md.Counter:
+		return fmt.Sprintf("rate(%s[%s])", sel.promQL(), rng)
+	}
+	return sel.promQL()
+}
+
+// promQLRange is the range the PromQL copied with Q takes rates over.
+const promQLRange = "5m"
+
+// promQLPicker offers the PromQL for the selected series to copy: for every
+// series of its metric that passes the filters, and for just that series.
+func (m model) promQLPicker() model {
+	if m.selected < 0 || m.selected >= len(m.metricsList) {
+		return m
+	}
+	md := m.metricsList[m.selected]
+	queries := []string{m.seriesPromQL(md, promQLRange)}
+	items := []string{"every series passing the filters: " + queries[0]}
+	if !md.synthetic {
+		sel := selector{matchers: []labelMatcher{{name: "__name__", typ: matchEqual, value: md.name}}}
+		for _, lp := range md.labelPairs {
+			sel.matchers = append(sel.matchers, labelMatcher{name: lp.GetName(), typ: matchEqual, value: lp.GetValue()})
+		}
+		if q := m.wrapPromQL(md, sel, promQLRange); q != queries[0] {
+			queries = append(queries, q)
+			items = append(items, "just this series: "+q)
+		}
+	}
+	m.picker = &picker{
+		title: "Copy the PromQL for " + md.key,
+		items: items,
+		choose: func(m model, item string) (model, tea.Cmd) {
+			return m, yankCmd(queries[slices.Index(items, item)], "PromQL")
+		},
+	}
+	return m
+}
@@ -0,0 +1,376 @@
+package ui
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jaxxstorm/met/pkg/met"
+)
+
+type PushgatewayCmd struct {
+	URL        string        `arg:"" help:"Base URL of the Pushgateway, e.g. http://localhost:9091"`
+	StaleAfter time.Duration `help:"Highlight groups that haven't been pushed to for this long" default:"5m"`
+}
+
+// pushGroup is one push group on a Pushgateway: the metrics pushed under a
+// grouping key such as job="batch",instance="host-1".
+type pushGroup struct {
+	labels   map[string]string
+	key      string
+	pushed   time.Time
+	failed   bool
+	series   []string
+	families int
+}
+
+// pushgatewayFamily is a metric family as returned by the Pushgateway's
+// /api/v1/metrics endpoint.
+type pushgatewayFamily struct {
+	Type    string `json:"type"`
+	Metrics []struct {
+		Labels map[string]string `json:"labels"`
+		Value  string            `json:"value"`
+		Count  string            `json:"count"`
+		Sum    string            `json:"sum"`
+	} `json:"metrics"`
+}
+
+// fetchPushGroups lists the push groups on a Pushgateway, sorted by
+// grouping key.
+func fetchPushGroups(client *http.Client, base string) ([]pushGroup, error) {
+	resp, err := client.Get(strings.TrimSuffix(base, "/") + "/api/v1/metrics")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, met.StatusError(resp.StatusCode)
+	}
+	var body struct {
+		Data []map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding Pushgateway response: %w", err)
+	}
+
+	groups := make([]pushGroup, 0, len(body.Data))
+	for _, entry := range body.Data {
+		var g pushGroup
+		if err := json.Unmarshal(entry["labels"], &g.labels); err != nil {
+			return nil, fmt.Errorf("decoding push group labels: %w", err)
+		}
+		g.key = groupingKey(g.labels)
+		var ok bool
+		if json.Unmarshal(entry["last_push_successful"], &ok) == nil {
+			g.failed = !ok
+		}
+		for name, raw := range entry {
+			if name == "labels" || name == "last_push_successful" {
+				continue
+			}
+			var fam pushgatewayFamily
+			if err := json.Unmarshal(raw, &fam); err != nil {
+				continue
+			}
+			g.families++
+			for _, pm := range fam.Metrics {
+				value := pm.Value
+				if value == "" {
+					// Histograms and summaries have a count and
+					// sum rather than a value.
+					value = fmt.Sprintf("count=%s sum=%s", pm.Count, pm.Sum)
+				}
+				if name == "push_time_seconds" {
+					if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+						g.pushed = time.Unix(0, int64(secs*float64(time.Second)))
+					}
+				}
+				g.series = append(g.series, fmt.Sprintf("%s{%s} %s", name, seriesLabels(pm.Labels, g.labels), value))
+			}
+		}
+		sort.Strings(g.series)
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].key < groups[j].key })
+	return groups, nil
+}
+
+// groupingKey renders a grouping key with job first, as the Pushgateway
+// shows it.
+func groupingKey(labels map[string]string) string {
+	parts := []string{fmt.Sprintf("job=%q", labels["job"])}
+	names := make([]string, 0, len(labels))
+	for n := range labels {
+		if n != "job" {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		parts = append(parts, fmt.Sprintf("%s=%q", n, labels[n]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// seriesLabels renders a series' labels, leaving out the group's, which
+// every series in the group has.
+func seriesLabels(labels, group map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for n, v := range labels {
+		if group[n] != v {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, n := range names {
+		parts[i] = n + `="` + labelValueEscaper.Replace(labels[n]) + `"`
+	}
+	return strings.Join(parts, ",")
+}
+
+// deleteGroupPath is the API path that deletes a push group. Values that
+// can't appear in a path segment are base64 encoded, as the Pushgateway
+// expects.
+func deleteGroupPath(labels map[string]string) string {
+	seg := func(name, value string) string {
+		if value == "" || strings.Contains(value, "/") {
+			enc := base64.URLEncoding.EncodeToString([]byte(value))
+			if enc == "" {
+				// An empty value still needs a path segment.
+				enc = "="
+			}
+			return name + "@base64/" + enc
+		}
+		return name + "/" + value
+	}
+	path := "/metrics/" + seg("job", labels["job"])
+	names := make([]string, 0, len(labels))
+	for n := range labels {
+		if n != "job" {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		path += "/" + seg(n, labels[n])
+	}
+	return path
+}
+
+func deleteGroupCmd(client *http.Client, base string, g pushGroup) tea.Cmd {
+	return func() tea.Msg {
+		req, err := http.NewRequest(http.MethodDelete, strings.TrimSuffix(base, "/")+deleteGroupPath(g.labels), nil)
+		if err != nil {
+			return statusMsg(fmt.Sprintf("Deleting %s failed: %v", g.key, err))
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return statusMsg(fmt.Sprintf("Deleting %s failed: %v", g.key, err))
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return statusMsg(fmt.Sprintf("Deleting %s failed: %v", g.key, met.StatusError(resp.StatusCode)))
+		}
+		return pushDeletedMsg(g.key)
+	}
+}
+
+type pushGroupsMsg struct {
+	groups []pushGroup
+	err    error
+}
+
+type pushDeletedMsg string
+
+func fetchPushGroupsCmd(client *http.Client, base string) tea.Cmd {
+	return func() tea.Msg {
+		groups, err := fetchPushGroups(client, base)
+		return pushGroupsMsg{groups: groups, err: err}
+	}
+}
+
+// pushgatewayModel browses the push groups on a Pushgateway as a tree of
+// groups and their series.
+type pushgatewayModel struct {
+	client     *http.Client
+	base       string
+	interval   time.Duration
+	staleAfter time.Duration
+	theme      theme
+	pageSize   int
+
+	groups   []pushGroup
+	expanded map[string]bool
+	cursor   int
+	// confirm is the group waiting for y to be deleted.
+	confirm *pushGroup
+	status  string
+	err     error
+	quit    bool
+}
+
+// pushRow is a line of the tree: a group, or one of its series when
+// series is non-negative.
+type pushRow struct {
+	group  int
+	series int
+}
+
+func (m pushgatewayModel) rows() []pushRow {
+	var rows []pushRow
+	for i, g := range m.groups {
+		rows = append(rows, pushRow{group: i, series: -1})
+		if m.expanded[g.key] {
+			for j := range g.series {
+				rows = append(rows, pushRow{group: i, series: j})
+			}
+		}
+	}
+	return rows
+}
+
+func (m pushgatewayModel) Init() tea.Cmd {
+	return fetchPushGroupsCmd(m.client, m.base)
+}
+
+func (m pushgatewayModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tickMsg:
+		return m, fetchPushGroupsCmd(m.client, m.base)
+	case pushGroupsMsg:
+		m.err = msg.err
+		if msg.err == nil {
+			m.groups = msg.groups
+		}
+		if n := len(m.rows()); m.cursor >= n {
+			m.cursor = max(n-1, 0)
+		}
+		return m, tickCmd(m.interval)
+	case pushDeletedMsg:
+		m.status = "Deleted " + string(msg)
+		// Refresh straight away. The tick already pending will refresh
+		// again, which is harmless.
+		return m, fetchPushGroupsCmd(m.client, m.base)
+	case statusMsg:
+		m.status = string(msg)
+	case tea.KeyMsg:
+		if m.confirm != nil {
+			g := *m.confirm
+			m.confirm = nil
+			if msg.String() == "y" {
+				m.status = "Deleting " + g.key
+				return m, deleteGroupCmd(m.client, m.base, g)
+			}
+			m.status = "Not deleted"
+			return m, nil
+		}
+		rows := m.rows()
+		switch msg.String() {
+		case "ctrl+c", "q":
+			m.quit = true
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(rows)-1 {
+				m.cursor++
+			}
+		case "enter", " ":
+			if m.cursor < len(rows) {
+				r := rows[m.cursor]
+				key := m.groups[r.group].key
+				expanded := make(map[string]bool, len(m.expanded)+1)
+				for k, v := range m.expanded {
+					expanded[k] = v
+				}
+				expanded[key] = !expanded[key]
+				m.expanded = expanded
+				// Collapsing from a series moves up to its group.
+				m.cursor = 0
+				for i, nr := range m.rows() {
+					if nr.group == r.group && (nr.series == r.series || nr.series == -1 && !expanded[key]) {
+						m.cursor = i
+						break
+					}
+				}
+			}
+		case "d":
+			if m.cursor < len(rows) {
+				g := m.groups[rows[m.cursor].group]
+				m.confirm = &g
+			}
+		case "r":
+			return m, fetchPushGroupsCmd(m.client, m.base)
+		}
+	}
+	return m, nil
+}
+
+func (m pushgatewayModel) View() string {
+	if m.quit {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(m.theme.title.Render(fmt.Sprintf("Push groups on %s (every %s)", m.base, m.interval)) + "\n\n")
+	if m.err != nil {
+		sb.WriteString(fmt.Sprintf("Error: %v\n\n", m.err))
+	}
+	rows := m.rows()
+	if len(rows) == 0 && m.err == nil {
+		sb.WriteString("No push groups.\n")
+	}
+	start := 0
+	if m.cursor >= m.pageSize {
+		start = m.cursor - m.pageSize + 1
+	}
+	end := min(start+m.pageSize, len(rows))
+	now := time.Now()
+	for i := start; i < end; i++ {
+		r := rows[i]
+		g := m.groups[r.group]
+		cursor := " "
+		if i == m.cursor {
+			cursor = ">"
+		}
+		if r.series >= 0 {
+			sb.WriteString(fmt.Sprintf("%s     %s\n", cursor, g.series[r.series]))
+			continue
+		}
+		arrow := "▸"
+		if m.expanded[g.key] {
+			arrow = "▾"
+		}
+		age := "never pushed"
+		if !g.pushed.IsZero() {
+			age = "pushed " + now.Sub(g.pushed).Truncate(time.Second).String() + " ago"
+		}
+		line := fmt.Sprintf("%s %s {%s}  %d series, %s", cursor, arrow, g.key, len(g.series), age)
+		switch {
+		case g.failed:
+			line = m.theme.negative.Render(line + ", last push failed")
+		case !g.pushed.IsZero() && now.Sub(g.pushed) > m.staleAfter:
+			line = m.theme.stale.Render(line + " (stale)")
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString(fmt.Sprintf("\n%d groups\n\n", len(m.groups)))
+	if m.confirm != nil {
+		sb.WriteString(fmt.Sprintf("Delete group {%s}? (y/n)\n", m.confirm.key))
+	} else {
+		sb.WriteString("Use ↑/↓ to move, Enter to expand/collapse a group, d to delete it, r to refresh.\nPress q or Ctrl+C to quit.\n")
+	}
+	if m.status != "" {
+		sb.WriteString("\n" + m.theme.status.Render(m.status) + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"math"
+	"strconv"
+)
+
+// bucketQuantile estimates the q quantile of the observations counted in a
+// histogram's buckets, interpolating linearly within the bucket it falls in
+// the way Prometheus' histogram_quantile does. incs are per-bucket, not
+// cumulative, counts. It returns NaN if there were no observations.
+func bucketQuantile(q float64, bounds, incs []float64) float64 {
+	var total float64
+	for _, v := range incs {
+		total += v
+	}
+	if total == 0 || len(bounds) == 0 {
+		return math.NaN()
+	}
+	rank := q * total
+	var cum float64
+	for i, v := range incs {
+		if cum+v < rank || v == 0 {
+			cum += v
+			continue
+		}
+		if math.IsInf(bounds[i], 1) {
+			// Nothing is known about the +Inf bucket, so report the
+			// highest finite bound.
+			if i == 0 {
+				return math.NaN()
+			}
+			return bounds[i-1]
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = bounds[i-1]
+		} else if bounds[i] <= 0 {
+			return bounds[i]
+		}
+		return lower + (bounds[i]-lower)*(rank-cum)/v
+	}
+	return bounds[len(bounds)-1]
+}
+
+// quantileTrend estimates the q quantile of the observations made between
+// each pair of consecutive scrapes of a histogram.
+func quantileTrend(md metricData, q float64) []float64 {
+	out := make([]float64, len(md.bucketHistory))
+	for i, incs := range md.bucketHistory {
+		out[i] = bucketQuantile(q, md.bucketBounds, incs)
+	}
+	return out
+}
+
+// quantileName names a quantile as a percentile, such as p99 or p99.9.
+func quantileName(q float64) string {
+	return "p" + strconv.FormatFloat(q*100, 'g', 6, 64)
+}
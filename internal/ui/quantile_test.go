@@ -0,0 +1,44 @@
+package ui
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBucketQuantile(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		name   string
+		q      float64
+		bounds []float64
+		incs   []float64
+		want   float64
+	}{
+		{"median", 0.5, []float64{0.1, 0.5, 1, inf}, []float64{10, 10, 0, 0}, 0.1},
+		{"interpolated", 0.75, []float64{0.1, 0.5, 1, inf}, []float64{10, 10, 0, 0}, 0.3},
+		{"q=0", 0, []float64{0.1, 0.5, 1, inf}, []float64{10, 10, 0, 0}, 0},
+		{"q=0 skips empty buckets", 0, []float64{0.1, 0.5, 1, inf}, []float64{0, 10, 0, 0}, 0.1},
+		{"q=1", 1, []float64{0.1, 0.5, 1, inf}, []float64{10, 10, 0, 0}, 0.5},
+		{"q=1 in the last finite bucket", 1, []float64{0.1, 0.5, 1, inf}, []float64{0, 0, 4, 0}, 1},
+		{"+Inf bucket reports the highest finite bound", 0.9, []float64{0.1, 0.5, 1, inf}, []float64{0, 0, 5, 5}, 1},
+		{"q=1 in the +Inf bucket", 1, []float64{0.1, 0.5, 1, inf}, []float64{5, 0, 0, 1}, 1},
+		{"only a +Inf bucket", 0.5, []float64{inf}, []float64{5}, math.NaN()},
+		{"first bucket at or below zero", 0.5, []float64{-1, 0, inf}, []float64{4, 0, 0}, -1},
+		{"no observations", 0.5, []float64{0.1, 0.5, inf}, []float64{0, 0, 0}, math.NaN()},
+		{"no buckets", 0.5, nil, nil, math.NaN()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bucketQuantile(tt.q, tt.bounds, tt.incs)
+			if math.IsNaN(tt.want) {
+				if !math.IsNaN(got) {
+					t.Errorf("bucketQuantile(%v) = %v, want NaN", tt.q, got)
+				}
+				return
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("bucketQuantile(%v) = %v, want %v", tt.q, got, tt.want)
+			}
+		})
+	}
+}
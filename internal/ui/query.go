@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jaxxstorm/met/internal/scrape"
+	"github.com/jaxxstorm/met/internal/store"
+	"github.com/olekukonko/tablewriter"
+)
+
+type QueryCmd struct {
+	Session uint64 `arg:"" optional:"" help:"Session to inspect; lists sessions when omitted"`
+}
+
+func newPlainTable(w io.Writer, header []string) *tablewriter.Table {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(true)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	return table
+}
+
+// runQuery prints the stored sessions, or a summary of every series in one
+// session, applying the same name and label filters as the TUI.
+func runQuery(w io.Writer, s *store.Store, m model, session uint64) error {
+	if session == 0 {
+		sessions, err := s.Sessions()
+		if err != nil {
+			return err
+		}
+		table := newPlainTable(w, []string{"Session", "Endpoint", "Started", "Scrapes"})
+		for _, si := range sessions {
+			table.Append([]string{
+				strconv.FormatUint(si.ID, 10),
+				si.Endpoint,
+				si.Started.Format(time.DateTime),
+				strconv.Itoa(si.Scrapes),
+			})
+		}
+		table.Render()
+		return nil
+	}
+
+	scrapes, err := s.LoadScrapes(session, 0)
+	if err != nil {
+		return err
+	}
+	type summary struct {
+		samples     int
+		first, last float64
+		min, max    float64
+		lastSeen    time.Time
+	}
+	sums := make(map[string]*summary)
+	for _, sc := range scrapes {
+		for name, mf := range sc.Families() {
+			if !m.passNameFilters(name) {
+				continue
+			}
+			for _, pm := range mf.Metric {
+				if !m.passLabelFilters(pm.Label) || !m.passSelectors(name, pm.Label) {
+					continue
+				}
+				_, lblKey := renderLabels(pm.Label)
+				key := name + "{" + lblKey + "}"
+				v := scrape.RawValue(mf, pm)
+				sm, ok := sums[key]
+				if !ok {
+					sm = &summary{first: v, min: math.Inf(1), max: math.Inf(-1)}
+					sums[key] = sm
+				}
+				sm.samples++
+				sm.last = v
+				sm.lastSeen = sc.At
+				sm.min = math.Min(sm.min, v)
+				sm.max = math.Max(sm.max, v)
+			}
+		}
+	}
+	keys := make([]string, 0, len(sums))
+	for k := range sums {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	notes, err := s.Notes(session)
+	if err != nil {
+		return err
+	}
+	header := []string{"Key", "Samples", "First", "Last", "Min", "Max", "Last Seen"}
+	if len(notes) > 0 {
+		header = append(header, "Note")
+	}
+	table := newPlainTable(w, header)
+	for _, k := range keys {
+		sm := sums[k]
+		row := []string{
+			k,
+			strconv.Itoa(sm.samples),
+			formatRaw(sm.first),
+			formatRaw(sm.last),
+			formatRaw(sm.min),
+			formatRaw(sm.max),
+			sm.lastSeen.Format(time.DateTime),
+		}
+		if len(notes) > 0 {
+			row = append(row, notes[k])
+		}
+		table.Append(row)
+	}
+	table.Render()
+	return nil
+}
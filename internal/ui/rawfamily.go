@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jaxxstorm/met/internal/scrape"
+)
+
+// familySuffixes are the suffixes of the series that belong to a family
+// with a different name, such as a histogram's buckets.
+var familySuffixes = []string{"_bucket", "_sum", "_count", "_created", "_total", "_info"}
+
+// extractFamily picks the lines of the family called name out of a text
+// exposition, as they were written: its HELP and TYPE comments and every one
+// of its series.
+func extractFamily(body []byte, name string) (string, bool) {
+	var sb strings.Builder
+	found := false
+	current := ""
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if rest, ok := strings.CutPrefix(line, "#"); ok {
+			fields := strings.Fields(rest)
+			if len(fields) >= 2 && (fields[0] == "HELP" || fields[0] == "TYPE") {
+				current = fields[1]
+				if current == name {
+					sb.WriteString(line + "\n")
+				}
+			}
+			continue
+		}
+		series := sampleName(line)
+		if series == "" {
+			continue
+		}
+		if series == name || (current == name && belongsTo(series, name)) {
+			sb.WriteString(line + "\n")
+			found = true
+		}
+	}
+	return sb.String(), found
+}
+
+// sampleName returns the name of the series on a sample line.
+func sampleName(line string) string {
+	line = strings.TrimSpace(line)
+	if i := strings.IndexAny(line, "{ \t"); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+func belongsTo(series, family string) bool {
+	suffix, ok := strings.CutPrefix(series, family)
+	if !ok {
+		return false
+	}
+	for _, s := range familySuffixes {
+		if suffix == s {
+			return true
+		}
+	}
+	return false
+}
+
+// rawFamilyCmd scrapes the endpoint again for the family md belongs to,
+// saving its lines exactly as the exporter wrote them, for bug reports.
+func (m model) rawFamilyCmd(md metricData, at time.Time) tea.Cmd {
+	src, ok := m.source.(scrape.HTTP)
+	if !ok || src.Format != "prometheus" || md.synthetic {
+		return func() tea.Msg {
+			return statusMsg("The raw exposition is only available for series from Prometheus endpoints")
+		}
+	}
+	path := filepath.Join(m.snapshotDir, fmt.Sprintf("met-family-%s-%s.txt", unsafeFileChars.ReplaceAllString(md.name, "_"), at.Format("20060102-150405")))
+	return func() tea.Msg {
+		body, err := src.Body()
+		if err != nil {
+			return statusMsg(fmt.Sprintf("Fetching %s failed: %v", src.URL, err))
+		}
+		block, ok := extractFamily(body, md.name)
+		if !ok {
+			return statusMsg(fmt.Sprintf("%s isn't in the exposition, was it renamed by relabeling?", md.name))
+		}
+		return saveCmd(block, path, "Raw "+md.name, m.snapshotClipboard)()
+	}
+}
@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/golang/snappy"
+	"github.com/jaxxstorm/met/internal/scrape"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// remoteWriter forwards every scrape to a Prometheus remote-write endpoint
+// so data seen in met can be queried later from a real TSDB.
+type remoteWriter struct {
+	url      string
+	job      string
+	instance string
+	client   *http.Client
+}
+
+func newRemoteWriter(writeURL, job, endpoint string) *remoteWriter {
+	instance := endpoint
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		instance = u.Host
+	}
+	return &remoteWriter{
+		url:      writeURL,
+		job:      job,
+		instance: instance,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type rwSeries struct {
+	labels [][2]string
+	value  float64
+}
+
+func remoteWriteCmd(w *remoteWriter, families map[string]*dto.MetricFamily, at time.Time) tea.Cmd {
+	return func() tea.Msg {
+		if err := w.write(context.Background(), families, at); err != nil {
+			return statusMsg(fmt.Sprintf("Remote write failed: %v", err))
+		}
+		return nil
+	}
+}
+
+func (w *remoteWriter) write(ctx context.Context, families map[string]*dto.MetricFamily, at time.Time) error {
+	body := snappy.Encode(nil, w.encode(families, at.UnixMilli()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "met/"+Version)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("got status %d from server: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// encode builds a prometheus.WriteRequest protobuf by hand; the message is
+// small enough that pulling in the full prompb package isn't worth it.
+func (w *remoteWriter) encode(families map[string]*dto.MetricFamily, ts int64) []byte {
+	var buf []byte
+	for _, s := range w.series(families) {
+		var tsBuf []byte
+		for _, l := range s.labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+			lb = protowire.AppendString(lb, l[0])
+			lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+			lb = protowire.AppendString(lb, l[1])
+			tsBuf = protowire.AppendTag(tsBuf, 1, protowire.BytesType)
+			tsBuf = protowire.AppendBytes(tsBuf, lb)
+		}
+		var sb []byte
+		sb = protowire.AppendTag(sb, 1, protowire.Fixed64Type)
+		sb = protowire.AppendFixed64(sb, math.Float64bits(s.value))
+		sb = protowire.AppendTag(sb, 2, protowire.VarintType)
+		sb = protowire.AppendVarint(sb, uint64(ts))
+		tsBuf = protowire.AppendTag(tsBuf, 2, protowire.BytesType)
+		tsBuf = protowire.AppendBytes(tsBuf, sb)
+
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, tsBuf)
+	}
+	return buf
+}
+
+// series flattens families into individual samples, adding the job and
+// instance labels.
+func (w *remoteWriter) series(families map[string]*dto.MetricFamily) []rwSeries {
+	var out []rwSeries
+	forEachSeries(families, func(name string, lbls [][2]string, v float64) {
+		all := append([][2]string{{"__name__", name}, {"job", w.job}, {"instance", w.instance}}, lbls...)
+		out = append(out, rwSeries{labels: sortedLabels(all), value: v})
+	})
+	return out
+}
+
+// forEachSeries visits every series the way Prometheus would store them,
+// expanding histograms and summaries into their _bucket/_sum/_count and
+// quantile series.
+func forEachSeries(families map[string]*dto.MetricFamily, fn func(name string, lbls [][2]string, v float64)) {
+	for name, mf := range families {
+		for _, pm := range mf.Metric {
+			add := func(n string, v float64, extra ...string) {
+				lbls := make([][2]string, 0, len(pm.Label)+1)
+				for _, lp := range pm.Label {
+					lbls = append(lbls, [2]string{lp.GetName(), lp.GetValue()})
+				}
+				for i := 0; i+1 < len(extra); i += 2 {
+					lbls = append(lbls, [2]string{extra[i], extra[i+1]})
+				}
+				fn(n, lbls, v)
+			}
+			switch mf.GetType() {
+			case dto.MetricType_HISTOGRAM:
+				h := pm.GetHistogram()
+				hasInf := false
+				for _, b := range h.GetBucket() {
+					hasInf = hasInf || math.IsInf(b.GetUpperBound(), 1)
+					add(name+"_bucket", float64(b.GetCumulativeCount()), "le", formatFloat(b.GetUpperBound()))
+				}
+				if !hasInf {
+					add(name+"_bucket", float64(h.GetSampleCount()), "le", "+Inf")
+				}
+				add(name+"_sum", h.GetSampleSum())
+				add(name+"_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				sm := pm.GetSummary()
+				for _, q := range sm.GetQuantile() {
+					add(name, q.GetValue(), "quantile", formatFloat(q.GetQuantile()))
+				}
+				add(name+"_sum", sm.GetSampleSum())
+				add(name+"_count", float64(sm.GetSampleCount()))
+			default:
+				add(name, scrape.RawValue(mf, pm))
+			}
+		}
+	}
+}
+
+// sortedLabels sorts by name and drops duplicates, letting labels exposed
+// by the target override the job and instance labels met adds.
+func sortedLabels(lbls [][2]string) [][2]string {
+	sort.SliceStable(lbls, func(i, j int) bool { return lbls[i][0] < lbls[j][0] })
+	out := lbls[:0]
+	for _, l := range lbls {
+		if len(out) > 0 && out[len(out)-1][0] == l[0] {
+			out[len(out)-1] = l
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,188 @@
+package ui
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/jaxxstorm/met/pkg/met"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// decodedSeries is a time series read back from a WriteRequest, with its
+// labels rendered as name="value" pairs in the order they were written.
+type decodedSeries struct {
+	labels []string
+	value  float64
+	ts     int64
+}
+
+// decodeWriteRequest reads back a WriteRequest written by encode.
+func decodeWriteRequest(t *testing.T, b []byte) []decodedSeries {
+	t.Helper()
+	// fields calls fn with each field of the message in b.
+	fields := func(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, x uint64)) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			if n < 0 {
+				t.Fatalf("bad tag: %v", protowire.ParseError(n))
+			}
+			b = b[n:]
+			switch typ {
+			case protowire.BytesType:
+				v, n := protowire.ConsumeBytes(b)
+				if n < 0 {
+					t.Fatalf("bad field %d: %v", num, protowire.ParseError(n))
+				}
+				fn(num, typ, v, 0)
+				b = b[n:]
+			case protowire.Fixed64Type:
+				x, n := protowire.ConsumeFixed64(b)
+				if n < 0 {
+					t.Fatalf("bad field %d: %v", num, protowire.ParseError(n))
+				}
+				fn(num, typ, nil, x)
+				b = b[n:]
+			case protowire.VarintType:
+				x, n := protowire.ConsumeVarint(b)
+				if n < 0 {
+					t.Fatalf("bad field %d: %v", num, protowire.ParseError(n))
+				}
+				fn(num, typ, nil, x)
+				b = b[n:]
+			default:
+				t.Fatalf("unexpected wire type %v for field %d", typ, num)
+			}
+		}
+	}
+	var out []decodedSeries
+	fields(b, func(_ protowire.Number, _ protowire.Type, ts []byte, _ uint64) {
+		var s decodedSeries
+		fields(ts, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) {
+			switch num {
+			case 1:
+				var name, value string
+				fields(v, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) {
+					if num == 1 {
+						name = string(v)
+					} else {
+						value = string(v)
+					}
+				})
+				s.labels = append(s.labels, name+"="+value)
+			case 2:
+				fields(v, func(num protowire.Number, _ protowire.Type, _ []byte, x uint64) {
+					if num == 1 {
+						s.value = math.Float64frombits(x)
+					} else {
+						s.ts = int64(x)
+					}
+				})
+			}
+		})
+		out = append(out, s)
+	})
+	return out
+}
+
+func TestRemoteWriteEncode(t *testing.T) {
+	fams := map[string]*dto.MetricFamily{
+		"up": {
+			Name: proto.String("up"),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{
+				// Labels exposed by the target win over the ones met adds.
+				Label: []*dto.LabelPair{met.LabelPair("instance", "pod-1")},
+				Gauge: &dto.Gauge{Value: proto.Float64(1)},
+			}},
+		},
+		"latency": {
+			Name: proto.String("latency"),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{{Histogram: &dto.Histogram{
+				SampleCount: proto.Uint64(3),
+				SampleSum:   proto.Float64(0.5),
+				Bucket:      []*dto.Bucket{{UpperBound: proto.Float64(0.1), CumulativeCount: proto.Uint64(2)}},
+			}}},
+		},
+	}
+	w := newRemoteWriter("http://tsdb/api/v1/write", "node", "http://localhost:9100/metrics")
+	got := decodeWriteRequest(t, w.encode(fams, 1700000000000))
+
+	want := map[string]float64{
+		"__name__=up instance=pod-1 job=node":                              1,
+		"__name__=latency_bucket instance=localhost:9100 job=node le=0.1":  2,
+		"__name__=latency_bucket instance=localhost:9100 job=node le=+Inf": 3,
+		"__name__=latency_sum instance=localhost:9100 job=node":            0.5,
+		"__name__=latency_count instance=localhost:9100 job=node":          3,
+	}
+	if len(got) != len(want) {
+		t.Errorf("encoded %d series, want %d: %v", len(got), len(want), got)
+	}
+	for _, s := range got {
+		if !slices.IsSorted(s.labels) {
+			t.Errorf("labels %v aren't sorted", s.labels)
+		}
+		key := strings.Join(s.labels, " ")
+		v, ok := want[key]
+		if !ok {
+			t.Errorf("unexpected series %s", key)
+			continue
+		}
+		if s.value != v || s.ts != 1700000000000 {
+			t.Errorf("%s = %v at %d, want %v at 1700000000000", key, s.value, s.ts, v)
+		}
+	}
+}
+
+func TestRemoteWriteWrite(t *testing.T) {
+	var body []byte
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if body, err = snappy.Decode(nil, b); err != nil {
+			t.Errorf("body isn't snappy encoded: %v", err)
+		}
+		if r.URL.Path == "/full" {
+			http.Error(w, "out of disk", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	fams := counterFamilies(map[string]float64{"200": 6})
+	at := time.UnixMilli(1700000000000)
+	w := newRemoteWriter(srv.URL+"/write", "api", "http://localhost:8080/metrics")
+	if err := w.write(context.Background(), fams, at); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	for k, v := range map[string]string{
+		"Content-Encoding":                  "snappy",
+		"Content-Type":                      "application/x-protobuf",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	} {
+		if got := header.Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	if got := decodeWriteRequest(t, body); len(got) != 1 || got[0].value != 6 {
+		t.Errorf("wrote %v, want requests_total at 6", got)
+	}
+
+	w = newRemoteWriter(srv.URL+"/full", "api", "http://localhost:8080/metrics")
+	if err := w.write(context.Background(), fams, at); err == nil || !strings.Contains(err.Error(), "out of disk") {
+		t.Errorf("write = %v, want the server's error", err)
+	}
+}
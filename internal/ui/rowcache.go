@@ -0,0 +1,50 @@
+package ui
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// rowCache keeps the formatted cells of rows that have been drawn, so
+// redrawing after the selection moves doesn't format them again. Only the
+// visible page is ever formatted, so the cache stays small however many
+// series there are. It's shared by copies of the model, and reset by
+// anything that could change what the rows show.
+type rowCache struct {
+	cells map[string][]string
+}
+
+func newRowCache() *rowCache {
+	return &rowCache{cells: make(map[string][]string)}
+}
+
+func (c *rowCache) get(key string) ([]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	cells, ok := c.cells[key]
+	return cells, ok
+}
+
+func (c *rowCache) put(key string, cells []string) {
+	if c != nil {
+		c.cells[key] = cells
+	}
+}
+
+func (c *rowCache) reset() {
+	if c != nil && len(c.cells) > 0 {
+		c.cells = make(map[string][]string)
+	}
+}
+
+// isNavigation reports whether msg only moves the selection, leaving the
+// rows' contents alone.
+func (m model) isNavigation(msg tea.Msg) bool {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok || m.capturingInput() {
+		return false
+	}
+	switch key.String() {
+	case "up", "k", "down", "j", "pgup", "pgdown":
+		return true
+	}
+	return false
+}
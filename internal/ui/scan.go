@@ -0,0 +1,190 @@
+package ui
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaxxstorm/met/internal/scrape"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type ScanCmd struct {
+	Ports   string        `help:"Ports to probe, as a comma-separated list of ports and ranges, e.g. 8080,9000-9999" default:"9000-9999"`
+	Host    string        `help:"Host to probe" default:"localhost"`
+	Path    string        `help:"Path metrics are served on" default:"/metrics"`
+	Timeout time.Duration `help:"How long to wait for each port to respond" default:"1s"`
+	All     bool          `help:"Scrape every endpoint found instead of picking from a list"`
+}
+
+// scanWorkers is how many ports are probed at once.
+const scanWorkers = 64
+
+// scanResult is an endpoint found by met scan.
+type scanResult struct {
+	port     int
+	endpoint string
+	series   int
+	// exporter guesses what's serving the endpoint from its metric names.
+	exporter string
+}
+
+// parsePorts parses a comma-separated list of ports and ranges of ports,
+// such as 8080,9000-9100.
+func parsePorts(spec string) ([]int, error) {
+	var ports []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("bad port %q", lo)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil {
+				return nil, fmt.Errorf("bad port %q", hi)
+			}
+		}
+		if first < 1 || last > 65535 || first > last {
+			return nil, fmt.Errorf("bad port range %q", part)
+		}
+		for p := first; p <= last; p++ {
+			if !seen[p] {
+				seen[p] = true
+				ports = append(ports, p)
+			}
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no ports in %q", spec)
+	}
+	return ports, nil
+}
+
+// scanPorts probes every port on host for a metrics endpoint at path,
+// returning those that serve something parseable, ordered by port.
+func scanPorts(client *http.Client, cmd ScanCmd, maxBodySize int64) ([]scanResult, error) {
+	ports, err := parsePorts(cmd.Ports)
+	if err != nil {
+		return nil, err
+	}
+	probe := *client
+	probe.Timeout = cmd.Timeout
+
+	var (
+		mu      sync.Mutex
+		results []scanResult
+		wg      sync.WaitGroup
+	)
+	work := make(chan int)
+	for range min(scanWorkers, len(ports)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for port := range work {
+				host := net.JoinHostPort(cmd.Host, strconv.Itoa(port))
+				// Most ports are closed, and refuse a connection far
+				// quicker than an HTTP request times out.
+				conn, err := net.DialTimeout("tcp", host, cmd.Timeout)
+				if err != nil {
+					continue
+				}
+				conn.Close()
+				ep := (&url.URL{Scheme: "http", Host: host, Path: cmd.Path}).String()
+				fams, err := scrape.HTTP{URL: ep, Client: &probe, MaxBodySize: maxBodySize}.Scrape()
+				if err != nil || len(fams) == 0 {
+					continue
+				}
+				var series int
+				forEachSeries(fams, func(string, [][2]string, float64) { series++ })
+				mu.Lock()
+				results = append(results, scanResult{port: port, endpoint: ep, series: series, exporter: guessExporter(fams)})
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, p := range ports {
+		work <- p
+	}
+	close(work)
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].port < results[j].port })
+	return results, nil
+}
+
+// commonPrefixes are metric name prefixes that most exporters share, and so
+// say nothing about which exporter it is.
+var commonPrefixes = map[string]bool{"go": true, "process": true, "promhttp": true, "python": true, "jvm": true}
+
+// guessExporter names an exporter by the most common prefix of its metric
+// names, e.g. node for the node exporter, ignoring runtime metrics. It
+// returns "" when no prefix is shared by more than one metric.
+func guessExporter(fams map[string]*dto.MetricFamily) string {
+	counts := make(map[string]int)
+	for name := range fams {
+		prefix, _, _ := strings.Cut(name, "_")
+		if !commonPrefixes[prefix] {
+			counts[prefix]++
+		}
+	}
+	var best string
+	for prefix, n := range counts {
+		if n > counts[best] || n == counts[best] && prefix < best {
+			best = prefix
+		}
+	}
+	if counts[best] < 2 {
+		return ""
+	}
+	return best
+}
+
+// selectScanned scans for endpoints and decides which to scrape: every one
+// found with --all, otherwise those picked from a list.
+func selectScanned(client *http.Client, cmd ScanCmd, maxBodySize int64) ([]string, error) {
+	results, err := scanPorts(client, cmd, maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no metrics endpoints found on %s ports %s", cmd.Host, cmd.Ports)
+	}
+	if cmd.All {
+		endpoints := make([]string, len(results))
+		for i, r := range results {
+			endpoints[i] = r.endpoint
+		}
+		return endpoints, nil
+	}
+	items := make([]string, len(results))
+	for i, r := range results {
+		exporter := r.exporter
+		if exporter == "" {
+			exporter = "unknown"
+		}
+		items[i] = fmt.Sprintf("%-5d %-12s %6d series  %s", r.port, exporter, r.series, r.endpoint)
+	}
+	picked, err := pickItems(fmt.Sprintf("Metrics endpoints on %s", cmd.Host), items)
+	if err != nil {
+		return nil, err
+	}
+	if len(picked) == 0 {
+		return nil, fmt.Errorf("no endpoints picked")
+	}
+	endpoints := make([]string, len(picked))
+	for i, idx := range picked {
+		endpoints[i] = results[idx].endpoint
+	}
+	return endpoints, nil
+}
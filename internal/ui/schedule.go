@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// scrapeOffset is how far into each interval endpoint is scraped when tabs
+// spread their scrapes out. It's hashed from the endpoint, as Prometheus
+// does, so every endpoint keeps its own slot rather than all of them being
+// scraped at once.
+func scrapeOffset(endpoint string, interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(endpoint))
+	return time.Duration(h.Sum64() % uint64(interval))
+}
+
+// scrapeJitter is a random delay of up to a twentieth of interval, added to
+// each spread scrape so endpoints whose hashes land close together don't
+// stay in step.
+func scrapeJitter(interval time.Duration) time.Duration {
+	if interval < 20 {
+		return 0
+	}
+	return rand.N(interval / 20)
+}
+
+// firstTick waits for the endpoint's first slot when spreading scrapes, so
+// tabs opened together don't all take their first scrape at once.
+func (m model) firstTick() tea.Cmd {
+	now := time.Now()
+	next := now.Truncate(m.interval).Add(scrapeOffset(m.endpoint, m.interval))
+	if next.Before(now) {
+		next = next.Add(m.interval)
+	}
+	return tickCmd(next.Sub(now) + scrapeJitter(m.interval))
+}
+
+// nextTick waits for the next scrape: an interval after the last, or when
+// spreading scrapes, until the endpoint's slot comes round. The slot taken
+// is at least half an interval away, so a scrape that ran late isn't
+// followed by another straight after.
+func (m model) nextTick() tea.Cmd {
+	if !m.spread || m.interval <= 0 {
+		return tickCmd(m.interval)
+	}
+	now := time.Now()
+	next := now.Truncate(m.interval).Add(scrapeOffset(m.endpoint, m.interval))
+	for next.Sub(now) < m.interval/2 {
+		next = next.Add(m.interval)
+	}
+	return tickCmd(next.Sub(now) + scrapeJitter(m.interval))
+}
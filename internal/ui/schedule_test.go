@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScrapeOffset(t *testing.T) {
+	interval := 15 * time.Second
+	a := scrapeOffset("http://a:9100/metrics", interval)
+	if a < 0 || a >= interval {
+		t.Errorf("scrapeOffset = %v, want within [0, %v)", a, interval)
+	}
+	if again := scrapeOffset("http://a:9100/metrics", interval); again != a {
+		t.Errorf("scrapeOffset = %v then %v, want the same slot each time", a, again)
+	}
+	if b := scrapeOffset("http://b:9100/metrics", interval); b == a {
+		t.Errorf("scrapeOffset gave two endpoints the same slot %v", a)
+	}
+	if got := scrapeOffset("http://a:9100/metrics", 0); got != 0 {
+		t.Errorf("scrapeOffset with no interval = %v, want 0", got)
+	}
+}
+
+func TestScrapeJitter(t *testing.T) {
+	interval := 10 * time.Second
+	for range 100 {
+		if j := scrapeJitter(interval); j < 0 || j >= interval/20 {
+			t.Fatalf("scrapeJitter = %v, want within [0, %v)", j, interval/20)
+		}
+	}
+	if got := scrapeJitter(0); got != 0 {
+		t.Errorf("scrapeJitter with no interval = %v, want 0", got)
+	}
+}
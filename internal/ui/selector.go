@@ -0,0 +1,232 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+type matchType int
+
+const (
+	matchEqual matchType = iota
+	matchNotEqual
+	matchRegexp
+	matchNotRegexp
+)
+
+func (t matchType) String() string {
+	switch t {
+	case matchNotEqual:
+		return "!="
+	case matchRegexp:
+		return "=~"
+	case matchNotRegexp:
+		return "!~"
+	}
+	return "="
+}
+
+type labelMatcher struct {
+	name  string
+	typ   matchType
+	value string
+	re    *regexp.Regexp
+}
+
+func (lm labelMatcher) matches(v string) bool {
+	switch lm.typ {
+	case matchNotEqual:
+		return v != lm.value
+	case matchRegexp:
+		return lm.re.MatchString(v)
+	case matchNotRegexp:
+		return !lm.re.MatchString(v)
+	}
+	return v == lm.value
+}
+
+// selector is a PromQL-style series selector such as
+// http_requests_total{code=~"5..",handler!="/healthz"}. The metric name is
+// treated as an equality matcher on __name__.
+type selector struct {
+	matchers []labelMatcher
+	// source is the text the selector was parsed from.
+	source string
+}
+
+// matches reports whether a series satisfies every matcher. As in Prometheus,
+// a missing label is treated as having the empty value.
+func (s selector) matches(name string, lbls []*dto.LabelPair) bool {
+	for _, lm := range s.matchers {
+		v := name
+		if lm.name != "__name__" {
+			v = ""
+			for _, lp := range lbls {
+				if lp.GetName() == lm.name {
+					v = lp.GetValue()
+					break
+				}
+			}
+		}
+		if !lm.matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseSelector(input string) (selector, error) {
+	p := &selectorParser{input: strings.TrimSpace(input)}
+	sel, err := p.selector()
+	if err != nil {
+		return sel, err
+	}
+	sel.source = p.input
+	p.skipSpace()
+	if !p.done() {
+		return sel, p.errorf("unexpected trailing input")
+	}
+	return sel, nil
+}
+
+type selectorParser struct {
+	input string
+	pos   int
+}
+
+func (p *selectorParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *selectorParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *selectorParser) skipSpace() {
+	for !p.done() && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *selectorParser) consume(tok string) bool {
+	if strings.HasPrefix(p.input[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func isIdentChar(c byte, first bool) bool {
+	if c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+		return true
+	}
+	return !first && c >= '0' && c <= '9'
+}
+
+func (p *selectorParser) ident() string {
+	start := p.pos
+	for !p.done() && isIdentChar(p.input[p.pos], p.pos == start) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+// selector parses a selector starting at the current position, stopping
+// after the closing brace (or the metric name if there are no matchers).
+func (p *selectorParser) selector() (selector, error) {
+	var sel selector
+	if name := p.ident(); name != "" {
+		sel.matchers = append(sel.matchers, labelMatcher{name: "__name__", typ: matchEqual, value: name})
+	}
+	p.skipSpace()
+	if !p.consume("{") {
+		if len(sel.matchers) == 0 {
+			return sel, p.errorf("expected metric name or '{'")
+		}
+		return sel, nil
+	}
+	for {
+		p.skipSpace()
+		if p.consume("}") {
+			break
+		}
+		lm, err := p.matcher()
+		if err != nil {
+			return sel, err
+		}
+		sel.matchers = append(sel.matchers, lm)
+		p.skipSpace()
+		if p.consume(",") {
+			continue
+		}
+		if !p.consume("}") {
+			return sel, p.errorf("expected ',' or '}'")
+		}
+		break
+	}
+	if len(sel.matchers) == 0 {
+		return sel, fmt.Errorf("selector must contain a metric name or at least one matcher")
+	}
+	return sel, nil
+}
+
+func (p *selectorParser) matcher() (labelMatcher, error) {
+	var lm labelMatcher
+	lm.name = p.ident()
+	if lm.name == "" {
+		return lm, p.errorf("expected label name")
+	}
+	p.skipSpace()
+	switch {
+	case p.consume("=~"):
+		lm.typ = matchRegexp
+	case p.consume("!~"):
+		lm.typ = matchNotRegexp
+	case p.consume("!="):
+		lm.typ = matchNotEqual
+	case p.consume("="):
+		lm.typ = matchEqual
+	default:
+		return lm, p.errorf("expected one of =, !=, =~, !~")
+	}
+	p.skipSpace()
+	val, err := p.quoted()
+	if err != nil {
+		return lm, err
+	}
+	lm.value = val
+	if lm.typ == matchRegexp || lm.typ == matchNotRegexp {
+		// Prometheus regex matchers are fully anchored.
+		re, err := regexp.Compile("^(?:" + val + ")$")
+		if err != nil {
+			return lm, fmt.Errorf("bad regex for label %q: %w", lm.name, err)
+		}
+		lm.re = re
+	}
+	return lm, nil
+}
+
+func (p *selectorParser) quoted() (string, error) {
+	if p.done() || (p.input[p.pos] != '"' && p.input[p.pos] != '`') {
+		return "", p.errorf("expected quoted label value")
+	}
+	quote := p.input[p.pos]
+	start := p.pos
+	p.pos++
+	for !p.done() {
+		c := p.input[p.pos]
+		if c == '\\' && quote == '"' {
+			p.pos += 2
+			continue
+		}
+		p.pos++
+		if c == quote {
+			return strconv.Unquote(p.input[start:p.pos])
+		}
+	}
+	return "", p.errorf("unterminated label value")
+}
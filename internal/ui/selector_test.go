@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/jaxxstorm/met/pkg/met"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestParseSelector(t *testing.T) {
+	lbls := []*dto.LabelPair{
+		met.LabelPair("code", "503"),
+		met.LabelPair("handler", "/api"),
+	}
+	tests := []struct {
+		input string
+		name  string
+		want  bool
+	}{
+		{`http_requests_total`, "http_requests_total", true},
+		{`http_requests_total`, "http_errors_total", false},
+		{`{code="503"}`, "http_requests_total", true},
+		{`http_requests_total{code="200"}`, "http_requests_total", false},
+		{`http_requests_total{code!="200"}`, "http_requests_total", true},
+		{`http_requests_total{code!="503"}`, "http_requests_total", false},
+		{`http_requests_total{code=~"5.."}`, "http_requests_total", true},
+		// Regex matchers are anchored, as in Prometheus.
+		{`http_requests_total{code=~"5"}`, "http_requests_total", false},
+		{`http_requests_total{code!~"5.."}`, "http_requests_total", false},
+		{`http_requests_total{code!~"2.."}`, "http_requests_total", true},
+		{`http_requests_total{code=~"5..", handler!="/healthz"}`, "http_requests_total", true},
+		{`http_requests_total{code=~"5..",handler="/healthz"}`, "http_requests_total", false},
+		{"http_requests_total{handler=`/api`}", "http_requests_total", true},
+		// A missing label has the empty value.
+		{`http_requests_total{method=""}`, "http_requests_total", true},
+		{`http_requests_total{method!=""}`, "http_requests_total", false},
+		{`{__name__=~"http_.*"}`, "http_requests_total", true},
+		{`  http_requests_total { code = "503" }  `, "http_requests_total", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			sel, err := parseSelector(tt.input)
+			if err != nil {
+				t.Fatalf("parseSelector(%q) failed: %v", tt.input, err)
+			}
+			if got := sel.matches(tt.name, lbls); got != tt.want {
+				t.Errorf("parseSelector(%q).matches(%q) = %v, want %v", tt.input, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`{}`,
+		`http_requests_total{code}`,
+		`http_requests_total{code=503}`,
+		`http_requests_total{code=="503"}`,
+		`http_requests_total{code="503"`,
+		`http_requests_total{code="503}`,
+		`http_requests_total{code=~"("}`,
+		`http_requests_total extra`,
+	}
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			if _, err := parseSelector(input); err == nil {
+				t.Errorf("parseSelector(%q) succeeded, want an error", input)
+			}
+		})
+	}
+}
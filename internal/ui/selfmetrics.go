@@ -0,0 +1,180 @@
+package ui
+
+import (
+	"net"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaxxstorm/met/internal/scrape"
+	"github.com/jaxxstorm/met/pkg/met"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+)
+
+// selfMetrics collects met's own operational metrics, served with
+// --self-metrics so a long-running session can itself be monitored.
+type selfMetrics struct {
+	mu        sync.Mutex
+	start     time.Time
+	endpoints map[string]*endpointStats
+}
+
+// endpointStats tracks the scrapes of a single endpoint.
+type endpointStats struct {
+	scrapes     float64
+	failures    float64
+	parseErrors float64
+	durationSum float64
+	bytes       int64
+	series      int
+}
+
+// self is nil unless --self-metrics is set, in which case models report
+// their scrapes to it.
+var self *selfMetrics
+
+func (s *selfMetrics) endpoint(name string) *endpointStats {
+	if s.endpoints == nil {
+		s.endpoints = make(map[string]*endpointStats)
+	}
+	es, ok := s.endpoints[name]
+	if !ok {
+		es = &endpointStats{bytes: -1}
+		s.endpoints[name] = es
+	}
+	return es
+}
+
+// observeScrape records a scrape of endpoint. It's a no-op on a nil
+// selfMetrics.
+func (s *selfMetrics) observeScrape(endpoint string, st scrape.Stats) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	es := s.endpoint(endpoint)
+	es.scrapes++
+	es.durationSum += st.Duration.Seconds()
+	if st.Err != nil {
+		es.failures++
+	}
+	if st.ParseFailed {
+		es.parseErrors++
+	}
+	if st.Bytes >= 0 {
+		es.bytes = st.Bytes
+	}
+}
+
+// observeSeries records how many series met is tracking for endpoint.
+func (s *selfMetrics) observeSeries(endpoint string, n int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endpoint(endpoint).series = n
+}
+
+// families returns the current metrics, sorted by name.
+func (s *selfMetrics) families() []*dto.MetricFamily {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.endpoints))
+	for name := range s.endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	perEndpoint := func(name, help string, typ dto.MetricType, value func(*endpointStats) *dto.Metric) *dto.MetricFamily {
+		mf := &dto.MetricFamily{Name: proto.String(name), Help: proto.String(help), Type: typ.Enum()}
+		for _, ep := range names {
+			m := value(s.endpoints[ep])
+			if m == nil {
+				continue
+			}
+			m.Label = []*dto.LabelPair{met.LabelPair("endpoint", ep)}
+			mf.Metric = append(mf.Metric, m)
+		}
+		return mf
+	}
+	counter := func(v float64) *dto.Metric { return &dto.Metric{Counter: &dto.Counter{Value: proto.Float64(v)}} }
+	gauge := func(v float64) *dto.Metric { return &dto.Metric{Gauge: &dto.Gauge{Value: proto.Float64(v)}} }
+	single := func(name, help string, typ dto.MetricType, m *dto.Metric) *dto.MetricFamily {
+		return &dto.MetricFamily{Name: proto.String(name), Help: proto.String(help), Type: typ.Enum(), Metric: []*dto.Metric{m}}
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	build := gauge(1)
+	build.Label = []*dto.LabelPair{met.LabelPair("version", Version), met.LabelPair("goversion", runtime.Version())}
+
+	fams := []*dto.MetricFamily{
+		single("met_build_info", "A metric with a constant '1' value labeled by the version met was built from.", dto.MetricType_GAUGE, build),
+		perEndpoint("met_scrapes_total", "Scrapes of the endpoint.", dto.MetricType_COUNTER, func(es *endpointStats) *dto.Metric {
+			return counter(es.scrapes)
+		}),
+		perEndpoint("met_scrape_failures_total", "Scrapes of the endpoint that failed, including those that couldn't be parsed.", dto.MetricType_COUNTER, func(es *endpointStats) *dto.Metric {
+			return counter(es.failures)
+		}),
+		perEndpoint("met_scrape_parse_errors_total", "Scrapes of the endpoint whose response couldn't be parsed.", dto.MetricType_COUNTER, func(es *endpointStats) *dto.Metric {
+			return counter(es.parseErrors)
+		}),
+		perEndpoint("met_scrape_duration_seconds", "How long scrapes of the endpoint took.", dto.MetricType_SUMMARY, func(es *endpointStats) *dto.Metric {
+			return &dto.Metric{Summary: &dto.Summary{SampleCount: proto.Uint64(uint64(es.scrapes)), SampleSum: proto.Float64(es.durationSum)}}
+		}),
+		perEndpoint("met_scrape_response_bytes", "Size of the endpoint's last response body, as sent over the wire.", dto.MetricType_GAUGE, func(es *endpointStats) *dto.Metric {
+			if es.bytes < 0 {
+				return nil
+			}
+			return gauge(float64(es.bytes))
+		}),
+		perEndpoint("met_series", "Series met is tracking for the endpoint, after filtering.", dto.MetricType_GAUGE, func(es *endpointStats) *dto.Metric {
+			return gauge(float64(es.series))
+		}),
+		single("go_goroutines", "Number of goroutines that currently exist.", dto.MetricType_GAUGE, gauge(float64(runtime.NumGoroutine()))),
+		single("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", dto.MetricType_GAUGE, gauge(float64(ms.Alloc))),
+		single("go_memstats_sys_bytes", "Number of bytes obtained from system.", dto.MetricType_GAUGE, gauge(float64(ms.Sys))),
+		single("go_memstats_heap_objects", "Number of allocated objects.", dto.MetricType_GAUGE, gauge(float64(ms.HeapObjects))),
+		single("go_gc_cycles_total", "Number of completed GC cycles.", dto.MetricType_COUNTER, counter(float64(ms.NumGC))),
+		single("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", dto.MetricType_GAUGE, gauge(float64(s.start.UnixNano())/1e9)),
+	}
+	return fams
+}
+
+func (s *selfMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	for _, mf := range s.families() {
+		if _, err := expfmt.MetricFamilyToText(w, mf); err != nil {
+			return
+		}
+	}
+}
+
+// listenSelfMetrics serves met's own metrics on addr, given as host:port
+// followed by an optional path, e.g. :9099/metrics.
+func listenSelfMetrics(addr string) (*selfMetrics, error) {
+	path := "/metrics"
+	if i := strings.Index(addr, "/"); i >= 0 {
+		addr, path = addr[:i], addr[i:]
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &selfMetrics{start: time.Now()}
+	mux := http.NewServeMux()
+	mux.Handle(path, s)
+	go func() {
+		if err := http.Serve(ln, mux); err != nil {
+			logger.Error("Self-metrics server stopped", "err", err)
+		}
+	}()
+	return s, nil
+}
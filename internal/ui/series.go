@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+
+	"github.com/jaxxstorm/met/internal/scrape"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// scrapedSeries is a single series from a scrape, with its key rendered and
+// value extracted. Scrapes of large endpoints have a lot of series, so this
+// is done by the scrape command rather than in Update, leaving Update with
+// map lookups and arithmetic.
+type scrapedSeries struct {
+	key    string
+	labels string
+	mf     *dto.MetricFamily
+	pm     *dto.Metric
+	raw    float64
+	// sum hashes everything observed from the series, so Update can tell
+	// it hasn't changed since the last scrape without looking further.
+	sum uint64
+}
+
+func prepareSeries(families map[string]*dto.MetricFamily) []scrapedSeries {
+	n := 0
+	for _, mf := range families {
+		n += len(mf.Metric)
+	}
+	out := make([]scrapedSeries, 0, n)
+	for name, mf := range families {
+		for _, pm := range mf.Metric {
+			lblStr, lblKey := renderLabels(pm.Label)
+			out = append(out, scrapedSeries{
+				key:    name + "{" + lblKey + "}",
+				labels: lblStr,
+				mf:     mf,
+				pm:     pm,
+				raw:    scrape.RawValue(mf, pm),
+				sum:    seriesSum(mf, pm),
+			})
+		}
+	}
+	return out
+}
+
+// seriesSum hashes the type, value and timestamp of pm, and for histograms
+// and summaries, their buckets or quantiles too.
+func seriesSum(mf *dto.MetricFamily, pm *dto.Metric) uint64 {
+	h := fnv.New64a()
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(mf.GetType()))
+	h.Write(b[:])
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(scrape.RawValue(mf, pm)))
+	h.Write(b[:])
+	binary.LittleEndian.PutUint64(b[:], uint64(pm.GetTimestampMs()))
+	h.Write(b[:])
+	var m proto.Message
+	switch {
+	case pm.Histogram != nil:
+		m = pm.Histogram
+	case pm.Summary != nil:
+		m = pm.Summary
+	}
+	if m != nil {
+		// Deterministic, so the same histogram always hashes the same.
+		bs, _ := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+		h.Write(bs)
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestApplyScrapeUnchanged checks that skipping series that haven't changed
+// leaves the model just as observing them again would.
+func TestApplyScrapeUnchanged(t *testing.T) {
+	scrape := func(count, gauge float64, observations uint64) map[string]*dto.MetricFamily {
+		fams := counterFamilies(map[string]float64{"200": count})
+		fams["queue"] = &dto.MetricFamily{
+			Name:   proto.String("queue"),
+			Type:   dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: proto.Float64(gauge)}}},
+		}
+		fams["latency"] = &dto.MetricFamily{
+			Name: proto.String("latency"),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{{Histogram: &dto.Histogram{
+				SampleCount: proto.Uint64(observations),
+				SampleSum:   proto.Float64(float64(observations)),
+				Bucket: []*dto.Bucket{
+					{UpperBound: proto.Float64(1), CumulativeCount: proto.Uint64(observations)},
+				},
+			}}},
+		}
+		return fams
+	}
+	scrapes := []map[string]*dto.MetricFamily{
+		scrape(10, 1, 1),
+		scrape(15, 1, 2),
+		scrape(15, 1, 2),
+		scrape(15, 3, 2),
+		scrape(4, 3, 5),
+		scrape(4, 3, 5),
+	}
+	start := time.Unix(1000, 0)
+	skipped := model{metricsIndex: map[string]int{}}
+	observed := model{metricsIndex: map[string]int{}}
+	for i, fams := range scrapes {
+		at := start.Add(time.Duration(i) * 5 * time.Second)
+		skipped = updateMetrics(skipped, fams, at)
+		// Forgetting the hashes makes every series be observed again.
+		for j := range observed.metricsList {
+			observed.metricsList[j].sum = 0
+		}
+		observed = updateMetrics(observed, fams, at)
+		for j := range observed.metricsList {
+			want := observed.metricsList[j]
+			got := skipped.metricsList[skipped.metricsIndex[want.key]]
+			got.sum, want.sum = 0, 0
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("scrape %d: %s = %+v, want %+v", i, want.key, got, want)
+			}
+		}
+	}
+}
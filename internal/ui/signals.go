@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jaxxstorm/met/internal/scrape"
+)
+
+// settings are what's read from the config file, resolved against the
+// command line.
+type settings struct {
+	cfg     config
+	relabel []scrape.RelabelRule
+	theme   theme
+}
+
+func loadSettings(cli CLI) (settings, error) {
+	var s settings
+	var err error
+	if s.cfg, err = loadConfig(cli.Config); err != nil {
+		return s, err
+	}
+	if s.relabel, err = scrape.CompileRelabelConfigs(s.cfg.RelabelConfigs); err != nil {
+		return s, err
+	}
+	s.theme = noColorTheme()
+	if !cli.NoColor && os.Getenv("NO_COLOR") == "" {
+		name := cli.Theme
+		if name == "" {
+			name = s.cfg.Theme
+		}
+		if name == "" {
+			name = "dark"
+		}
+		if s.theme, err = resolveTheme(name, s.cfg); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// reloadMsg carries the settings read again from the config file on
+// SIGHUP.
+type reloadMsg struct {
+	settings settings
+	err      error
+}
+
+// watchReload reloads the config file each time the process gets SIGHUP,
+// sending the result to p, until stop is called.
+func watchReload(p *tea.Program, cli CLI) (stop func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			s, err := loadSettings(cli)
+			p.Send(reloadMsg{settings: s, err: err})
+		}
+	}()
+	return func() {
+		signal.Stop(hup)
+		close(hup)
+	}
+}
+
+// reload applies settings read again from the config file, keeping the
+// series and their history. Series whose keys change with the relabeling
+// rules start afresh under their new keys.
+func (m model) reload(msg reloadMsg) model {
+	if msg.err != nil {
+		m.status = fmt.Sprintf("Reloading %s failed: %v", m.configPath, msg.err)
+		return m
+	}
+	m.presets = msg.settings.cfg.Presets
+	m.bookmarks = msg.settings.cfg.Bookmarks
+	m.relabel = msg.settings.relabel
+	m.theme = msg.settings.theme
+	m.rejected = nil
+	m.status = "Reloaded the config file"
+	return m
+}
+
+// exitModels lists the models shown by the program that just exited, to
+// save their history.
+func exitModels(tm tea.Model) []model {
+	switch tm := tm.(type) {
+	case asciiModel:
+		return exitModels(tm.Model)
+	case loggedModel:
+		return exitModels(tm.Model)
+	case webModel:
+		return exitModels(tm.Model)
+	case model:
+		return []model{tm}
+	case tabs:
+		ms := make([]model, len(tm.tabs))
+		for i, tb := range tm.tabs {
+			ms[i] = tb.m
+		}
+		return ms
+	case targetsModel:
+		if tm.watching {
+			return []model{tm.watch}
+		}
+	}
+	return nil
+}
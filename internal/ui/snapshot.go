@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+type statusMsg string
+
+// snapshot renders the current table (and graph or heatmap, if shown) as
+// markdown with terminal colors stripped so it can be pasted into a chat or
+// ticket.
+func (m model) snapshot(at time.Time) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# met snapshot\n\nTaken at %s\n\n```\n", at.Format(time.RFC3339)))
+	sb.WriteString(m.renderTablePage())
+	if chart := m.renderChart(); chart != "" {
+		sb.WriteString("\n")
+		sb.WriteString(chart)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("```\n")
+	sb.WriteString(m.renderNotes())
+	return ansiEscape.ReplaceAllString(sb.String(), "")
+}
+
+func snapshotCmd(content, dir string, toClipboard bool, at time.Time) tea.Cmd {
+	path := filepath.Join(dir, fmt.Sprintf("met-snapshot-%s.md", at.Format("20060102-150405")))
+	return saveCmd(content, path, "Snapshot", toClipboard)
+}
+
+// saveCmd writes content to path, or copies it to the clipboard, reporting
+// what was saved.
+func saveCmd(content, path, what string, toClipboard bool) tea.Cmd {
+	return func() tea.Msg {
+		if toClipboard {
+			if err := copyToClipboard(content); err != nil {
+				return statusMsg(fmt.Sprintf("%s failed: %v", what, err))
+			}
+			return statusMsg(what + " copied to clipboard")
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return statusMsg(fmt.Sprintf("%s failed: %v", what, err))
+		}
+		return statusMsg(what + " written to " + path)
+	}
+}
+
+// copyToClipboard uses the OSC 52 escape sequence, which works in most
+// modern terminals and over SSH without any platform-specific tooling.
+func copyToClipboard(s string) error {
+	seq := osc52.New(s)
+	if os.Getenv("TMUX") != "" {
+		seq = seq.Tmux()
+	}
+	_, err := seq.WriteTo(os.Stderr)
+	return err
+}
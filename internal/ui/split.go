@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// splitGap separates the panes of the split view.
+const splitGap = "   "
+
+// toggleSplit opens the split view with the selected series in its left
+// pane, leaving the selection to pick the right one, or closes it.
+func (m model) toggleSplit() model {
+	if m.splitKey != "" {
+		m.splitKey = ""
+		m.status = "Closed split view"
+		return m
+	}
+	if m.selected < 0 || m.selected >= len(m.metricsList) {
+		return m
+	}
+	m.splitKey = m.metricsList[m.selected].key
+	m.status = "Split view: move the selection to pick the right pane, V to move it to the left"
+	return m
+}
+
+// setSplitLeft moves the selected series into the split view's left pane.
+func (m model) setSplitLeft() model {
+	if m.splitKey == "" || m.selected < 0 || m.selected >= len(m.metricsList) {
+		return m
+	}
+	m.splitKey = m.metricsList[m.selected].key
+	return m
+}
+
+// renderSplit graphs the split view's series side by side with the
+// selected one.
+func (m model) renderSplit() string {
+	cols := 0
+	if m.width > 0 {
+		cols = (m.width - len(splitGap)) / 2
+	}
+	left := "(" + m.splitKey + " is gone)"
+	if idx, ok := m.metricsIndex[m.splitKey]; ok && idx < len(m.metricsList) {
+		left = m.renderSeriesGraph(m.metricsList[idx], cols)
+	}
+	right := m.renderGraph()
+	if cols > 0 {
+		left = lipgloss.NewStyle().MaxWidth(cols).Render(left)
+		right = lipgloss.NewStyle().MaxWidth(cols).Render(right)
+	}
+	gap := strings.TrimSuffix(strings.Repeat(splitGap+"\n", strings.Count(left, "\n")+1), "\n")
+	return lipgloss.JoinHorizontal(lipgloss.Top, left, gap, right)
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"math"
+)
+
+// statsHeader is the headers of the columns --stats adds, summarizing each
+// series over the history it retains.
+var statsHeader = []string{"Min", "Max", "Avg"}
+
+// windowValues is what a series' range is measured over: its retained
+// history, or for counters, which only ever go up, the increase per scrape.
+func windowValues(md metricData) []float64 {
+	if md.Counter {
+		return graphDelta.points(md)
+	}
+	return md.History
+}
+
+// statsColumns returns md's minimum, maximum and average over its history,
+// so a spike is seen after the fact without going back through graphs.
+func statsColumns(md metricData, format func(float64) string) []string {
+	values := windowValues(md)
+	lo, hi := minMax(values)
+	if math.IsInf(lo, 1) {
+		return []string{"--", "--", "--"}
+	}
+	sum, n := 0.0, 0
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			sum += v
+			n++
+		}
+	}
+	return []string{format(lo), format(hi), format(sum / float64(n))}
+}
+
+// toggleStats shows or hides the statistics columns.
+func (m model) toggleStats() model {
+	m.stats = !m.stats
+	if m.stats {
+		m.status = "Showing the min, max and average of each series' history"
+	} else {
+		m.status = "Hiding the min, max and average columns"
+	}
+	return m
+}
@@ -0,0 +1,5 @@
+package ui
+
+type StatsdCmd struct {
+	Listen string `help:"UDP address to receive StatsD packets on" default:":8125"`
+}
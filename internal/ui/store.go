@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jaxxstorm/met/internal/store"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func storeCmd(s *store.Store, session uint64, families map[string]*dto.MetricFamily, at time.Time) tea.Cmd {
+	return func() tea.Msg {
+		if err := s.Record(session, families, at); err != nil {
+			return statusMsg(fmt.Sprintf("Storing scrape failed: %v", err))
+		}
+		return nil
+	}
+}
+
+// restoreHistory replays the most recent stored session for the model's
+// endpoint so graphs and accumulated values survive a restart.
+func (m model) restoreHistory(s *store.Store) (model, error) {
+	si, ok, err := s.LastSession(m.endpoint)
+	if err != nil || !ok {
+		return m, err
+	}
+	scrapes, err := s.LoadScrapes(si.ID, maxHistory)
+	if err != nil {
+		return m, err
+	}
+	for _, sc := range scrapes {
+		m = updateMetrics(m, sc.Families(), sc.At)
+	}
+	m.notes = si.Notes
+	return m, nil
+}
+
+// startRecording restores the model's history from s and starts the session
+// its scrapes will be recorded to.
+func (m model) startRecording(s *store.Store) (model, error) {
+	m, err := m.restoreHistory(s)
+	if err != nil {
+		return m, fmt.Errorf("restoring history: %w", err)
+	}
+	m.session, err = s.StartSession(m.endpoint, time.Now())
+	if err != nil {
+		return m, fmt.Errorf("starting session: %w", err)
+	}
+	if len(m.notes) > 0 {
+		if err := s.SetNotes(m.session, m.notes); err != nil {
+			return m, fmt.Errorf("storing notes: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// historyScrapes rebuilds the scrapes behind the model's history, for
+// saving a session that wasn't stored as it went, from the samples kept
+// with each point of it. They're the samples --store would have recorded,
+// so replaying them gives what restoring a stored session would.
+func (m model) historyScrapes() []store.Scrape {
+	byTime := make(map[time.Time]*store.Scrape)
+	for _, md := range m.metricsList {
+		if md.synthetic || len(md.scraped) == 0 {
+			continue
+		}
+		var labels map[string]string
+		if len(md.labelPairs) > 0 {
+			labels = make(map[string]string, len(md.labelPairs))
+			for _, lp := range md.labelPairs {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+		}
+		// scraped is trimmed along with the end of the history.
+		at := md.HistoryAt[len(md.HistoryAt)-len(md.scraped):]
+		for i, ss := range md.scraped {
+			sc, ok := byTime[at[i]]
+			if !ok {
+				sc = &store.Scrape{At: at[i]}
+				byTime[at[i]] = sc
+			}
+			ss.Name, ss.Labels = md.name, labels
+			sc.Samples = append(sc.Samples, ss)
+		}
+	}
+	out := make([]store.Scrape, 0, len(byTime))
+	for _, sc := range byTime {
+		out = append(out, *sc)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].At.Before(out[j].At) })
+	return out
+}
+
+// saveHistory saves the history of each of ms to the store at path as a
+// session of its own, so it can be restored with --store or looked at with
+// met query.
+func saveHistory(path string, ms []model) error {
+	s, err := store.Open(path)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	for _, m := range ms {
+		scrapes := m.historyScrapes()
+		if len(scrapes) == 0 {
+			continue
+		}
+		session, err := s.StartSession(m.endpoint, scrapes[0].At)
+		if err != nil {
+			return err
+		}
+		if len(m.notes) > 0 {
+			if err := s.SetNotes(session, m.notes); err != nil {
+				return err
+			}
+		}
+		for _, sc := range scrapes {
+			if err := s.Put(session, sc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
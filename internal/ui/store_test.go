@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHistoryScrapes(t *testing.T) {
+	latency := func(count uint64, buckets ...uint64) map[string]*dto.MetricFamily {
+		h := &dto.Histogram{SampleCount: proto.Uint64(count), SampleSum: proto.Float64(1)}
+		for i, c := range buckets {
+			h.Bucket = append(h.Bucket, &dto.Bucket{UpperBound: proto.Float64(float64(i + 1)), CumulativeCount: proto.Uint64(c)})
+		}
+		return map[string]*dto.MetricFamily{"latency": {
+			Name:   proto.String("latency"),
+			Type:   dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{{Histogram: h}},
+		}}
+	}
+	// The counter resets between the second and third scrapes.
+	counts := []float64{10, 15, 3}
+	start := time.Unix(1000, 0)
+	m := model{metricsIndex: map[string]int{}}
+	for i, v := range counts {
+		fams := counterFamilies(map[string]float64{"200": v})
+		fams["latency"] = latency(uint64(i+1), uint64(i), uint64(i+1))["latency"]
+		m = updateMetrics(m, fams, start.Add(time.Duration(i)*5*time.Second))
+	}
+
+	scrapes := m.historyScrapes()
+	if len(scrapes) != len(counts) {
+		t.Fatalf("historyScrapes gave %d scrapes, want %d", len(scrapes), len(counts))
+	}
+	for i, sc := range scrapes {
+		if want := start.Add(time.Duration(i) * 5 * time.Second); !sc.At.Equal(want) {
+			t.Errorf("scrape %d at %v, want %v", i, sc.At, want)
+		}
+		for _, ss := range sc.Samples {
+			switch ss.Name {
+			case "requests_total":
+				if ss.Value != counts[i] || !ss.Counter || ss.Type != "COUNTER" || ss.Labels["code"] != "200" {
+					t.Errorf("scrape %d: counter sample = %+v, want the raw value %v", i, ss, counts[i])
+				}
+			case "latency":
+				if ss.Type != "HISTOGRAM" || ss.Count != uint64(i+1) || len(ss.Buckets) != 2 || ss.Buckets[1].Count != uint64(i+1) {
+					t.Errorf("scrape %d: histogram sample = %+v", i, ss)
+				}
+			default:
+				t.Errorf("scrape %d: unexpected sample %+v", i, ss)
+			}
+		}
+	}
+
+	// Replaying the scrapes accumulates the counter as it was the first
+	// time, from the first scrape and across the reset.
+	replayed := model{metricsIndex: map[string]int{}}
+	for _, sc := range scrapes {
+		replayed = updateMetrics(replayed, sc.Families(), sc.At)
+	}
+	accum := func(m model) float64 {
+		for _, md := range m.metricsList {
+			if md.name == "requests_total" {
+				return md.Total
+			}
+		}
+		return 0
+	}
+	if got, want := accum(replayed), accum(m); got != want || want != 8 {
+		t.Errorf("replayed counter accumulated %v, want %v (8)", got, want)
+	}
+}
@@ -0,0 +1,365 @@
+package ui
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jaxxstorm/met/internal/scrape"
+)
+
+// tabs shows one model per endpoint. Each tab keeps its own table, filters
+// and selection; tab, shift+tab and the number keys switch between them.
+type tabs struct {
+	tabs   []tab
+	active int
+	nextID int
+	quit   bool
+
+	// With discover set, tabs are added and removed as targets come and
+	// go, such as from a Prometheus file_sd file or a DNS SRV record.
+	// newTab builds the model for a new target.
+	discover scrape.Discoverer
+	interval time.Duration
+	newTab   func(endpoint string) model
+	status   string
+
+	// With agg set, aggModel shows every tab's series collapsed into one
+	// table, and takes the keys the active tab normally would.
+	agg      aggMode
+	aggModel model
+
+	// width is the terminal's width, passed on to tabs added later.
+	width int
+}
+
+type tab struct {
+	id int
+	m  model
+	// discovered tabs were found by discovery and are removed when their
+	// target disappears.
+	discovered bool
+	// health is the target's health check status, if discovery reports
+	// one.
+	health string
+}
+
+// tabMsg routes a message produced by one of a tab's commands back to that
+// tab. Tabs are addressed by id rather than position so messages still find
+// the right tab if the list changes.
+type tabMsg struct {
+	id  int
+	msg tea.Msg
+}
+
+// addTab adds a tab for m, starting its store session if it has one, and
+// returns the command that starts it scraping. Tabs added before the program
+// starts are started by Init instead.
+func (t *tabs) addTab(m model, discovered bool) tea.Cmd {
+	if m.store != nil {
+		var err error
+		if m, err = m.startRecording(m.store); err != nil {
+			m.err = err
+		}
+	}
+	if t.width > 0 {
+		m.width = t.width
+	}
+	m.spread = true
+	tb := tab{id: t.nextID, m: m, discovered: discovered}
+	t.nextID++
+	t.tabs = append(t.tabs, tb)
+	return wrapTabCmd(tb.id, m.Init())
+}
+
+// syncDiscovered adds tabs for newly discovered targets and removes those
+// that are no longer found, keeping the active tab where possible.
+func (t *tabs) syncDiscovered(targets []scrape.Target) tea.Cmd {
+	want := make(map[string]bool, len(targets))
+	health := make(map[string]string, len(targets))
+	for _, tg := range targets {
+		want[tg.Endpoint] = true
+		health[tg.Endpoint] = tg.Health
+	}
+	var activeID int
+	if t.active < len(t.tabs) {
+		activeID = t.tabs[t.active].id
+	}
+	have := make(map[string]bool, len(t.tabs))
+	kept := make([]tab, 0, len(t.tabs))
+	for _, tb := range t.tabs {
+		if tb.discovered && !want[tb.m.endpoint] {
+			continue
+		}
+		have[tb.m.endpoint] = true
+		if tb.discovered {
+			tb.health = health[tb.m.endpoint]
+		}
+		kept = append(kept, tb)
+	}
+	t.tabs = kept
+	var cmds []tea.Cmd
+	for _, tg := range targets {
+		if !have[tg.Endpoint] {
+			cmds = append(cmds, t.addTab(t.newTab(tg.Endpoint), true))
+			t.tabs[len(t.tabs)-1].health = tg.Health
+		}
+	}
+	t.active = 0
+	for i, tb := range t.tabs {
+		if tb.id == activeID {
+			t.active = i
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// wrapTabCmd tags the messages produced by cmd with the tab they belong to.
+func wrapTabCmd(id int, cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		switch msg := cmd().(type) {
+		case nil:
+			return nil
+		case tea.QuitMsg:
+			return msg
+		case tea.BatchMsg:
+			out := make(tea.BatchMsg, len(msg))
+			for i, c := range msg {
+				out[i] = wrapTabCmd(id, c)
+			}
+			return out
+		default:
+			return tabMsg{id: id, msg: msg}
+		}
+	}
+}
+
+func (t tabs) Init() tea.Cmd {
+	cmds := make([]tea.Cmd, 0, len(t.tabs)+1)
+	for _, tb := range t.tabs {
+		cmds = append(cmds, wrapTabCmd(tb.id, tb.m.Init()))
+	}
+	if t.discover != nil {
+		cmds = append(cmds, watchDiscoveryCmd(t.discover, t.interval))
+	}
+	return tea.Batch(cmds...)
+}
+
+func (t tabs) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tabMsg:
+		if msg.id == aggTabID {
+			return t.updateAggregate(msg.msg)
+		}
+		for i, tb := range t.tabs {
+			if tb.id == msg.id {
+				nt, cmd := t.updateTab(i, msg.msg)
+				if _, ok := msg.msg.(metricsMsg); ok && nt.agg != aggOff {
+					nt.aggModel = nt.aggregateView(nt.aggModel)
+				}
+				return nt, cmd
+			}
+		}
+		return t, nil
+
+	case reloadMsg:
+		t.tabs = append([]tab(nil), t.tabs...)
+		for i := range t.tabs {
+			t.tabs[i].m = t.tabs[i].m.reload(msg)
+		}
+		if t.agg != aggOff {
+			t.aggModel = t.aggModel.reload(msg)
+		}
+		if msg.err != nil || t.discover == nil {
+			return t, nil
+		}
+		newTab := t.newTab
+		t.newTab = func(endpoint string) model {
+			m := newTab(endpoint).reload(msg)
+			m.status = ""
+			return m
+		}
+		d := t.discover
+		return t, func() tea.Msg {
+			targets, err := d.Discover()
+			return discoveryMsg{targets: targets, err: err, reload: true}
+		}
+
+	case discoveryMsg:
+		next := watchDiscoveryCmd(t.discover, t.interval)
+		if msg.reload {
+			next = nil
+		}
+		if msg.err != nil {
+			// Keep the current targets rather than dropping every tab
+			// while a file is being rewritten or DNS is flaky.
+			t.status = fmt.Sprintf("Discovering targets from %s failed: %v", t.discover, msg.err)
+			return t, next
+		}
+		t.status = ""
+		t.tabs = append([]tab(nil), t.tabs...)
+		return t, tea.Batch(t.syncDiscovered(msg.targets), next)
+
+	case tea.WindowSizeMsg:
+		t.width = msg.Width
+		t.tabs = append([]tab(nil), t.tabs...)
+		for i := range t.tabs {
+			t.tabs[i].m.width = msg.Width
+		}
+		t.aggModel.width = msg.Width
+		return t, nil
+
+	case tea.KeyMsg:
+		if t.agg != aggOff && t.aggModel.capturingInput() {
+			return t.updateAggregate(msg)
+		}
+		if t.agg == aggOff && len(t.tabs) > 0 && t.tabs[t.active].m.capturingInput() {
+			return t.updateTab(t.active, msg)
+		}
+		switch msg.String() {
+		case "ctrl+c", "q":
+			t.quit = true
+			return t, tea.Quit
+		}
+		if len(t.tabs) == 0 {
+			return t, nil
+		}
+		switch k := msg.String(); k {
+		case "tab":
+			t.active = (t.active + 1) % len(t.tabs)
+			t.agg = aggOff
+			return t, nil
+		case "shift+tab":
+			t.active = (t.active + len(t.tabs) - 1) % len(t.tabs)
+			t.agg = aggOff
+			return t, nil
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			if n, _ := strconv.Atoi(k); n <= len(t.tabs) {
+				t.active = n - 1
+				t.agg = aggOff
+			}
+			return t, nil
+		case "a":
+			t.agg = (t.agg + 1) % numAggModes
+			if t.agg == aggSum {
+				t.aggModel = model{}
+			}
+			if t.agg != aggOff {
+				t.aggModel = t.aggregateView(t.aggModel)
+			}
+			return t, nil
+		case "m", "M":
+			if t.agg == aggOff {
+				break
+			}
+			// Marks apply to every tab so the aggregate can be
+			// compared against them.
+			var cmds []tea.Cmd
+			for i := range t.tabs {
+				var cmd tea.Cmd
+				t, cmd = t.updateTab(i, msg)
+				cmds = append(cmds, cmd)
+			}
+			t.aggModel = t.aggregateView(t.aggModel)
+			nt, cmd := t.updateAggregate(msg)
+			return nt, tea.Batch(append(cmds, cmd)...)
+		case "f":
+			if t.agg == aggOff {
+				break
+			}
+			// Likewise hot series, which are refreshed by the tabs.
+			t.aggModel = t.aggModel.toggleHot()
+			t.tabs = append([]tab(nil), t.tabs...)
+			var cmds []tea.Cmd
+			for i := range t.tabs {
+				var cmd tea.Cmd
+				t.tabs[i].m.hot = t.aggModel.hot
+				t.tabs[i].m, cmd = t.tabs[i].m.startHot()
+				t.tabs[i].m.rows.reset()
+				cmds = append(cmds, wrapTabCmd(t.tabs[i].id, cmd))
+			}
+			return t, tea.Batch(cmds...)
+		}
+		if t.agg != aggOff {
+			return t.updateAggregate(msg)
+		}
+		return t.updateTab(t.active, msg)
+	}
+	return t, nil
+}
+
+func (t tabs) updateAggregate(msg tea.Msg) (tabs, tea.Cmd) {
+	nm, cmd := t.aggModel.Update(msg)
+	t.aggModel = nm.(model)
+	return t, wrapTabCmd(aggTabID, cmd)
+}
+
+func (t tabs) updateTab(i int, msg tea.Msg) (tabs, tea.Cmd) {
+	tabs := make([]tab, len(t.tabs))
+	copy(tabs, t.tabs)
+	nm, cmd := tabs[i].m.Update(msg)
+	tabs[i].m = nm.(model)
+	t.tabs = tabs
+	return t, wrapTabCmd(tabs[i].id, cmd)
+}
+
+func (t tabs) View() string {
+	if t.quit {
+		return ""
+	}
+	var sb strings.Builder
+	if len(t.tabs) == 0 {
+		sb.WriteString(fmt.Sprintf("Waiting for targets from %s...\n", t.discover))
+		if t.status != "" {
+			sb.WriteString("\n" + t.status + "\n")
+		}
+		sb.WriteString("\nPress q or Ctrl+C to quit.\n")
+		return sb.String()
+	}
+	th := t.tabs[t.active].m.theme
+	for i, tb := range t.tabs {
+		unhealthy := tb.health != "" && tb.health != "passing"
+		label := fmt.Sprintf(" %d %s ", i+1, tabLabel(tb.m.endpoint))
+		if unhealthy {
+			label = fmt.Sprintf(" %d %s (%s) ", i+1, tabLabel(tb.m.endpoint), tb.health)
+		}
+		switch {
+		case i == t.active && t.agg == aggOff:
+			label = th.title.Render("[" + label + "]")
+		case unhealthy:
+			label = " " + th.negative.Render(label) + " "
+		default:
+			label = " " + label + " "
+		}
+		sb.WriteString(label)
+	}
+	if t.agg != aggOff {
+		sb.WriteString(th.title.Render("[ all (" + t.agg.String() + ") ]"))
+	}
+	sb.WriteString("\n\n")
+	if t.agg != aggOff {
+		sb.WriteString(t.aggModel.View())
+		sb.WriteString("\n" + t.renderBreakdown() + "\n")
+	} else {
+		sb.WriteString(t.tabs[t.active].m.View())
+	}
+	sb.WriteString("Press tab/shift+tab or 1-9 to switch endpoints, a to aggregate them.\n")
+	if t.status != "" {
+		sb.WriteString("\n" + t.tabs[t.active].m.theme.status.Render(t.status) + "\n")
+	}
+	return sb.String()
+}
+
+// tabLabel shortens an endpoint URL to its host for the tab bar.
+func tabLabel(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return endpoint
+}
@@ -0,0 +1,246 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jaxxstorm/met/pkg/met"
+)
+
+type TargetsCmd struct {
+	URL string `arg:"" help:"Base URL of the Prometheus server, e.g. http://localhost:9090"`
+	Job string `help:"Only list the targets of this job's scrape pool"`
+}
+
+// promTarget is an active scrape target of a Prometheus server.
+type promTarget struct {
+	pool       string
+	instance   string
+	url        string
+	health     string
+	lastError  string
+	lastScrape time.Time
+	duration   time.Duration
+}
+
+// fetchTargets lists a Prometheus server's active targets, sorted by scrape
+// pool and URL.
+func fetchTargets(client *http.Client, base, job string) ([]promTarget, error) {
+	resp, err := client.Get(strings.TrimSuffix(base, "/") + "/api/v1/targets?state=active")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, met.StatusError(resp.StatusCode)
+	}
+	var body struct {
+		Data struct {
+			ActiveTargets []struct {
+				Labels             map[string]string `json:"labels"`
+				ScrapePool         string            `json:"scrapePool"`
+				ScrapeURL          string            `json:"scrapeUrl"`
+				Health             string            `json:"health"`
+				LastError          string            `json:"lastError"`
+				LastScrape         time.Time         `json:"lastScrape"`
+				LastScrapeDuration float64           `json:"lastScrapeDuration"`
+			} `json:"activeTargets"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding Prometheus response: %w", err)
+	}
+	var targets []promTarget
+	for _, t := range body.Data.ActiveTargets {
+		if job != "" && t.ScrapePool != job && t.Labels["job"] != job {
+			continue
+		}
+		targets = append(targets, promTarget{
+			pool:       t.ScrapePool,
+			instance:   t.Labels["instance"],
+			url:        t.ScrapeURL,
+			health:     t.Health,
+			lastError:  t.LastError,
+			lastScrape: t.LastScrape,
+			duration:   time.Duration(t.LastScrapeDuration * float64(time.Second)),
+		})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].pool != targets[j].pool {
+			return targets[i].pool < targets[j].pool
+		}
+		return targets[i].url < targets[j].url
+	})
+	return targets, nil
+}
+
+type targetsMsg struct {
+	targets []promTarget
+	err     error
+}
+
+func fetchTargetsCmd(client *http.Client, base, job string) tea.Cmd {
+	return func() tea.Msg {
+		targets, err := fetchTargets(client, base, job)
+		return targetsMsg{targets: targets, err: err}
+	}
+}
+
+// targetsModel lists a Prometheus server's scrape targets, and scrapes the
+// one picked with Enter until Esc goes back to the list.
+type targetsModel struct {
+	client   *http.Client
+	base     string
+	job      string
+	interval time.Duration
+	theme    theme
+	pageSize int
+	// newWatch returns the model that scrapes a target's endpoint.
+	newWatch func(endpoint string) model
+
+	targets []promTarget
+	cursor  int
+	width   int
+	err     error
+	quit    bool
+
+	// While watching, keys and the messages tagged with watchID go to
+	// watch. Each target watched gets a new id, so messages still in
+	// flight for the last one are dropped.
+	watching bool
+	watch    model
+	watchID  int
+}
+
+func (m targetsModel) Init() tea.Cmd {
+	return fetchTargetsCmd(m.client, m.base, m.job)
+}
+
+func (m targetsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tabMsg:
+		if !m.watching || msg.id != m.watchID {
+			return m, nil
+		}
+		return m.updateWatch(msg.msg)
+	case tickMsg:
+		return m, fetchTargetsCmd(m.client, m.base, m.job)
+	case targetsMsg:
+		m.err = msg.err
+		if msg.err == nil {
+			m.targets = msg.targets
+		}
+		if m.cursor >= len(m.targets) {
+			m.cursor = max(len(m.targets)-1, 0)
+		}
+		return m, tickCmd(m.interval)
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		if m.watching {
+			return m.updateWatch(msg)
+		}
+	case tea.KeyMsg:
+		if m.watching {
+			if k := msg.String(); (k == "esc" || k == "backspace") && !m.watch.capturingInput() {
+				m.watching = false
+				return m, nil
+			}
+			return m.updateWatch(msg)
+		}
+		switch msg.String() {
+		case "ctrl+c", "q":
+			m.quit = true
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.targets)-1 {
+				m.cursor++
+			}
+		case "enter":
+			if m.cursor >= len(m.targets) {
+				break
+			}
+			m.watchID++
+			m.watching = true
+			m.watch = m.newWatch(m.targets[m.cursor].url)
+			m.watch.width = m.width
+			return m, wrapTabCmd(m.watchID, m.watch.Init())
+		case "r":
+			return m, fetchTargetsCmd(m.client, m.base, m.job)
+		}
+	}
+	return m, nil
+}
+
+func (m targetsModel) updateWatch(msg tea.Msg) (targetsModel, tea.Cmd) {
+	nm, cmd := m.watch.Update(msg)
+	m.watch = nm.(model)
+	return m, wrapTabCmd(m.watchID, cmd)
+}
+
+func (m targetsModel) View() string {
+	if m.quit {
+		return ""
+	}
+	if m.watching {
+		return m.watch.View() + "Press Esc to go back to the targets.\n"
+	}
+	var sb strings.Builder
+	sb.WriteString(m.theme.title.Render(fmt.Sprintf("Targets of %s (every %s)", m.base, m.interval)) + "\n\n")
+	if m.err != nil {
+		sb.WriteString(fmt.Sprintf("Error: %v\n\n", m.err))
+	}
+	if len(m.targets) == 0 && m.err == nil {
+		sb.WriteString("No active targets.\n")
+	}
+
+	var tsb strings.Builder
+	table := newPlainTable(&tsb, []string{"Pool", "Endpoint", "Health", "Last Scrape", "Duration", "Error"})
+	start := 0
+	if m.cursor >= m.pageSize {
+		start = m.cursor - m.pageSize + 1
+	}
+	end := min(start+m.pageSize, len(m.targets))
+	now := time.Now()
+	var errs int
+	for _, t := range m.targets {
+		if t.health != "up" {
+			errs++
+		}
+	}
+	for i := start; i < end; i++ {
+		t := m.targets[i]
+		cursor := " "
+		if i == m.cursor {
+			cursor = ">"
+		}
+		last := "never"
+		if !t.lastScrape.IsZero() {
+			last = now.Sub(t.lastScrape).Truncate(time.Second).String() + " ago"
+		}
+		health := t.health
+		switch health {
+		case "up":
+		case "down":
+			health = m.theme.negative.Render(health)
+		default:
+			health = m.theme.stale.Render(health)
+		}
+		table.Append([]string{cursor + " " + t.pool, t.url, health, last, formatSeconds(t.duration.Seconds()), t.lastError})
+	}
+	if len(m.targets) > 0 {
+		table.Render()
+		sb.WriteString(tsb.String())
+	}
+	sb.WriteString(fmt.Sprintf("\n%d targets, %d not up\n\n", len(m.targets), errs))
+	sb.WriteString("Use ↑/↓ to move, Enter to scrape a target's endpoint, r to refresh.\nPress q or Ctrl+C to quit.\n")
+	return sb.String()
+}
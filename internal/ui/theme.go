@@ -0,0 +1,128 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// themeConfig describes a theme in the config file. Colors are anything
+// lipgloss understands: ANSI numbers ("10") or hex ("#5fff87").
+type themeConfig struct {
+	Positive string `json:"positive,omitempty"`
+	Negative string `json:"negative,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Stale    string `json:"stale,omitempty"`
+	New      string `json:"new,omitempty"`
+	// Heat grades values with --heat, from the coolest color to the
+	// hottest.
+	Heat []string `json:"heat,omitempty"`
+	// Overlay colors the series overlaid on one graph, in the order
+	// they're pinned. Only ANSI numbers color the lines themselves.
+	Overlay []string `json:"overlay,omitempty"`
+}
+
+var builtinThemes = map[string]themeConfig{
+	"dark": {
+		Positive: "10",
+		Negative: "9",
+		Title:    "12",
+		Status:   "11",
+		Stale:    "8",
+		New:      "13",
+		Heat:     []string{"10", "11", "9"},
+		Overlay:  []string{"12", "10", "11", "13", "14", "9"},
+	},
+	"light": {
+		Positive: "28",
+		Negative: "124",
+		Title:    "25",
+		Status:   "130",
+		Stale:    "246",
+		New:      "90",
+		Heat:     []string{"28", "136", "124"},
+		Overlay:  []string{"25", "28", "130", "90", "30", "124"},
+	},
+}
+
+type theme struct {
+	positive lipgloss.Style
+	negative lipgloss.Style
+	title    lipgloss.Style
+	status   lipgloss.Style
+	stale    lipgloss.Style
+	new      lipgloss.Style
+	heat     []lipgloss.Style
+	overlay  []string
+}
+
+func style(color string) lipgloss.Style {
+	s := lipgloss.NewStyle()
+	if color != "" {
+		s = s.Foreground(lipgloss.Color(color))
+	}
+	return s
+}
+
+func newTheme(tc themeConfig) theme {
+	return theme{
+		positive: style(tc.Positive),
+		negative: style(tc.Negative),
+		title:    style(tc.Title).Bold(true),
+		status:   style(tc.Status),
+		stale:    style(tc.Stale),
+		new:      style(tc.New).Bold(true),
+		heat:     heatStyles(tc.Heat),
+		overlay:  tc.Overlay,
+	}
+}
+
+// noColorTheme renders everything as plain text.
+func noColorTheme() theme {
+	return newTheme(themeConfig{})
+}
+
+// resolveTheme looks a theme up by name, preferring themes defined in the
+// config file over the built-in ones.
+func resolveTheme(name string, cfg config) (theme, error) {
+	if tc, ok := cfg.Themes[name]; ok {
+		return newTheme(tc), nil
+	}
+	if tc, ok := builtinThemes[name]; ok {
+		return newTheme(tc), nil
+	}
+	var names []string
+	for n := range builtinThemes {
+		names = append(names, n)
+	}
+	for n := range cfg.Themes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return theme{}, fmt.Errorf("unknown theme %q, want one of %s", name, strings.Join(names, ", "))
+}
+
+// delta formats a change in value with format, highlighting increases and
+// decreases.
+func (t theme) delta(d float64, format func(float64) string) string {
+	if d > 0 {
+		return t.positive.Render("+" + format(d))
+	} else if d < 0 {
+		return t.negative.Render(format(d))
+	}
+	return format(0)
+}
+
+// change renders a gauge's change, colored like a counter's delta but with
+// an arrow rather than a sign.
+func (t theme) change(d float64, format func(float64) string) string {
+	if d > 0 {
+		return t.positive.Render(arrowed(d, format))
+	} else if d < 0 {
+		return t.negative.Render(arrowed(d, format))
+	}
+	return format(0)
+}
@@ -0,0 +1,243 @@
+package ui
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// treeNode groups metric names sharing an underscore-separated prefix. A
+// node's label can span several segments, e.g. http_server, when there's
+// nothing to pick between them.
+type treeNode struct {
+	// prefix is the full name up to and including this node.
+	prefix string
+	label  string
+	// metric is set when prefix is itself a metric name, and own counts
+	// its series. Metrics that are also prefixes of others get a child of
+	// their own once the tree is built.
+	metric   bool
+	own      int
+	metrics  int
+	series   int
+	children []*treeNode
+}
+
+// treeView browses metric names grouped by prefix in place of the table.
+type treeView struct {
+	// expanded holds the prefixes of the nodes that are open. Like the
+	// model's other maps, it's replaced rather than modified.
+	expanded map[string]bool
+	cursor   int
+}
+
+// treeRow is a node as shown on screen, indented by depth.
+type treeRow struct {
+	node  *treeNode
+	depth int
+}
+
+// buildTree groups the names of the series in metricsList by prefix.
+func buildTree(metricsList []metricData) *treeNode {
+	root := &treeNode{}
+	byPrefix := make(map[string]*treeNode)
+	for _, md := range metricsList {
+		if md.synthetic {
+			continue
+		}
+		n := root
+		n.series++
+		var prefix string
+		for i, seg := range strings.Split(md.name, "_") {
+			if i > 0 {
+				prefix += "_"
+			}
+			prefix += seg
+			child, ok := byPrefix[prefix]
+			if !ok {
+				child = &treeNode{prefix: prefix, label: seg}
+				byPrefix[prefix] = child
+				n.children = append(n.children, child)
+			}
+			child.series++
+			n = child
+		}
+		n.own++
+		if !n.metric {
+			n.metric = true
+			for _, p := range parentsOf(md.name) {
+				byPrefix[p].metrics++
+			}
+			root.metrics++
+		}
+	}
+	for _, n := range byPrefix {
+		if n.metric && len(n.children) > 0 {
+			n.children = append(n.children, &treeNode{prefix: n.prefix, label: n.prefix, metric: true, own: n.own, metrics: 1, series: n.own})
+			n.metric = false
+		}
+	}
+	compactTree(root)
+	return root
+}
+
+// parentsOf lists name's prefixes, including name itself.
+func parentsOf(name string) []string {
+	var out []string
+	for i, c := range name {
+		if c == '_' {
+			out = append(out, name[:i])
+		}
+	}
+	return append(out, name)
+}
+
+// compactTree merges each node that only leads to one other into it, and
+// sorts children by label.
+func compactTree(n *treeNode) {
+	for i, c := range n.children {
+		for !c.metric && len(c.children) == 1 {
+			only := c.children[0]
+			only.label = c.label + "_" + only.label
+			c = only
+		}
+		n.children[i] = c
+		compactTree(c)
+	}
+	sort.Slice(n.children, func(i, j int) bool { return n.children[i].label < n.children[j].label })
+}
+
+// rows flattens the open part of the tree under root.
+func (t treeView) rows(root *treeNode) []treeRow {
+	var out []treeRow
+	var walk func(n *treeNode, depth int)
+	walk = func(n *treeNode, depth int) {
+		for _, c := range n.children {
+			out = append(out, treeRow{node: c, depth: depth})
+			if t.expanded[c.prefix] {
+				walk(c, depth+1)
+			}
+		}
+	}
+	walk(root, 0)
+	return out
+}
+
+func (m model) startTree() model {
+	m.tree = &treeView{}
+	return m
+}
+
+func (m model) updateTree(msg tea.KeyMsg) (model, tea.Cmd) {
+	t := *m.tree
+	rows := t.rows(buildTree(m.metricsList))
+	t.cursor = min(t.cursor, max(len(rows)-1, 0))
+	var row treeRow
+	if t.cursor < len(rows) {
+		row = rows[t.cursor]
+	}
+	setOpen := func(prefix string, open bool) {
+		t.expanded = maps.Clone(t.expanded)
+		if t.expanded == nil {
+			t.expanded = make(map[string]bool)
+		}
+		if open {
+			t.expanded[prefix] = true
+		} else {
+			delete(t.expanded, prefix)
+		}
+	}
+	switch msg.String() {
+	case "esc", "q", "ctrl+c", "t":
+		m.tree = nil
+		return m, nil
+	case "up", "k":
+		if t.cursor > 0 {
+			t.cursor--
+		}
+	case "down", "j":
+		if t.cursor < len(rows)-1 {
+			t.cursor++
+		}
+	case "right", "l":
+		if row.node != nil && len(row.node.children) > 0 {
+			setOpen(row.node.prefix, true)
+		}
+	case "left", "h":
+		if row.node == nil {
+			break
+		}
+		if t.expanded[row.node.prefix] {
+			setOpen(row.node.prefix, false)
+			break
+		}
+		// Otherwise move up to the parent.
+		for i := t.cursor - 1; i >= 0; i-- {
+			if rows[i].depth < row.depth {
+				t.cursor = i
+				break
+			}
+		}
+	case "enter":
+		if row.node == nil {
+			break
+		}
+		if len(row.node.children) > 0 {
+			setOpen(row.node.prefix, !t.expanded[row.node.prefix])
+			break
+		}
+		// A metric, so select its first series in the table.
+		m.tree = nil
+		for i, md := range m.metricsList[:m.visibleLen()] {
+			if md.name == row.node.prefix {
+				m.selected = i
+				m.enforcePageBounds()
+				break
+			}
+		}
+		return m, nil
+	}
+	m.tree = &t
+	return m, nil
+}
+
+// renderTree shows the metric names grouped by prefix in place of the
+// table.
+func (m model) renderTree() string {
+	root := buildTree(m.metricsList)
+	rows := m.tree.rows(root)
+	var sb strings.Builder
+	sb.WriteString(m.theme.title.Render(fmt.Sprintf("Metrics from %s by prefix (%d metrics, %d series)", m.endpoint, root.metrics, root.series)) + "\n\n")
+
+	var tsb strings.Builder
+	table := newPlainTable(&tsb, []string{"Name", "Metrics", "Series"})
+	m.layout.configure(table)
+	cursor := min(m.tree.cursor, max(len(rows)-1, 0))
+	start := 0
+	if cursor >= m.pageSize {
+		start = cursor - m.pageSize + 1
+	}
+	for i := start; i < len(rows) && i < start+m.pageSize; i++ {
+		n := rows[i].node
+		mark := " "
+		if i == cursor {
+			mark = ">"
+		}
+		label := n.label
+		switch {
+		case len(n.children) == 0:
+			label = "  " + label
+		case m.tree.expanded[n.prefix]:
+			label = "▾ " + label + "_"
+		default:
+			label = "▸ " + label + "_"
+		}
+		table.Append([]string{mark + " " + strings.Repeat("  ", rows[i].depth) + label, fmt.Sprint(n.metrics), fmt.Sprint(n.series)})
+	}
+	table.Render()
+	sb.WriteString(tsb.String())
+	return sb.String()
+}
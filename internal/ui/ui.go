@@ -0,0 +1,1941 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/guptarohit/asciigraph"
+	"github.com/jaxxstorm/met/internal/scrape"
+	"github.com/jaxxstorm/met/internal/store"
+	"github.com/olekukonko/tablewriter"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Version is the version of met, as reported to endpoints and in its own
+// metrics. The met command sets it.
+var Version = "dev"
+
+type CLI struct {
+	Endpoint  []string      `help:"Metrics endpoint to poll (repeatable, each endpoint gets its own tab)" short:"e" env:"MET_ENDPOINT"`
+	Var       []string      `help:"Values of a {name} in --endpoint, as name=a,b,c, expanding it into an endpoint for each (repeatable)" sep:"none"`
+	FileSD    string        `help:"Prometheus file_sd file (JSON or YAML) to read endpoints from, watched for changes" name:"file-sd" type:"existingfile"`
+	Interval  time.Duration `help:"Poll interval" default:"2s" short:"s" env:"MET_INTERVAL"`
+	Format    string        `help:"Format of the endpoint: prometheus, expvar (Go's /debug/vars) or json" enum:"prometheus,expvar,json" default:"prometheus" env:"MET_FORMAT"`
+	Version   bool          `help:"Print version information" short:"v"`
+	Include   []string      `help:"Include metrics whose name contains these substrings" short:"i"`
+	Exclude   []string      `help:"Exclude metrics whose name contains these substrings" short:"x"`
+	Labels    []string      `help:"Show only metrics with label=value (ANDed)" short:"l"`
+	Select    []string      `help:"PromQL-style series selector, e.g. 'http_requests_total{code=~\"5..\"}' (repeatable, ORed)" sep:"none"`
+	Preset    string        `help:"Start with the filters of a preset saved in the config file"`
+	Expr      []string      `help:"Derived expression shown as its own row, e.g. 'error_ratio = rate(errors_total) / rate(requests_total)' (repeatable)" sep:"none"`
+	ShowGraph bool          `help:"Display an ASCII graph for the selected metric" default:"false"`
+	Layout    string        `help:"Table layout: table, compact (borderless) or wide (adds rate, min/max and a sparkline); L cycles it" enum:"table,compact,wide" default:"table"`
+	Quantile  float64       `help:"Quantile the quantile graph mode estimates for histograms, e.g. 0.99 for p99" default:"0.99"`
+	Raw       bool          `help:"Show raw values instead of formatting bytes, durations and counts with units"`
+	ASCII     bool          `help:"Draw with ASCII only, for terminals without Unicode (the default when the locale isn't UTF-8)" name:"ascii"`
+	Stats     bool          `help:"Show the min, max and average of each series over the history it retains; s toggles them"`
+	Increase  bool          `help:"Show how much each counter went up over the last 1, 5 and 15 minutes"`
+	InfoRows  bool          `help:"Show *_info metrics, such as build_info, as table rows rather than in a panel under the title" name:"info-rows"`
+	Join      string        `help:"Join the labels of this *_info metric, e.g. build_info, onto the series they match; J picks one"`
+	Precision int           `help:"Decimal places to show numbers, counts and seconds with; values too small or large for them switch to scientific notation" default:"2"`
+
+	GraphScale string `help:"Graph y-axis scale: linear, log, or auto to switch to log for values spanning several orders of magnitude; G cycles it" enum:"linear,log,auto" default:"linear"`
+
+	Hot         []string      `help:"Series selector for hot series, refreshed every --hot-interval for high-resolution graphs (repeatable, ORed)" sep:"none"`
+	HotInterval time.Duration `help:"Poll interval for hot series, marked with --hot or f; each poll scrapes the whole endpoint" default:"1s"`
+
+	StaleTimeout time.Duration `help:"Keep series that vanish from a scrape, greyed out, for this long before dropping them" default:"0s"`
+	StuckAfter   int           `help:"List gauges that keep the same value for this many scrapes in the issues panel (0 to never)" default:"60"`
+	SeriesBudget int           `help:"Warn about endpoints exposing more series than this, cutting them down as --over-budget says (0 for no budget)" default:"50000"`
+	OverBudget   string        `help:"What to do about endpoints over --series-budget: ask, sample a stable subset of the series, aggregate each metric's series into one, or show all" enum:"ask,sample,aggregate,all" default:"ask"`
+	MaxPoints    int           `help:"Cap on the history points kept across all series, dropping the history of the series selected least recently first (0 for no cap)" default:"1000000"`
+	NotifyNew    bool          `help:"Highlight series that appear after the first scrape, such as the first increment of a rare error counter"`
+	NotifyWith   string        `help:"Also alert on new series with the terminal bell or a desktop notification: none, bell or desktop" enum:"none,bell,desktop" default:"none"`
+
+	Heat          bool    `help:"Color deltas and rates from green to red by how far they stray from the series' recent values"`
+	HeatThreshold float64 `help:"Color deltas and rates by how close they are to this value instead (implies --heat)"`
+	ColorBy       string  `help:"Give series with the same value of this label, e.g. code or instance, the same color in the table and graphs" name:"color-by"`
+
+	OnScrape string `help:"Run this shell command after every scrape, with the scrape and its samples as JSON on stdin" name:"on-scrape"`
+	OnError  string `help:"Run this shell command after every failed scrape, with the error as JSON on stdin" name:"on-error"`
+
+	GraphiteURL    string   `help:"Poll this Graphite server's render API instead of --endpoint" name:"graphite-url"`
+	GraphiteTarget []string `help:"Graphite target expression to render (repeatable)" name:"graphite-target" sep:"none"`
+	GraphiteFrom   string   `help:"How far back to ask Graphite for datapoints" name:"graphite-from" default:"-5min"`
+
+	ProxyURL string `help:"HTTP or SOCKS5 proxy to scrape through, e.g. socks5://localhost:1080 (defaults to HTTP_PROXY/HTTPS_PROXY)" name:"proxy-url"`
+	SSH      string `help:"Scrape through an SSH tunnel via this host, e.g. user@bastion or user@bastion:2222" name:"ssh"`
+
+	OAuth2TokenURL     string   `help:"Authenticate with a bearer token from this OAuth2 token URL, using the client credentials grant" name:"oauth2-token-url"`
+	OAuth2ClientID     string   `help:"OAuth2 client ID" name:"oauth2-client-id" env:"MET_OAUTH2_CLIENT_ID"`
+	OAuth2ClientSecret string   `help:"OAuth2 client secret" name:"oauth2-client-secret" env:"MET_OAUTH2_CLIENT_SECRET"`
+	OAuth2Scopes       []string `help:"OAuth2 scopes to request" name:"oauth2-scopes"`
+
+	MaxBodySize byteSize `help:"Fail scrapes, and reject OTLP requests, whose body, once decompressed, is larger than this, e.g. 64MiB (0 for no limit)" default:"128MiB"`
+
+	SelfMetrics string `help:"Serve met's own metrics (scrape durations and errors, series counts, memory) on this address, e.g. :9099/metrics" name:"self-metrics"`
+	Web         string `help:"Serve a read-only web page mirroring the TUI, refreshing itself, on this address, e.g. :8080"`
+
+	LogFile  string `help:"Log scrapes, failures, status messages and panics to this file; the TUI owns the terminal, so nothing is logged without one" type:"path" name:"log-file"`
+	LogLevel string `help:"Level to log at: debug adds every scrape's timings and the series filtered out" enum:"debug,info,warn,error" default:"info" name:"log-level"`
+
+	Config  string `help:"Path to the JSON config file" default:"${config_path}" env:"MET_CONFIG"`
+	Theme   string `help:"Color theme: dark, light or one defined in the config file"`
+	NoColor bool   `help:"Disable colored output (also honours NO_COLOR)"`
+
+	RemoteWrite    string `help:"Also forward every scrape to this Prometheus remote-write URL"`
+	RemoteWriteJob string `help:"Value of the job label added to remote-written series" default:"met"`
+
+	Baseline    string `help:"Show the percentage change against a baseline saved with 'met baseline save'"`
+	BaselineDir string `help:"Directory baselines are saved in" default:"${baseline_dir}" type:"path"`
+
+	Store      string `help:"Persist every scrape to this database file, restoring history from it on startup" type:"path" env:"MET_STORE"`
+	Cache      bool   `help:"Cache each endpoint's last successful scrape, to show if it can't be scraped when met starts" default:"true" negatable:""`
+	CacheDir   string `help:"Directory to cache scrapes in, by default met in the user's cache directory" type:"path"`
+	SaveOnExit string `help:"Save the history collected to this database file when met exits, including on SIGTERM, to restore with --store or inspect with 'met query'" type:"path" name:"save-on-exit"`
+
+	SnapshotDir       string `help:"Directory to write snapshots taken with S" default:"." type:"existingdir"`
+	SnapshotClipboard bool   `help:"Copy snapshots taken with S to the clipboard instead of writing a file"`
+
+	Watch       WatchCmd       `cmd:"" default:"1" help:"Continuously display all matching metrics"`
+	Top         TopCmd         `cmd:"" help:"Continuously display the series with the highest rate of change"`
+	Query       QueryCmd       `cmd:"" help:"Inspect sessions recorded with --store"`
+	Baselines   BaselineCmd    `cmd:"" name:"baseline" help:"Save and list baselines to compare against"`
+	Cardinality CardinalityCmd `cmd:"" help:"Report series per metric and distinct values per label"`
+	Lint        LintCmd        `cmd:"" help:"Scrape once and report problems with the endpoint's exposition format"`
+	Get         GetCmd         `cmd:"" help:"Scrape once and print the matching series as a table, JSON or YAML"`
+	Check       CheckCmd       `cmd:"" help:"Scrape once and check assertions such as 'up == 1', exiting non-zero if any fail"`
+	Otlp        OtlpCmd        `cmd:"" name:"otlp" help:"Receive OpenTelemetry metrics over OTLP/HTTP and display them"`
+	Statsd      StatsdCmd      `cmd:"" help:"Receive StatsD packets over UDP and display them"`
+	Consul      ConsulCmd      `cmd:"" help:"Scrape every instance of a service registered in Consul, following the catalog live"`
+	Docker      DockerCmd      `cmd:"" help:"Pick running Docker containers to scrape"`
+	Pushgateway PushgatewayCmd `cmd:"" help:"Browse and delete the push groups on a Prometheus Pushgateway"`
+	Targets     TargetsCmd     `cmd:"" help:"Browse a Prometheus server's scrape targets and scrape one of them"`
+	Scan        ScanCmd        `cmd:"" help:"Probe local ports for metrics endpoints and pick the ones to scrape"`
+	Proc        ProcCmd        `cmd:"" help:"Watch a process, through its own metrics endpoint or its CPU, memory and file descriptor use"`
+	Demo        DemoCmd        `cmd:"" help:"Watch a built-in exporter serving synthetic metrics, to try met out without one of your own"`
+	Connect     ConnectCmd     `cmd:"" help:"Scrape --endpoint from a remote host, through met agent run there over SSH"`
+	Import      ImportCmd      `cmd:"" help:"Browse and graph series from an OpenMetrics file or promtool tsdb dump, without scraping anything"`
+	Agent       AgentCmd       `cmd:"" help:"Scrape --endpoint whenever asked on stdin, sending the parsed samples to stdout; started by met connect"`
+}
+
+type WatchCmd struct{}
+
+type TopCmd struct {
+	Count int `help:"Number of series to display" default:"10" short:"n"`
+}
+
+// AfterApply rejects a --count that would leave nothing to page through.
+func (c *TopCmd) AfterApply() error {
+	if c.Count < 1 {
+		return fmt.Errorf("--count must be at least 1, got %d", c.Count)
+	}
+	return nil
+}
+
+func (c *CLI) AfterApply(kctx *kong.Context) error {
+	if c.Version {
+		return nil
+	}
+	endpoints, err := expandEndpoints(c.Endpoint, c.Var)
+	if err != nil {
+		return err
+	}
+	c.Endpoint = endpoints
+	switch kctx.Command() {
+	case "query", "query <session>":
+		if c.Store == "" {
+			return errors.New("must specify a database to query with --store")
+		}
+	case "baseline list", "import <path>", "otlp", "statsd", "consul", "docker", "pushgateway <url>", "targets <url>", "scan", "proc", "demo":
+	default:
+		if c.GraphiteURL != "" {
+			if len(c.GraphiteTarget) == 0 {
+				return errors.New("must specify at least one --graphite-target with --graphite-url")
+			}
+			return nil
+		}
+		multi := kctx.Command() == "watch" || kctx.Command() == "top"
+		if c.FileSD != "" && multi {
+			return nil
+		}
+		for _, ep := range c.Endpoint {
+			if scrape.IsSRV(ep) && !multi {
+				return fmt.Errorf("%s doesn't support srv:// endpoints", kctx.Command())
+			}
+		}
+		if len(c.Endpoint) == 0 {
+			return errors.New("must specify an endpoint to scrape, e.g. --endpoint http://localhost:9090/metrics")
+		}
+		if len(c.Endpoint) > 1 && !multi {
+			return fmt.Errorf("%s takes a single --endpoint", kctx.Command())
+		}
+	}
+	return nil
+}
+
+// metricData is a series as shown: its value, change, rate and history,
+// kept by the embedded scrape.Series, and everything met shows about it.
+type metricData struct {
+	scrape.Series
+	key        string
+	name       string
+	labels     string
+	labelPairs []*dto.LabelPair
+	markVal    float64
+	synthetic  bool
+	unit       unit
+	lastSeen   time.Time
+	stale      bool
+	// selectedAt is when the series was last selected, to pick whose
+	// history goes first under --max-points.
+	selectedAt time.Time
+	// appeared is when the series first showed up, if that was after the
+	// first scrape and --notify-new is set.
+	appeared time.Time
+	// sampleAt is the timestamp exposed with the latest sample, if there
+	// was one, and stampMovedAt is when that last changed.
+	sampleAt     time.Time
+	stampMovedAt time.Time
+	// resetAt is when the counter last went backwards, from resetFrom to
+	// resetTo, and resetRestart whether the process restarted then.
+	// unchanged counts the scrapes a gauge has kept its value for.
+	resetAt      time.Time
+	resetFrom    float64
+	resetTo      float64
+	resetRestart bool
+	unchanged    int
+	// resets are when the counter went backwards within its history, to
+	// mark on its graph.
+	resets []time.Time
+
+	// Histograms also track how many observations fell into each bucket
+	// on every scrape, for the heatmap, and how long the last scrape's
+	// increments took to count, for their rates.
+	// native is set for native histograms, whose bounds are merged as
+	// buckets appear rather than starting over.
+	native        bool
+	bucketBounds  []float64
+	prevBuckets   []float64
+	bucketHistory [][]float64
+	bucketElapsed float64
+
+	// scraped is the sample behind each point of a scraped series'
+	// history, as it was scraped, for saving the session on exit.
+	scraped []store.Sample
+	// sum is the hash of the sample last observed, to skip observing it
+	// again while it's unchanged.
+	sum uint64
+
+	// checkpoints are a counter's accumulated value every increaseStep,
+	// taken at checkpointAt, for --increase.
+	checkpointAt []time.Time
+	checkpoints  []float64
+}
+
+type labelFilter struct {
+	name  string
+	value string
+}
+
+type model struct {
+	endpoint string
+	source   scrape.Source
+	interval time.Duration
+	// spread scrapes this endpoint at its own offset into the interval,
+	// when there are several, rather than an interval after the last.
+	// imported is set for series read by met import, which aren't scraped.
+	spread       bool
+	imported     bool
+	initialized  bool
+	metricsList  []metricData
+	metricsIndex map[string]int
+	err          error
+	quit         bool
+
+	includes     []string
+	excludes     []string
+	labelFilters []labelFilter
+	selectors    []selector
+	relabel      []scrape.RelabelRule
+	rows         *rowCache
+	configPath   string
+	presets      map[string]filterPreset
+	prompt       *prompt
+	picker       *picker
+	drill        *drillDown
+	diff         *diffView
+	tree         *treeView
+	capA, capB   *capture
+	exprs        []*watchExpr
+	remoteWriter *remoteWriter
+	store        *store.Store
+	// cacheDir is where the last successful scrape is cached, if it is.
+	// cachedAt is when the values shown were scraped, if they were read
+	// back from the cache, and cacheTried whether that's been tried.
+	cacheDir     string
+	cachedAt     time.Time
+	cacheTried   bool
+	session      uint64
+	baseline     *baseline
+	baselineName string
+	showGraph    bool
+	graphMode    graphMode
+	graphScale   graphScale
+	// splitKey is the series in the split view's left pane, if it's open.
+	splitKey string
+	// overlay is the series pinned to the overlaid graph, in order.
+	overlay     []string
+	layout      layout
+	quantile    float64
+	showHeatmap bool
+	showHealth  bool
+	health      []scrape.Stats
+	raw         bool
+	precision   int
+	stats       bool
+	increase    bool
+	infoRows    bool
+	info        []infoSeries
+	// join is the info metric whose labels are joined onto the series.
+	join         string
+	staleTimeout time.Duration
+	stuckAfter   int
+	maxPoints    int
+	// seriesBudget is how many series an endpoint can expose before
+	// budgetMode is applied. exposed is how many it did on the last
+	// scrape, and sampleRate the fraction kept while sampling.
+	seriesBudget int
+	budgetMode   string
+	budgetAsked  bool
+	exposed      int
+	sampleRate   float64
+	points       int
+	heapBytes    uint64
+	processStart float64
+	restarts     []time.Time
+	showIssues   bool
+	hot          map[string]struct{}
+	muted        map[string]struct{}
+	showMuted    bool
+	bookmarks    map[string][]string
+	notes        map[string]string
+	hotSelectors []selector
+	hotInterval  time.Duration
+	// hotTicking is set while hot series are being refreshed, so marking
+	// another doesn't start a second chain of refreshes.
+	hotTicking  bool
+	notifyNew   bool
+	notifyWith  string
+	heat        bool
+	heatMax     float64
+	colorBy     string
+	labelColors map[string]int
+	onScrape    string
+	onError     string
+	timestamps  bool
+	known       map[string]struct{}
+	rejected    map[string]struct{}
+	topN        int
+	sortBy      string
+	sortDesc    bool
+	lastScrape  time.Time
+	markedAt    time.Time
+	status      string
+	theme       theme
+
+	snapshotDir       string
+	snapshotClipboard bool
+
+	selected  int
+	pageStart int
+	pageSize  int
+	// keyScroll is how many cells keys too wide for the terminal are
+	// scrolled left by.
+	keyScroll int
+	// width is the terminal's width, once it's known.
+	width int
+}
+
+type tickMsg time.Time
+type metricsMsg struct {
+	families map[string]*dto.MetricFamily
+	series   []scrapedSeries
+	at       time.Time
+	stats    scrape.Stats
+	err      error
+}
+
+const maxHistory = scrape.MaxHistory
+
+// Init takes the first scrape, or when spreading scrapes, waits for the
+// endpoint's slot. Each scrape's result schedules the next, so there's a
+// single chain of scrapes however long they take. Hot series given with
+// --hot start being refreshed straight away.
+func (m model) Init() tea.Cmd {
+	if m.imported {
+		return nil
+	}
+	cmds := []tea.Cmd{fetchMetricsCmd(m.source, m.relabel)}
+	if m.spread && m.interval > 0 {
+		cmds[0] = m.firstTick()
+	}
+	if m.hotTicking {
+		cmds = append(cmds, hotTickCmd(m.hotInterval))
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	nm, cmd := m.update(msg)
+	// Each model gets its own cache on its first update, so models copied
+	// for tabs don't share one.
+	if nm.rows == nil {
+		nm.rows = newRowCache()
+	} else if !m.isNavigation(msg) {
+		nm.rows.reset()
+	}
+	if nm.status != m.status && nm.status != "" {
+		nm.logStatus()
+	}
+	return nm, cmd
+}
+
+func (m model) update(msg tea.Msg) (model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case statusMsg:
+		m.status = string(msg)
+
+	case reloadMsg:
+		return m.reload(msg), nil
+
+	case cachedMsg:
+		return m.showCached(msg), nil
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+
+	case tickMsg:
+		return m, fetchMetricsCmd(m.source, m.relabel)
+
+	case hotTickMsg:
+		if !m.hasHot() {
+			m.hotTicking = false
+			return m, nil
+		}
+		if !m.initialized {
+			return m, hotTickCmd(m.hotInterval)
+		}
+		return m, fetchHotCmd(m.source, m.relabel)
+
+	case hotMetricsMsg:
+		// Failures are left for the regular scrape to report.
+		if msg.err == nil {
+			m = m.updateHot(msg.series, msg.at)
+		}
+		return m, hotTickCmd(m.hotInterval)
+
+	case metricsMsg:
+		m.recordHealth(msg.stats)
+		m.logScrape(msg.stats)
+		self.observeScrape(m.endpoint, msg.stats)
+		if msg.err != nil {
+			m.err = msg.err
+			cmds := []tea.Cmd{m.nextTick(), m.scrapeHook(msg)}
+			if !m.initialized && m.cacheDir != "" && !m.cacheTried {
+				m.cacheTried = true
+				cmds = append(cmds, loadCacheCmd(m.cacheDir, m.endpoint))
+			}
+			return m, tea.Batch(cmds...)
+		}
+		m.err = nil
+		if !m.cachedAt.IsZero() {
+			m = m.dropCached()
+		}
+		if msg.series == nil {
+			msg.series = prepareSeries(msg.families)
+		}
+		m, msg.series = m.budgetSeries(msg.series)
+		var added []string
+		if m.notifyNew {
+			added = m.trackNew(msg.series)
+		}
+		newM := applyScrape(m, msg.series, msg.families, msg.at)
+		self.observeSeries(newM.endpoint, len(newM.metricsList))
+		if newM.topN > 0 || newM.sortBy != "" {
+			newM.sortMetrics(newM.order())
+		} else if !newM.initialized || !newM.mutedLast() {
+			newM.sortMetrics(byKey)
+		}
+		newM.initialized = true
+		// Make sure selected/pageStart are still valid if the list shrinks
+		if newM.selected >= newM.visibleLen() {
+			newM.selected = newM.visibleLen() - 1
+		}
+		newM.enforcePageBounds()
+		newM.markSelected(msg.at)
+		newM.trimHistory()
+		newM = newM.assignLabelColors()
+		cmds := []tea.Cmd{newM.nextTick()}
+		if newM.remoteWriter != nil {
+			cmds = append(cmds, remoteWriteCmd(newM.remoteWriter, msg.families, msg.at))
+		}
+		if newM.store != nil {
+			cmds = append(cmds, storeCmd(newM.store, newM.session, msg.families, msg.at))
+		}
+		if newM.cacheDir != "" && len(msg.families) > 0 {
+			cmds = append(cmds, saveCacheCmd(newM.cacheDir, newM.endpoint, msg.families, msg.at))
+		}
+		if hook := newM.scrapeHook(msg); hook != nil {
+			cmds = append(cmds, hook)
+		}
+		// Only alert on new series that pass the filters.
+		var shown []string
+		for _, key := range added {
+			if idx, ok := newM.metricsIndex[key]; ok {
+				newM.metricsList[idx].appeared = msg.at
+				shown = append(shown, key)
+			}
+		}
+		if len(shown) > 0 {
+			newM.status = fmt.Sprintf("New series at %s: %s", msg.at.Format(time.TimeOnly), strings.Join(shown, ", "))
+			if newM.notifyWith != "none" {
+				cmds = append(cmds, notifyCmd(newM.notifyWith, shown))
+			}
+		}
+		return newM, tea.Batch(cmds...)
+
+	case tea.KeyMsg:
+		if m.prompt != nil {
+			return m.updatePrompt(msg)
+		}
+		if m.picker != nil {
+			return m.updatePicker(msg)
+		}
+		if m.drill != nil {
+			return m.updateDrill(msg)
+		}
+		if m.diff != nil {
+			return m.updateDiff(msg)
+		}
+		if m.tree != nil {
+			return m.updateTree(msg)
+		}
+		switch msg.String() {
+		case "ctrl+c", "q":
+			m.quit = true
+			return m, tea.Quit
+
+		case "P":
+			m = m.savePresetPrompt()
+		case "p":
+			m = m.presetPicker()
+		case "i":
+			m = m.filterPrompt(filterInclude)
+		case "x":
+			m = m.filterPrompt(filterExclude)
+		case "l":
+			m = m.filterPrompt(filterLabel)
+
+		case "m":
+			m.setMark(time.Now())
+			m.status = "Marked current values at " + m.markedAt.Format(time.TimeOnly)
+		case "M":
+			m.clearMark()
+			m.status = "Cleared mark"
+
+		case "f":
+			return m.toggleHot().startHot()
+		case "b":
+			m = m.toggleBookmark()
+		case "n":
+			m = m.notePrompt()
+		case "X":
+			m = m.mutePicker()
+		case "U":
+			m = m.toggleShowMuted()
+
+		case "A":
+			m = m.captureA()
+		case "B":
+			m = m.captureB()
+
+		case "enter":
+			m = m.startDrill()
+		case "t":
+			m = m.startTree()
+
+		case "s":
+			m = m.toggleStats()
+		case "L":
+			m.layout = (m.layout + 1) % numLayouts
+			m.status = "Layout: " + m.layout.String()
+
+		case "h":
+			m.showHeatmap = !m.showHeatmap
+		case "H":
+			m.showHealth = !m.showHealth
+		case "I":
+			m.showIssues = !m.showIssues
+		case "g":
+			m.graphMode = (m.graphMode + 1) % numGraphModes
+			m.status = "Graphing " + m.graphModeName() + " values"
+		case "G":
+			m.graphScale = (m.graphScale + 1) % numGraphScales
+			m.status = "Graph scale: " + m.graphScale.String()
+		case "o":
+			m = m.toggleOverlay()
+		case "J":
+			m = m.joinPicker()
+		case ":":
+			m = m.palettePrompt("")
+		case "W":
+			if m.overBudget() {
+				m = m.budgetPicker()
+			} else {
+				m.status = fmt.Sprintf("%s is within the series budget of %d", m.endpoint, m.seriesBudget)
+			}
+		case "v":
+			m = m.toggleSplit()
+		case "V":
+			m = m.setSplitLeft()
+
+		case "y", "Y":
+			if m.selected < 0 || m.selected >= len(m.metricsList) {
+				break
+			}
+			md := m.metricsList[m.selected]
+			switch {
+			case md.synthetic:
+				for _, ex := range m.exprs {
+					if ex.name == md.name {
+						return m, yankCmd(ex.source, "expression")
+					}
+				}
+			case msg.String() == "y":
+				return m, yankCmd(md.exposition(), "line")
+			default:
+				return m, yankCmd(md.selectorString(), "selector")
+			}
+
+		case "S":
+			now := time.Now()
+			return m, snapshotCmd(m.snapshot(now), m.snapshotDir, m.snapshotClipboard, now)
+		case "E":
+			if m.selected < 0 || m.selected >= len(m.metricsList) {
+				break
+			}
+			return m, m.grafanaExportCmd(m.metricsList[m.selected], time.Now())
+		case "Q":
+			m = m.promQLPicker()
+		case "R":
+			if m.selected < 0 || m.selected >= len(m.metricsList) {
+				break
+			}
+			return m, m.rawFamilyCmd(m.metricsList[m.selected], time.Now())
+
+		case "up", "k":
+			if m.selected > 0 {
+				m.selected--
+				m.enforcePageBounds()
+			}
+		case "down", "j":
+			if m.selected < m.visibleLen()-1 {
+				m.selected++
+				m.enforcePageBounds()
+			}
+		case "pgup":
+			m.pageStart -= m.pageSize
+			if m.pageStart < 0 {
+				m.pageStart = 0
+			}
+			// if selected is now < pageStart, fix that
+			if m.selected < m.pageStart {
+				m.selected = m.pageStart
+			}
+		case "pgdn":
+			m.pageStart += m.pageSize
+			maxStart := m.visibleLen() - m.pageSize
+			if maxStart < 0 {
+				maxStart = 0
+			}
+			if m.pageStart > maxStart {
+				m.pageStart = maxStart
+			}
+			// if selected is beyond pageStart+pageSize-1, fix that
+			pageEnd := m.pageStart + m.pageSize - 1
+			if m.selected > pageEnd {
+				m.selected = pageEnd
+			}
+		case "left":
+			m.keyScroll = max(m.keyScroll-keyScrollStep, 0)
+		case "right":
+			m.keyScroll = min(m.keyScroll+keyScrollStep, m.keyOverflow())
+		}
+	}
+	return m, nil
+}
+
+func byKey(a, b metricData) bool {
+	if a.name == b.name {
+		return a.labels < b.labels
+	}
+	return a.name < b.name
+}
+
+// byRate orders by descending rate, whichever way the series is moving, so
+// a gauge falling fast ranks with one rising as fast. It falls back to key
+// order for ties so rows with equal rates don't shuffle on every scrape.
+func byRate(a, b metricData) bool {
+	ra, rb := math.Abs(a.Rate), math.Abs(b.Rate)
+	if ra == rb {
+		return byKey(a, b)
+	}
+	return ra > rb
+}
+
+// sortMetrics reorders metricsList, with bookmarked series first and muted
+// ones last, rebuilds metricsIndex and keeps the selection on the same series
+// it was on before the sort.
+func (m *model) sortMetrics(less func(a, b metricData) bool) {
+	less = m.sinkMuted(m.pinBookmarks(less))
+	var selectedKey string
+	if m.initialized && m.selected >= 0 && m.selected < len(m.metricsList) {
+		selectedKey = m.metricsList[m.selected].key
+	}
+	sort.SliceStable(m.metricsList, func(i, j int) bool {
+		return less(m.metricsList[i], m.metricsList[j])
+	})
+	for i, md := range m.metricsList {
+		m.metricsIndex[md.key] = i
+		if md.key == selectedKey {
+			m.selected = i
+		}
+	}
+}
+
+// scrape polls the model's source once, applying the relabeling rules from
+// the config file.
+func (m model) scrape() (map[string]*dto.MetricFamily, error) {
+	fams, err := m.source.Scrape()
+	if err != nil {
+		return nil, err
+	}
+	return scrape.RelabelFamilies(fams, m.relabel), nil
+}
+
+// visibleLen is the number of rows that can be navigated; in top mode only
+// the first topN series are shown, and muted series only when their section
+// is expanded.
+func (m model) visibleLen() int {
+	muted := m.mutedCount()
+	n := len(m.metricsList) - muted
+	if m.topN > 0 && m.topN < n {
+		n = m.topN
+	}
+	if m.showMuted {
+		n += muted
+	}
+	return n
+}
+
+// setMark records every series' current value as the baseline for the
+// "Since Mark" column.
+func (m *model) setMark(at time.Time) {
+	m.markedAt = at
+	for i := range m.metricsList {
+		m.metricsList[i].markVal = m.metricsList[i].Current()
+	}
+}
+
+func (m *model) clearMark() {
+	m.markedAt = time.Time{}
+}
+
+// Enforce that selected is in [pageStart, pageStart+pageSize-1]
+func (m *model) enforcePageBounds() {
+	pageEnd := m.pageStart + m.pageSize - 1
+	if m.selected < m.pageStart {
+		m.pageStart = m.selected
+	} else if m.selected > pageEnd {
+		m.pageStart = m.selected - (m.pageSize - 1)
+	}
+	if m.pageStart < 0 {
+		m.pageStart = 0
+	}
+}
+
+// View
+func (m model) View() string {
+	if m.quit {
+		return ""
+	}
+	if m.err != nil && len(m.metricsList) == 0 {
+		return fmt.Sprintf("Error: %v\n\n%s\n\nPress q or Ctrl+C to quit.\n", m.err, m.renderHealthBar())
+	}
+	if len(m.metricsList) == 0 {
+		return fmt.Sprintf("%s\nNo metrics matched filters or still fetching...\n\nPress q or Ctrl+C to quit.\n",
+			m.title())
+	}
+
+	if m.drill != nil {
+		return m.renderDrill() + "\n(←/→ to pick a label, ↑/↓ to move, Enter to narrow to a value, Esc to go back)\n" +
+			m.renderStatus()
+	}
+
+	if m.tree != nil {
+		return m.renderTree() + "\n(↑/↓ to move, →/← to open/close a prefix, Enter to select a metric, Esc to go back)\n" +
+			m.renderStatus()
+	}
+	if m.diff != nil {
+		return m.renderDiff() + "\n(↑/↓ to move, Enter to select a series, A to capture A again, Esc to go back)\n" +
+			m.renderStatus()
+	}
+
+	tableView := m.renderTablePage()
+	graphView := m.renderChart()
+	var sb strings.Builder
+	sb.WriteString(m.renderOffline())
+	sb.WriteString(tableView)
+	sb.WriteString(m.renderHealthBar() + "\n")
+	sb.WriteString(m.renderFilters())
+	issues := m.issues()
+	sb.WriteString(m.renderIssuesSummary(issues))
+	if graphView != "" {
+		sb.WriteString("\n")
+		sb.WriteString(graphView)
+	}
+	if m.showHealth {
+		sb.WriteString("\n\n")
+		sb.WriteString(m.renderHealthPanel())
+	}
+	if m.showIssues {
+		sb.WriteString("\n\n")
+		sb.WriteString(m.renderIssuesPanel(issues))
+	}
+	sb.WriteString("\n\n")
+	switch {
+	case m.prompt != nil:
+		sb.WriteString(m.renderPrompt())
+	case m.picker != nil:
+		sb.WriteString(m.renderPicker())
+	default:
+		sb.WriteString("Use ↑/↓ to move selection, PgUp/PgDn to scroll, ←/→ to scroll long keys, m/M to set/clear a mark, g/G to change graph mode/scale,\n" +
+			"v to split the graph, o to overlay a series on it, L to change layout, s for min/max/avg, h for a histogram heatmap,\n" +
+			"i/x/l to edit include/exclude/label filters, p/P to pick/save a filter preset, y/Y/Q to copy a line/selector/query,\n" +
+			"f to refresh a series faster, b to bookmark it, n to note it, X to mute it, U to show muted series, t to browse by prefix,\n" +
+			"Enter to drill into a metric by label, A/B to capture and compare values, S to snapshot, H for scrape health,\n" +
+			"E to export a Grafana panel, R to save the raw metric family, J to join an info metric's labels, I for issues.\n" +
+			"Press : for commands such as :sort rate or :interval 5s, and q or Ctrl+C to quit.\n")
+	}
+	sb.WriteString(m.renderStatus())
+	return sb.String()
+}
+
+// renderStatus renders the status line, if there is one.
+func (m model) renderStatus() string {
+	if m.status == "" {
+		return ""
+	}
+	return "\n" + m.theme.status.Render(m.status) + "\n"
+}
+
+func (m model) title() string {
+	if m.imported {
+		return "Metrics imported from " + m.endpoint
+	}
+	if m.topN > 0 {
+		return fmt.Sprintf("Top %d metrics by rate from %s (every %s)", m.topN, m.endpoint, m.interval)
+	}
+	return fmt.Sprintf("Prometheus metrics from %s (every %s)", m.endpoint, m.interval)
+}
+
+// Only render the slice in the current page, plus a table header.
+func (m model) renderTablePage() string {
+	var sb strings.Builder
+	sb.WriteString(m.theme.title.Render(m.title()) + "\n\n")
+	sb.WriteString(m.renderInfo())
+
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	m.layout.configure(table)
+
+	header := m.tableHeader()
+	start, end := m.pageStart, min(m.pageStart+m.pageSize, m.visibleLen())
+	rows, selected := m.pageRows()
+	// Narrow terminals drop optional columns and then shorten keys, which
+	// can be scrolled, with what's hidden of the selected series shown
+	// below the table.
+	cols, keyWidth := len(header), 0
+	if m.width > 0 {
+		cols, keyWidth = m.layout.fitWidth(header, rows, m.width)
+	}
+	table.SetHeader(header[:cols])
+	truncated := false
+	for i, row := range rows {
+		row = row[:cols]
+		if keyWidth > 0 && ansi.StringWidth(row[0]) > keyWidth {
+			row[0] = scrollKey(row[0], keyWidth, m.keyScroll)
+			truncated = truncated || start+i == m.selected
+		}
+		table.Append(row)
+	}
+	table.Render()
+	sb.WriteString(tableString.String())
+
+	// Footer line for pagination
+	sb.WriteString(
+		fmt.Sprintf("\nPage %d-%d of %d total metrics\n",
+			start+1, end, len(m.metricsList)),
+	)
+	sb.WriteString(m.renderMuted())
+	sb.WriteString(m.renderBudget())
+	if selected != nil {
+		sb.WriteString(renderDetail(header, selected, cols, truncated))
+	}
+	sb.WriteString(m.renderNote())
+	return sb.String()
+}
+
+// tableHeader is the header of the table, with the columns the options
+// shown add.
+func (m model) tableHeader() []string {
+	header := []string{"Key", "Value", "Delta", "Aggregate"}
+	if m.topN > 0 {
+		header = []string{"Key", "Value", "Delta", "Rate/s"}
+	}
+	if !m.markedAt.IsZero() {
+		header = append(header, "Since Mark")
+	}
+	if m.baseline != nil {
+		header = append(header, "vs "+m.baselineName)
+	}
+	if m.timestamps {
+		header = append(header, "Age")
+	}
+	if m.stats {
+		header = append(header, statsHeader...)
+	}
+	if m.increase {
+		header = append(header, increaseHeader...)
+	}
+	return append(header, m.layout.extraHeader(m.topN > 0, m.stats)...)
+}
+
+// pageRows returns the cells of the rows on the current page, with the
+// selection cursor before each key, and those of the selected series.
+func (m model) pageRows() (rows [][]string, selected []string) {
+	start, end := m.pageStart, min(m.pageStart+m.pageSize, m.visibleLen())
+	for i := start; i < end; i++ {
+		cursor := " "
+		if i == m.selected {
+			cursor = ">"
+		}
+		cells := m.rowCells(m.metricsList[i])
+		if i == m.selected {
+			selected = cells
+		}
+		rows = append(rows, append([]string{cursor + " " + cells[0]}, cells[1:]...))
+	}
+	return rows, selected
+}
+
+// rowCells formats md's cells for the table, without the selection cursor,
+// using the row cache when it can.
+func (m model) rowCells(md metricData) []string {
+	if cells, ok := m.rows.get(md.key); ok {
+		return cells
+	}
+	format := m.formatter(md)
+	valStr := format(md.Value)
+	incDiffStr := "--"
+	totalDiffStr := "--"
+	gauge := !md.Counter && !md.synthetic
+	if md.Counter {
+		incDiffStr = m.theme.delta(md.Delta, format)
+		totalDiffStr = format(md.Total)
+	} else if gauge {
+		incDiffStr = m.theme.change(md.Delta, format)
+	}
+	if m.topN > 0 {
+		totalDiffStr = format(md.Rate)
+		if gauge {
+			totalDiffStr = m.theme.change(md.Rate, format)
+		}
+	}
+	if m.heat && md.Counter {
+		incDiffStr = m.theme.heatCell(m.heatScore(md, graphDelta, md.Delta), signed(md.Delta, format))
+		if m.topN > 0 {
+			totalDiffStr = m.theme.heatCell(m.heatScore(md, graphRate, md.Rate), totalDiffStr)
+		}
+	}
+	keyStr := md.key
+	if joined := m.joinedLabels(md); joined != "" {
+		keyStr += " · " + joined
+	}
+	if md.synthetic {
+		keyStr += " (expr)"
+	}
+	if m.isHot(md.name, md.labelPairs, md.key) && !md.synthetic {
+		keyStr += " (hot)"
+	}
+	if m.isBookmarked(md.key) {
+		keyStr += " (bookmarked)"
+	}
+	if _, ok := m.notes[md.key]; ok {
+		keyStr += " (noted)"
+	}
+	if md.stale {
+		keyStr += " (stale)"
+	} else if md.isNew(m.lastScrape) {
+		keyStr += " (new)"
+	}
+	if m.isFrozen(md) {
+		keyStr += " (frozen)"
+	}
+	muted := m.isMuted(md)
+	if muted {
+		keyStr += " (muted)"
+	}
+	if i, ok := m.labelColor(md); ok {
+		keyStr = m.theme.overlayStyle(i).Render(keyStr)
+	}
+	row := []string{keyStr, valStr, incDiffStr, totalDiffStr}
+	if !m.markedAt.IsZero() {
+		row = append(row, m.theme.delta(md.Current()-md.markVal, format))
+	}
+	if m.baseline != nil {
+		row = append(row, m.formatBaselineChange(md.key, md.Value))
+	}
+	if m.timestamps {
+		row = append(row, m.ageCell(md))
+	}
+	if m.stats {
+		row = append(row, statsColumns(md, format)...)
+	}
+	if m.increase {
+		row = append(row, m.increaseColumns(md, format)...)
+	}
+	row = append(row, m.layout.extraColumns(md, format, m.topN > 0, m.stats)...)
+	// While the endpoint can't be scraped every row is out of date.
+	if md.stale || muted || m.err != nil {
+		for j := range row {
+			row[j] = m.theme.stale.Render(ansiEscape.ReplaceAllString(row[j], ""))
+		}
+	} else if md.isNew(m.lastScrape) {
+		for j := range row {
+			row[j] = m.theme.new.Render(ansiEscape.ReplaceAllString(row[j], ""))
+		}
+	}
+	m.rows.put(md.key, row)
+	return row
+}
+
+// formatter picks how a series' values are rendered: human-readable units
+// unless --raw was given.
+func (m model) formatter(md metricData) func(float64) string {
+	if m.raw {
+		return unitNone.formatter(m.precision)
+	}
+	return md.unit.formatter(m.precision)
+}
+
+// renderChart renders whichever view of the selected series is enabled
+// below the table: the split view, the heatmap, the graph, or nothing.
+func (m model) renderChart() string {
+	if m.splitKey != "" {
+		return m.renderSplit()
+	}
+	if len(m.overlay) > 0 {
+		return m.renderOverlay()
+	}
+	if m.showHeatmap {
+		return m.renderHeatmap()
+	}
+	if m.showGraph {
+		return m.renderGraph()
+	}
+	return ""
+}
+
+// If "showGraph" is true, show the graph for the selected metric
+func (m model) renderGraph() string {
+	if m.selected < 0 || m.selected >= len(m.metricsList) {
+		return ""
+	}
+	return m.renderSeriesGraph(m.metricsList[m.selected], m.width)
+}
+
+// renderSeriesGraph graphs md, fitted to cols columns if that's known.
+func (m model) renderSeriesGraph(md metricData, cols int) string {
+	if m.graphMode == graphQuantile && md.bucketBounds == nil {
+		return "(quantile graphs are only available for histograms)"
+	}
+	points := m.graphPoints(md)
+	if lo, _ := minMax(points); math.IsInf(lo, 1) {
+		return "(no data)"
+	}
+	title := fmt.Sprintf("%s{%s}", md.name, md.labels)
+	for _, ex := range m.exprs {
+		if md.synthetic && ex.name == md.name {
+			title = ex.name + " = " + ex.source
+		}
+	}
+	format := m.formatter(md)
+	lo, hi := minMax(points)
+	current := math.NaN()
+	for i := len(points) - 1; i >= 0 && math.IsNaN(current); i-- {
+		current = points[i]
+	}
+	scale := "linear"
+	plotted := points
+	if m.graphScale.isLog(points) {
+		scale = "log"
+		plotted = log10Points(points)
+		if lo, _ := minMax(plotted); math.IsInf(lo, 1) {
+			return "(nothing above zero to plot on a log scale)"
+		}
+	}
+	plot := func(width int) (string, int) {
+		opts := []asciigraph.Option{asciigraph.Height(12), asciigraph.Width(width)}
+		if i, ok := m.labelColor(md); ok {
+			opts = append(opts, asciigraph.SeriesColors(m.theme.overlayColor(i)))
+		}
+		graph := asciigraph.Plot(plotted, opts...)
+		axis := axisColumn(graph)
+		if scale == "log" {
+			// Label the axis with the values rather than their logs.
+			return relabelAxis(graph, axis, func(v float64) string { return format(math.Pow(10, v)) })
+		}
+		return graph, axis
+	}
+	width := defaultGraphWidth
+	graph, axis := plot(width)
+	if cols > 0 {
+		// How wide the value labels are depends on the data, so the plot
+		// is fitted to the terminal once they've been measured.
+		width = max(cols-axis-1, minGraphWidth)
+		graph, axis = plot(width)
+	}
+	var sb strings.Builder
+	sb.WriteString(graph + "\n")
+	times := graphTimes(md, len(points))
+	timeAxis, legend := annotateAxis(renderTimeAxis(times, axis, width), axis, width, times, m.graphEvents(md))
+	sb.WriteString(timeAxis + "\n")
+	sb.WriteString(strings.Repeat(" ", axis) + fmt.Sprintf("%s [%s, %s scale]\n", title, m.graphModeName(), scale))
+	sb.WriteString(strings.Repeat(" ", axis) + fmt.Sprintf("current %s · min %s · max %s", format(current), format(lo), format(hi)))
+	if legend != "" {
+		sb.WriteString("\n" + strings.Repeat(" ", axis) + legend)
+	}
+	return sb.String()
+}
+
+// Commands
+func fetchMetricsCmd(src scrape.Source, relabel []scrape.RelabelRule) tea.Cmd {
+	return func() tea.Msg {
+		start := time.Now()
+		var fams map[string]*dto.MetricFamily
+		stats := scrape.Stats{Bytes: -1}
+		var err error
+		if ss, ok := src.(scrape.StatsSource); ok {
+			fams, stats, err = ss.ScrapeWithStats()
+		} else {
+			fams, err = src.Scrape()
+		}
+		at := time.Now()
+		stats.At = at
+		stats.Duration = at.Sub(start)
+		stats.Err = err
+		for _, mf := range fams {
+			stats.Series += len(mf.Metric)
+		}
+		fams = scrape.RelabelFamilies(fams, relabel)
+		return metricsMsg{families: fams, series: prepareSeries(fams), at: at, stats: stats, err: err}
+	}
+}
+
+func tickCmd(interval time.Duration) tea.Cmd {
+	return tea.Tick(interval, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+// Main update logic
+func updateMetrics(m model, families map[string]*dto.MetricFamily, at time.Time) model {
+	return applyScrape(m, prepareSeries(families), families, at)
+}
+
+// applyScrape updates the model from a scrape whose series have already
+// been prepared, off the Update path, by prepareSeries.
+func applyScrape(m model, series []scrapedSeries, families map[string]*dto.MetricFamily, at time.Time) model {
+	if m.metricsIndex == nil {
+		m.metricsIndex = make(map[string]int)
+	}
+	// Series rejected by the filters are remembered so they aren't
+	// checked again every scrape. The set is dropped if it grows well
+	// past the size of a scrape, as it would with high churn.
+	if m.rejected == nil || len(m.rejected) > 2*len(series) {
+		m.rejected = make(map[string]struct{})
+	}
+	var elapsed float64
+	if !m.lastScrape.IsZero() {
+		elapsed = at.Sub(m.lastScrape).Seconds()
+	}
+	m.lastScrape = at
+	m.info = collectInfo(families)
+	restarted := m.observeRestart(series, at)
+	seen := 0
+	for _, ss := range series {
+		if !m.infoRows && isInfoFamily(ss.mf) {
+			continue
+		}
+		idx, found := m.metricsIndex[ss.key]
+		if !found {
+			if _, ok := m.rejected[ss.key]; ok {
+				continue
+			}
+			name, lbls := ss.mf.GetName(), ss.pm.Label
+			reason := m.rejectReason(name, lbls)
+			if reason == "" && !m.sampled(ss.key) {
+				reason = "sample"
+			}
+			if reason != "" {
+				logger.Debug("Filtered out a series", "endpoint", m.endpoint, "series", ss.key, "filter", reason)
+				m.rejected[ss.key] = struct{}{}
+				continue
+			}
+			// Starting from the first value, a counter's first
+			// observation adds nothing to its total.
+			md := metricData{
+				Series: scrape.Series{
+					Counter: ss.mf.GetType() == dto.MetricType_COUNTER,
+					Value:   ss.raw,
+				},
+				key:        ss.key,
+				name:       name,
+				labels:     ss.labels,
+				labelPairs: lbls,
+				unit:       detectUnit(name),
+			}
+			// Series that appear after the mark are measured from
+			// when they were first seen.
+			if !m.markedAt.IsZero() {
+				md.markVal = md.Current()
+			}
+			m.metricsList = append(m.metricsList, md)
+			idx = len(m.metricsList) - 1
+			m.metricsIndex[ss.key] = idx
+		}
+
+		md := &m.metricsList[idx]
+		if !md.lastSeen.Equal(at) {
+			seen++
+		}
+		md.checkSample(ss.mf, ss.raw, at, restarted)
+		if ss.sum == md.sum && len(md.History) > 0 {
+			md.observeUnchanged(at)
+		} else {
+			md.observe(ss.mf, ss.pm, ss.raw, at)
+			md.sum = ss.sum
+		}
+		if m.increase {
+			md.checkpoint(at)
+		}
+		if ss.pm.TimestampMs != nil {
+			md.observeTimestamp(ss.pm.GetTimestampMs(), at)
+			m.timestamps = true
+		}
+	}
+	for _, ex := range m.exprs {
+		m.updateSeries(ex.name, ex.root.eval(families, elapsed))
+		seen++
+	}
+	if seen == len(m.metricsList) {
+		// Nothing went missing, so the list and index stand.
+		return m
+	}
+	// Series missing from this scrape are kept, marked stale, until
+	// --stale-timeout has passed since they were last seen.
+	newList := make([]metricData, 0, len(m.metricsList))
+	newIndex := make(map[string]int, len(m.metricsList))
+	for _, md := range m.metricsList {
+		if !md.lastSeen.Equal(at) {
+			if at.Sub(md.lastSeen) >= m.staleTimeout {
+				continue
+			}
+			md.stale = true
+			md.Delta = 0
+			md.Rate = 0
+		}
+		newIndex[md.key] = len(newList)
+		newList = append(newList, md)
+	}
+	m.metricsList = newList
+	m.metricsIndex = newIndex
+	return m
+}
+
+// observe records raw, scraped at at, as the series' latest value. Rates
+// are measured from the series' previous observation, which is more recent
+// than the last full scrape for hot series.
+func (md *metricData) observe(mf *dto.MetricFamily, pm *dto.Metric, raw float64, at time.Time) {
+	var elapsed float64
+	if n := len(md.HistoryAt); n > 0 {
+		elapsed = at.Sub(md.HistoryAt[n-1]).Seconds()
+	}
+	md.Observe(raw, at)
+	md.scraped = append(md.scraped, store.SampleOf(mf, pm))
+	if n := len(md.scraped) - len(md.History); n > 0 {
+		md.scraped = md.scraped[n:]
+	}
+	if mf.GetType() == dto.MetricType_HISTOGRAM {
+		md.recordBuckets(pm.GetHistogram(), elapsed)
+	}
+	md.lastSeen = at
+	md.stale = false
+}
+
+// observeUnchanged records a scrape, taken at at, that found the series as
+// it was last time, as observe would but without going over the sample
+// again.
+func (md *metricData) observeUnchanged(at time.Time) {
+	var elapsed float64
+	if n := len(md.HistoryAt); n > 0 {
+		elapsed = at.Sub(md.HistoryAt[n-1]).Seconds()
+	}
+	md.ObserveUnchanged(at)
+	if n := len(md.scraped); n > 0 {
+		md.scraped = append(md.scraped, md.scraped[n-1])
+		if n := len(md.scraped) - len(md.History); n > 0 {
+			md.scraped = md.scraped[n:]
+		}
+	}
+	if md.bucketBounds != nil {
+		md.bucketElapsed = elapsed
+		md.bucketHistory = append(md.bucketHistory, make([]float64, len(md.bucketBounds)))
+		if len(md.bucketHistory) > maxHistory {
+			md.bucketHistory = md.bucketHistory[len(md.bucketHistory)-maxHistory:]
+		}
+	}
+	md.lastSeen = at
+	md.stale = false
+}
+
+// updateSeries records a new value for a synthetic series, such as the result
+// of a --expr, creating it on first use.
+func (m *model) updateSeries(name string, v float64) {
+	idx, found := m.metricsIndex[name]
+	if !found {
+		md := metricData{Series: scrape.Series{Value: v}, key: name, name: name, synthetic: true, unit: detectUnit(name)}
+		if !m.markedAt.IsZero() {
+			md.markVal = v
+		}
+		m.metricsList = append(m.metricsList, md)
+		idx = len(m.metricsList) - 1
+		m.metricsIndex[name] = idx
+	}
+	md := m.metricsList[idx]
+	md.Value = v
+	md.Record(v, m.lastScrape)
+	md.lastSeen = m.lastScrape
+	m.metricsList[idx] = md
+}
+
+// Substring-based filters
+func (m model) passNameFilters(metricName string) bool {
+	if len(m.includes) > 0 {
+		matchedAny := false
+		for _, inc := range m.includes {
+			if strings.Contains(metricName, inc) {
+				matchedAny = true
+				break
+			}
+		}
+		if !matchedAny {
+			return false
+		}
+	}
+	for _, exc := range m.excludes {
+		if strings.Contains(metricName, exc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m model) passLabelFilters(lbls []*dto.LabelPair) bool {
+	if len(m.labelFilters) == 0 {
+		return true
+	}
+	labelMap := make(map[string]string, len(lbls))
+	for _, lp := range lbls {
+		labelMap[lp.GetName()] = lp.GetValue()
+	}
+	for _, lf := range m.labelFilters {
+		val, ok := labelMap[lf.name]
+		if !ok || val != lf.value {
+			return false
+		}
+	}
+	return true
+}
+
+// filterFamilies returns the families and series that pass the model's name,
+// label and selector filters.
+func (m model) filterFamilies(families map[string]*dto.MetricFamily) map[string]*dto.MetricFamily {
+	out := make(map[string]*dto.MetricFamily, len(families))
+	for name, mf := range families {
+		if !m.passNameFilters(name) {
+			continue
+		}
+		var metrics []*dto.Metric
+		for _, pm := range mf.Metric {
+			if m.passLabelFilters(pm.Label) && m.passSelectors(name, pm.Label) {
+				metrics = append(metrics, pm)
+			}
+		}
+		if len(metrics) == 0 {
+			continue
+		}
+		out[name] = &dto.MetricFamily{Name: mf.Name, Help: mf.Help, Type: mf.Type, Unit: mf.Unit, Metric: metrics}
+	}
+	return out
+}
+
+// passSelectors reports whether a series matches any of the --select
+// selectors. With no selectors configured everything passes.
+func (m model) passSelectors(metricName string, lbls []*dto.LabelPair) bool {
+	if len(m.selectors) == 0 {
+		return true
+	}
+	for _, sel := range m.selectors {
+		if sel.matches(metricName, lbls) {
+			return true
+		}
+	}
+	return false
+}
+
+func renderLabels(lbls []*dto.LabelPair) (string, string) {
+	if len(lbls) == 0 {
+		return "", ""
+	}
+	sort.Slice(lbls, func(i, j int) bool {
+		return lbls[i].GetName() < lbls[j].GetName()
+	})
+	// This runs for every series on every scrape, so build the strings
+	// directly rather than with Sprintf.
+	var sb strings.Builder
+	for i, lp := range lbls {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(lp.GetName())
+		sb.WriteString(`="`)
+		sb.WriteString(lp.GetValue())
+		sb.WriteByte('"')
+	}
+	key := sb.String()
+	if len(lbls) == 1 {
+		return key, key
+	}
+	// The display form separates labels with spaces. Label values can
+	// contain commas, so rebuild it rather than replacing them.
+	sb.Reset()
+	for i, lp := range lbls {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(lp.GetName())
+		sb.WriteString(`="`)
+		sb.WriteString(lp.GetValue())
+		sb.WriteByte('"')
+	}
+	return sb.String(), key
+}
+
+// Run runs the command kong parsed cli for, such as "watch" or "check".
+func Run(command string, cli CLI) {
+	if cli.LogFile != "" {
+		f, err := openLog(cli.LogFile, cli.LogLevel)
+		if err != nil {
+			log.Fatalf("Opening --log-file: %v", err)
+		}
+		defer f.Close()
+		logger.Info("Starting", "version", Version, "command", command)
+	}
+
+	var exprs []*watchExpr
+	for _, raw := range cli.Expr {
+		ex, err := parseWatchExpr(raw)
+		if err != nil {
+			log.Fatalf("Bad --expr arg %q: %v", raw, err)
+		}
+		exprs = append(exprs, ex)
+	}
+
+	st, err := loadSettings(cli)
+	if err != nil {
+		log.Fatalf("Loading config: %v", err)
+	}
+	cfg, relabel, th := st.cfg, st.relabel, st.theme
+
+	labelFilters, err := parseLabelFilters(cli.Labels)
+	if err != nil {
+		log.Fatalf("Bad --labels arg: %v", err)
+	}
+	layout, err := parseLayout(cli.Layout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	graphScale, err := parseGraphScale(cli.GraphScale)
+	if err != nil {
+		log.Fatal(err)
+	}
+	asciiOnly = cli.ASCII || !unicodeTerminal()
+	if cli.Precision < 0 || cli.Precision > 12 {
+		log.Fatalf("Bad --precision %d, want a value between 0 and 12", cli.Precision)
+	}
+	if cli.Quantile < 0 || cli.Quantile > 1 {
+		log.Fatalf("Bad --quantile %v, want a value between 0 and 1", cli.Quantile)
+	}
+
+	var selectors []selector
+	for _, raw := range cli.Select {
+		sel, err := parseSelector(raw)
+		if err != nil {
+			log.Fatalf("Bad --select arg %q: %v", raw, err)
+		}
+		selectors = append(selectors, sel)
+	}
+	var hotSelectors []selector
+	for _, raw := range cli.Hot {
+		sel, err := parseSelector(raw)
+		if err != nil {
+			log.Fatalf("Bad --hot arg %q: %v", raw, err)
+		}
+		hotSelectors = append(hotSelectors, sel)
+	}
+
+	client, err := scrape.NewHTTPClient(cli.ProxyURL, cli.SSH)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cli.OAuth2TokenURL != "" {
+		tr, err := scrape.NewOAuth2Transport(client.Transport, cli.OAuth2TokenURL, cli.OAuth2ClientID, cli.OAuth2ClientSecret, cli.OAuth2Scopes)
+		if err != nil {
+			log.Fatal(err)
+		}
+		client.Transport = tr
+	}
+
+	if cli.SelfMetrics != "" {
+		if self, err = listenSelfMetrics(cli.SelfMetrics); err != nil {
+			log.Fatalf("Serving --self-metrics: %v", err)
+		}
+	}
+	if cli.Web != "" {
+		if web, err = listenWeb(cli.Web); err != nil {
+			log.Fatalf("Serving --web: %v", err)
+		}
+	}
+
+	// Endpoints given without a path are looked for at the usual ones.
+	var detected []string
+	for i, ep := range cli.Endpoint {
+		if command == "connect <host>" {
+			// The endpoint is looked for by the agent, on its host.
+			break
+		}
+		if found, ok := scrape.Detect(client, ep, cli.Format, int64(cli.MaxBodySize)); ok {
+			cli.Endpoint[i] = found
+			detected = append(detected, fmt.Sprintf("Found the metrics of %s at %s", ep, found))
+			fmt.Fprintln(os.Stderr, detected[len(detected)-1])
+		}
+	}
+
+	var endpoint string
+	if len(cli.Endpoint) > 0 {
+		endpoint = cli.Endpoint[0]
+	}
+	cacheDir := cli.CacheDir
+	if cacheDir == "" {
+		cacheDir = defaultCacheDir()
+	}
+	if !cli.Cache {
+		cacheDir = ""
+	}
+	initialModel := model{
+		endpoint:     endpoint,
+		source:       scrape.HTTP{URL: endpoint, Format: cli.Format, Client: client, MaxBodySize: int64(cli.MaxBodySize)},
+		interval:     cli.Interval,
+		includes:     cli.Include,
+		excludes:     cli.Exclude,
+		labelFilters: labelFilters,
+		selectors:    selectors,
+		relabel:      relabel,
+		configPath:   cli.Config,
+		presets:      cfg.Presets,
+		bookmarks:    cfg.Bookmarks,
+		exprs:        exprs,
+		showGraph:    cli.ShowGraph,
+		raw:          cli.Raw,
+		precision:    cli.Precision,
+		stats:        cli.Stats,
+		increase:     cli.Increase,
+		infoRows:     cli.InfoRows,
+		join:         cli.Join,
+		staleTimeout: cli.StaleTimeout,
+		stuckAfter:   cli.StuckAfter,
+		maxPoints:    cli.MaxPoints,
+		seriesBudget: cli.SeriesBudget,
+		budgetMode:   cli.OverBudget,
+		notifyNew:    cli.NotifyNew || cli.NotifyWith != "none",
+		notifyWith:   cli.NotifyWith,
+		heat:         cli.Heat || cli.HeatThreshold > 0,
+		heatMax:      cli.HeatThreshold,
+		colorBy:      cli.ColorBy,
+		onScrape:     cli.OnScrape,
+		onError:      cli.OnError,
+		hotSelectors: hotSelectors,
+		hotInterval:  cli.HotInterval,
+		hotTicking:   len(hotSelectors) > 0 && cli.HotInterval > 0,
+		quantile:     cli.Quantile,
+		graphScale:   graphScale,
+		layout:       layout,
+		theme:        th,
+
+		snapshotDir:       cli.SnapshotDir,
+		snapshotClipboard: cli.SnapshotClipboard,
+		cacheDir:          cacheDir,
+
+		// Initialize paging
+		pageSize:  15, // you can adjust this as needed
+		pageStart: 0,
+		selected:  0,
+
+		status: strings.Join(detected, "; "),
+	}
+
+	if cli.Preset != "" {
+		p, ok := cfg.Presets[cli.Preset]
+		if !ok {
+			log.Fatalf("No preset %q in %s", cli.Preset, cli.Config)
+		}
+		if initialModel, err = initialModel.applyPreset(p); err != nil {
+			log.Fatalf("Applying preset %q: %v", cli.Preset, err)
+		}
+	}
+
+	if cli.GraphiteURL != "" {
+		src := scrape.Graphite{BaseURL: cli.GraphiteURL, Targets: cli.GraphiteTarget, From: cli.GraphiteFrom, Client: client}
+		initialModel.source = src
+		initialModel.endpoint = src.String()
+	}
+
+	if cli.RemoteWrite != "" {
+		initialModel.remoteWriter = newRemoteWriter(cli.RemoteWrite, cli.RemoteWriteJob, endpoint)
+	}
+
+	if cli.Baseline != "" {
+		b, err := loadBaseline(cli.BaselineDir, cli.Baseline)
+		if err != nil {
+			log.Fatalf("Loading baseline: %v", err)
+		}
+		initialModel.baseline = &b
+		initialModel.baselineName = cli.Baseline
+	}
+
+	if cli.SaveOnExit != "" && cli.SaveOnExit == cli.Store {
+		log.Fatal("--save-on-exit isn't needed with the same --store, which saves every scrape as it's taken")
+	}
+	if cli.Store != "" {
+		st, err := store.Open(cli.Store)
+		if err != nil {
+			log.Fatalf("Opening store: %v", err)
+		}
+		defer st.Close()
+		initialModel.store = st
+	}
+
+	switch command {
+	case "query", "query <session>":
+		if err := runQuery(os.Stdout, initialModel.store, initialModel, cli.Query.Session); err != nil {
+			log.Fatal(err)
+		}
+	case "baseline save <name>":
+		path, err := saveBaseline(initialModel, cli.BaselineDir, cli.Baselines.Save.Name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Saved baseline %q to %s\n", cli.Baselines.Save.Name, path)
+	case "baseline list":
+		if err := listBaselines(os.Stdout, cli.BaselineDir); err != nil {
+			log.Fatal(err)
+		}
+	case "cardinality":
+		if err := runCardinality(os.Stdout, initialModel, cli.Cardinality.Limit); err != nil {
+			log.Fatal(err)
+		}
+	case "get":
+		if err := runGet(os.Stdout, initialModel, cli.Get.Output); err != nil {
+			log.Fatal(err)
+		}
+	case "check":
+		n, err := runCheck(os.Stdout, initialModel, cli.Check.Assert)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if n > 0 {
+			os.Exit(1)
+		}
+	case "lint":
+		n, err := runLint(os.Stdout, initialModel)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if n > 0 {
+			os.Exit(1)
+		}
+	case "otlp":
+		recv, err := scrape.ListenOTLP(cli.Otlp.Listen, int64(cli.MaxBodySize), logger)
+		if err != nil {
+			log.Fatalf("Starting OTLP receiver: %v", err)
+		}
+		initialModel.source = recv
+		initialModel.endpoint = recv.String()
+		// Pushed metrics have no endpoint to fall back from.
+		initialModel.cacheDir = ""
+		runTUI(initialModel, cli)
+	case "statsd":
+		recv, err := scrape.ListenStatsD(cli.Statsd.Listen, logger)
+		if err != nil {
+			log.Fatalf("Starting StatsD listener: %v", err)
+		}
+		initialModel.source = recv
+		initialModel.endpoint = recv.String()
+		// Pushed metrics have no endpoint to fall back from.
+		initialModel.cacheDir = ""
+		runTUI(initialModel, cli)
+	case "consul":
+		runEndpoints(initialModel, cli, scrape.Consul{
+			Client:      client,
+			Addr:        cli.Consul.Addr,
+			Service:     cli.Consul.Service,
+			Tag:         cli.Consul.Tag,
+			Token:       cli.Consul.Token,
+			Datacenter:  cli.Consul.Datacenter,
+			PassingOnly: cli.Consul.PassingOnly,
+			Scheme:      cli.Consul.Scheme,
+			MetricsPath: cli.Consul.MetricsPath,
+		})
+	case "pushgateway <url>":
+		runProgram(pushgatewayModel{
+			client:     client,
+			base:       cli.Pushgateway.URL,
+			interval:   cli.Interval,
+			staleAfter: cli.Pushgateway.StaleAfter,
+			theme:      th,
+			pageSize:   initialModel.pageSize,
+		}, cli)
+	case "targets <url>":
+		runProgram(targetsModel{
+			client:   client,
+			base:     cli.Targets.URL,
+			job:      cli.Targets.Job,
+			interval: cli.Interval,
+			theme:    th,
+			pageSize: initialModel.pageSize,
+			newWatch: func(endpoint string) model {
+				m := endpointModel(initialModel, cli, endpoint)
+				if m.store != nil {
+					var err error
+					if m, err = m.startRecording(m.store); err != nil {
+						m.err = err
+					}
+				}
+				return m
+			},
+		}, cli)
+	case "docker":
+		d, err := scrape.NewDocker(cli.Docker.Host)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := selectDockerTargets(d, cli.Docker); err != nil {
+			log.Fatal(err)
+		}
+		runEndpoints(initialModel, cli, d)
+	case "proc":
+		var endpoint, format string
+		if !cli.Proc.Collect {
+			if endpoint, format, err = scrape.FindProcEndpoint(client, cli.Proc.PID); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if endpoint != "" {
+			initialModel = endpointModel(initialModel, cli, endpoint)
+			src := initialModel.source.(scrape.HTTP)
+			src.Format = format
+			initialModel.source = src
+			initialModel.status = fmt.Sprintf("Found the metrics of process %d at %s", cli.Proc.PID, endpoint)
+		} else {
+			src := scrape.ProcSource{PID: cli.Proc.PID}
+			initialModel.source = src
+			initialModel.endpoint = src.String()
+			if !cli.Proc.Collect {
+				initialModel.status = fmt.Sprintf("Process %d doesn't serve metrics, so they're collected from /proc", cli.Proc.PID)
+			}
+		}
+		runTUI(initialModel, cli)
+	case "demo":
+		endpoint, err := scrape.StartDemo(cli.Demo.Listen, Version, logger)
+		if err != nil {
+			log.Fatalf("Starting the demo exporter: %v", err)
+		}
+		initialModel = endpointModel(initialModel, cli, endpoint)
+		src := initialModel.source.(scrape.HTTP)
+		src.Format = "prometheus"
+		initialModel.source = src
+		runTUI(initialModel, cli)
+	case "import <path>":
+		m, err := importModel(initialModel, cli.Import.Path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		runProgram(m, cli)
+	case "agent":
+		if err := scrape.RunAgent(os.Stdin, os.Stdout, initialModel.source.(scrape.HTTP)); err != nil {
+			log.Fatal(err)
+		}
+	case "connect <host>":
+		if cli.SSH != "" {
+			log.Fatal("--ssh isn't needed with connect, which runs the agent over SSH itself")
+		}
+		src, err := scrape.NewAgentSource(cli.Connect.Host, cli.Connect.Agent, endpoint, cli.Format, int64(cli.MaxBodySize))
+		if err != nil {
+			log.Fatal(err)
+		}
+		initialModel.source = src
+		initialModel.endpoint = endpoint + " on " + cli.Connect.Host
+		runTUI(initialModel, cli)
+	case "scan":
+		endpoints, err := selectScanned(client, cli.Scan, int64(cli.MaxBodySize))
+		if err != nil {
+			log.Fatal(err)
+		}
+		cli.Endpoint = endpoints
+		runEndpoints(endpointModel(initialModel, cli, endpoints[0]), cli)
+	case "top":
+		initialModel.topN = cli.Top.Count
+		initialModel.pageSize = cli.Top.Count
+		runEndpoints(initialModel, cli)
+	default:
+		runEndpoints(initialModel, cli)
+	}
+}
+
+// endpointModel returns a copy of m scraping endpoint, with its own
+// remote-write instance label and --expr state.
+func endpointModel(m model, cli CLI, endpoint string) model {
+	m.endpoint = endpoint
+	m.exprs = cloneExprs(m.exprs)
+	if src, ok := m.source.(scrape.HTTP); ok {
+		src.URL = endpoint
+		m.source = src
+	}
+	if cli.RemoteWrite != "" {
+		m.remoteWriter = newRemoteWriter(cli.RemoteWrite, cli.RemoteWriteJob, endpoint)
+	}
+	return m
+}
+
+// runEndpoints runs m on its own, or as tabs when there are several
+// endpoints, or a file_sd file or SRV records to discover them from.
+func runEndpoints(m model, cli CLI, extra ...scrape.Discoverer) {
+	var static []string
+	ds := scrape.Discoverers(extra)
+	if cli.FileSD != "" {
+		ds = append(ds, scrape.FileSD(cli.FileSD))
+	}
+	for _, ep := range cli.Endpoint {
+		if !scrape.IsSRV(ep) {
+			static = append(static, ep)
+			continue
+		}
+		d, err := scrape.ParseSRV(ep)
+		if err != nil {
+			log.Fatalf("Bad --endpoint %q: %v", ep, err)
+		}
+		ds = append(ds, d)
+	}
+	if cli.GraphiteURL != "" || (len(static) < 2 && len(ds) == 0) {
+		runTUI(m, cli)
+		return
+	}
+	// Tabs are started by Init, so the commands returned while setting
+	// them up aren't needed.
+	var t tabs
+	for _, ep := range static {
+		t.addTab(endpointModel(m, cli, ep), false)
+	}
+	if len(ds) > 0 {
+		endpoints, err := ds.Discover()
+		if err != nil {
+			log.Fatal(err)
+		}
+		t.discover = ds
+		t.interval = cli.Interval
+		t.newTab = func(endpoint string) model { return endpointModel(m, cli, endpoint) }
+		t.syncDiscovered(endpoints)
+	}
+	runProgram(t, cli)
+}
+
+func runTUI(m model, cli CLI) {
+	if st := m.store; st != nil {
+		var err error
+		if m, err = m.startRecording(st); err != nil {
+			log.Fatal(err)
+		}
+	}
+	runProgram(m, cli)
+}
+
+// runProgram runs m until it quits, reloading the config file on SIGHUP.
+// On the way out, whether from q, Ctrl+C or SIGTERM, the history collected
+// is saved to --save-on-exit.
+func runProgram(m tea.Model, cli CLI) {
+	if asciiOnly {
+		m = asciiModel{m}
+	}
+	if web != nil {
+		m = webModel{m, web}
+	}
+	if cli.LogFile != "" {
+		m = loggedModel{m}
+	}
+	p := tea.NewProgram(m)
+	stop := watchReload(p, cli)
+	final, err := p.Run()
+	stop()
+	if err != nil {
+		logger.Error("The TUI failed", "err", err)
+		log.Fatal(err)
+	}
+	if cli.SaveOnExit != "" {
+		if err := saveHistory(cli.SaveOnExit, exitModels(final)); err != nil {
+			log.Fatalf("Saving history to %s: %v", cli.SaveOnExit, err)
+		}
+	}
+}
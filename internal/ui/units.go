@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/met/internal/scrape"
+)
+
+// unit is inferred from Prometheus naming conventions and controls how
+// values are formatted.
+type unit int
+
+const (
+	unitNone unit = iota
+	unitBytes
+	unitSeconds
+	unitCount
+)
+
+// detectUnit looks at a metric name's suffix, ignoring the _total and _sum
+// suffixes that wrap the base unit. A _count is a number of observations,
+// whatever unit they were measured in.
+func detectUnit(name string) unit {
+	if strings.HasSuffix(name, "_count") {
+		return unitCount
+	}
+	base := strings.TrimSuffix(strings.TrimSuffix(name, "_total"), "_sum")
+	switch {
+	case strings.HasSuffix(base, "_bytes"):
+		return unitBytes
+	case strings.HasSuffix(base, "_seconds"):
+		return unitSeconds
+	case strings.HasSuffix(name, "_total"):
+		return unitCount
+	}
+	return unitNone
+}
+
+// defaultPrecision is how many decimal places values are shown with,
+// unless --precision says otherwise.
+const defaultPrecision = 2
+
+// maxWholeWidth is the widest the whole part of a number, sign included,
+// is written out in full before switching to scientific notation, so huge
+// values don't stretch the table. The decimal places --precision asks for
+// come on top of it.
+const maxWholeWidth = 11
+
+func formatRaw(v float64) string {
+	return formatNumber(v, defaultPrecision)
+}
+
+// formatNumber writes v with precision decimal places, or in scientific
+// notation if that would round it to zero or its whole part is wider than
+// maxWholeWidth.
+func formatNumber(v float64, precision int) string {
+	plain := strconv.FormatFloat(v, 'f', precision, 64)
+	if math.IsNaN(v) || math.IsInf(v, 0) || v == 0 {
+		return plain
+	}
+	whole, _, _ := strings.Cut(plain, ".")
+	if math.Abs(v) < math.Pow10(-precision) || len(whole) > maxWholeWidth {
+		return strconv.FormatFloat(v, 'e', precision, 64)
+	}
+	return plain
+}
+
+// formatter returns the function used to render values of unit u, with
+// precision decimal places for plain numbers, counts and seconds.
+func (u unit) formatter(precision int) func(float64) string {
+	switch u {
+	case unitBytes:
+		return formatBytes
+	case unitSeconds:
+		return secondsFormatter(precision)
+	case unitCount:
+		return siFormatter(precision)
+	}
+	return func(v float64) string { return formatNumber(v, precision) }
+}
+
+// withSign formats the magnitude of v and prefixes a minus sign if needed,
+// so the unit formatters only deal with positive numbers.
+func withSign(v float64, f func(float64) string) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return formatRaw(v)
+	}
+	if v < 0 {
+		return "-" + f(-v)
+	}
+	return f(v)
+}
+
+func formatBytes(v float64) string {
+	return withSign(v, func(v float64) string {
+		return scrape.FormatSize(int64(math.Round(v)))
+	})
+}
+
+func formatSeconds(v float64) string {
+	return secondsFormatter(defaultPrecision)(v)
+}
+
+func secondsFormatter(precision int) func(float64) string {
+	si := siFormatter(precision)
+	return func(v float64) string {
+		return withSign(v, func(v float64) string {
+			switch {
+			case v == 0:
+				return "0s"
+			case v < 1e-6:
+				return fmt.Sprintf("%.0fns", v*1e9)
+			case v < 1e-3:
+				return fmt.Sprintf("%.1fµs", v*1e6)
+			case v < 1:
+				return fmt.Sprintf("%.1fms", v*1e3)
+			case v < 60:
+				return formatNumber(v, precision) + "s"
+			case v < 1e9:
+				return time.Duration(v * float64(time.Second)).Round(time.Second).String()
+			}
+			return si(v) + "s"
+		})
+	}
+}
+
+func siFormatter(precision int) func(float64) string {
+	return func(v float64) string {
+		return withSign(v, func(v float64) string {
+			if v < 1000 {
+				return formatNumber(v, precision)
+			}
+			units := []string{"k", "M", "G", "T", "P", "E"}
+			i := -1
+			for v >= 1000 && i < len(units)-1 {
+				v /= 1000
+				i++
+			}
+			return formatNumber(v, precision) + units[i]
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// placeholderPattern matches a {name} in an endpoint.
+var placeholderPattern = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+
+// expandEndpoints expands the endpoint templates with the values of vars,
+// each given as name=value,value..., in the order the values are given. A
+// template has a {name} for each part that differs between the targets, so
+// a fleet of identical nodes is scraped with
+//
+//	met --endpoint 'http://{host}:9100/metrics' --var host=node-1,node-2,node-3
+//
+// and one with several variables expands into every combination of their
+// values.
+func expandEndpoints(endpoints, vars []string) ([]string, error) {
+	values := make(map[string][]string, len(vars))
+	for _, v := range vars {
+		name, list, ok := strings.Cut(v, "=")
+		if !ok || !placeholderPattern.MatchString("{"+name+"}") {
+			return nil, fmt.Errorf("bad --var %q, expected name=value,value...", v)
+		}
+		if _, dup := values[name]; dup {
+			return nil, fmt.Errorf("--var %s is given more than once", name)
+		}
+		for _, value := range strings.Split(list, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values[name] = append(values[name], value)
+			}
+		}
+		if len(values[name]) == 0 {
+			return nil, fmt.Errorf("--var %s has no values", name)
+		}
+	}
+
+	used := make(map[string]bool, len(values))
+	var out []string
+	for _, ep := range endpoints {
+		expanded := []string{ep}
+		seen := make(map[string]bool)
+		for _, match := range placeholderPattern.FindAllStringSubmatch(ep, -1) {
+			name := match[1]
+			if seen[name] {
+				// Replaced along with the first {name}.
+				continue
+			}
+			seen[name] = true
+			list, ok := values[name]
+			if !ok {
+				return nil, fmt.Errorf("--endpoint %s uses {%s}, but there's no --var %s=...", ep, name, name)
+			}
+			used[name] = true
+			next := make([]string, 0, len(expanded)*len(list))
+			for _, e := range expanded {
+				for _, value := range list {
+					next = append(next, strings.ReplaceAll(e, match[0], value))
+				}
+			}
+			expanded = next
+		}
+		out = append(out, expanded...)
+	}
+	for _, v := range vars {
+		name, _, _ := strings.Cut(v, "=")
+		if !used[name] {
+			return nil, fmt.Errorf("--var %s isn't used by any --endpoint, as {%s}", name, name)
+		}
+	}
+	return out, nil
+}
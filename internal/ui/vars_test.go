@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExpandEndpoints(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoints []string
+		vars      []string
+		want      []string
+	}{
+		{
+			name:      "no templates",
+			endpoints: []string{"http://localhost:9100/metrics"},
+			want:      []string{"http://localhost:9100/metrics"},
+		},
+		{
+			name:      "one variable",
+			endpoints: []string{"http://{host}:9100/metrics"},
+			vars:      []string{"host=node-1,node-2"},
+			want:      []string{"http://node-1:9100/metrics", "http://node-2:9100/metrics"},
+		},
+		{
+			name:      "every combination of several variables",
+			endpoints: []string{"http://{host}:{port}/metrics"},
+			vars:      []string{"host=a,b", "port=9100,9200"},
+			want: []string{
+				"http://a:9100/metrics",
+				"http://a:9200/metrics",
+				"http://b:9100/metrics",
+				"http://b:9200/metrics",
+			},
+		},
+		{
+			name:      "a variable used twice",
+			endpoints: []string{"http://{host}:9100/metrics?name={host}"},
+			vars:      []string{"host=a,b"},
+			want:      []string{"http://a:9100/metrics?name=a", "http://b:9100/metrics?name=b"},
+		},
+		{
+			name:      "variables shared by endpoints",
+			endpoints: []string{"http://{host}:9100/metrics", "http://{host}:8080/metrics", "http://static/metrics"},
+			vars:      []string{"host=a,b"},
+			want: []string{
+				"http://a:9100/metrics",
+				"http://b:9100/metrics",
+				"http://a:8080/metrics",
+				"http://b:8080/metrics",
+				"http://static/metrics",
+			},
+		},
+		{
+			name:      "blank values are skipped",
+			endpoints: []string{"http://{host}/metrics"},
+			vars:      []string{"host= a, ,b,"},
+			want:      []string{"http://a/metrics", "http://b/metrics"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandEndpoints(tt.endpoints, tt.vars)
+			if err != nil {
+				t.Fatalf("expandEndpoints failed: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expandEndpoints = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandEndpointsErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoints []string
+		vars      []string
+	}{
+		{"missing =", []string{"http://{host}/metrics"}, []string{"host"}},
+		{"bad name", []string{"http://{host}/metrics"}, []string{"1host=a"}},
+		{"no values", []string{"http://{host}/metrics"}, []string{"host=,"}},
+		{"given twice", []string{"http://{host}/metrics"}, []string{"host=a", "host=b"}},
+		{"undefined variable", []string{"http://{host}/metrics"}, nil},
+		{"unused variable", []string{"http://{host}/metrics"}, []string{"host=a", "port=1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := expandEndpoints(tt.endpoints, tt.vars); err == nil {
+				t.Errorf("expandEndpoints(%q, %q) succeeded, want an error", tt.endpoints, tt.vars)
+			}
+		})
+	}
+}
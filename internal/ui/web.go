@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// web serves the TUI read-only over HTTP, for --web, or is nil.
+var web *webView
+
+// webRefresh is how often the web page fetches the latest frame.
+const webRefresh = time.Second
+
+// webView holds the frame the TUI last drew, for the web page to show.
+type webView struct {
+	mu    sync.Mutex
+	frame string
+}
+
+func (v *webView) publish(frame string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.frame = ansi.Strip(frame)
+}
+
+func (v *webView) latest() string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.frame
+}
+
+const webPage = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>met</title>
+<style>
+body { background: #111; color: #ddd; margin: 1em; }
+pre { font: 13px/1.25 ui-monospace, Menlo, Consolas, monospace; }
+</style>
+</head>
+<body>
+<pre id="frame">%s</pre>
+<script>
+setInterval(async () => {
+  try {
+    const resp = await fetch("frame.txt", {cache: "no-store"});
+    if (resp.ok) document.getElementById("frame").textContent = await resp.text();
+  } catch (e) {}
+}, %d);
+</script>
+</body>
+</html>
+`
+
+// listenWeb serves a page mirroring the TUI on addr, refreshing itself, and
+// the frame it shows as plain text at /frame.txt. Nothing can be changed
+// from it.
+func listenWeb(addr string) (*webView, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	v := &webView{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, webPage, html.EscapeString(v.latest()), webRefresh.Milliseconds())
+	})
+	mux.HandleFunc("GET /frame.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, v.latest())
+	})
+	go func() {
+		if err := http.Serve(ln, mux); err != nil {
+			logger.Error("Web server stopped", "err", err)
+		}
+	}()
+	return v, nil
+}
+
+// webModel publishes every frame of the model it wraps to the web view.
+type webModel struct {
+	tea.Model
+	view *webView
+}
+
+func (w webModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m, cmd := w.Model.Update(msg)
+	return webModel{m, w.view}, cmd
+}
+
+func (w webModel) View() string {
+	frame := w.Model.View()
+	w.view.publish(frame)
+	return frame
+}
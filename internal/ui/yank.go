@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// exposition renders md as a line of the Prometheus text format with its
+// last scraped value. Expressions are rendered as their name.
+func (md metricData) exposition() string {
+	return md.selectorString() + " " + formatFloat(md.Value)
+}
+
+// selectorString renders a PromQL selector matching exactly md, with label
+// values quoted and escaped.
+func (md metricData) selectorString() string {
+	if len(md.labelPairs) == 0 {
+		return md.name
+	}
+	parts := make([]string, 0, len(md.labelPairs))
+	for _, lp := range md.labelPairs {
+		parts = append(parts, lp.GetName()+`="`+labelValueEscaper.Replace(lp.GetValue())+`"`)
+	}
+	return md.name + "{" + strings.Join(parts, ",") + "}"
+}
+
+// labelValueEscaper escapes label values the same way in the exposition
+// format and PromQL.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// yankCmd copies s to the clipboard, reporting what was copied.
+func yankCmd(s, what string) tea.Cmd {
+	return func() tea.Msg {
+		if err := copyToClipboard(s); err != nil {
+			return statusMsg(fmt.Sprintf("Copy failed: %v", err))
+		}
+		return statusMsg(fmt.Sprintf("Copied %s to clipboard: %s", what, s))
+	}
+}
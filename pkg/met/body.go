@@ -0,0 +1,82 @@
+package met
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DecodeBody decompresses a response body according to its
+// Content-Encoding and caps how much of it can be read at limit bytes
+// (after decompression, so small compressed bodies can't expand without
+// bound). A limit of 0 means no limit.
+func DecodeBody(r io.Reader, encoding string, limit int64) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing gzip response: %w", err)
+		}
+		r = zr
+	case "deflate":
+		// deflate is meant to be zlib-wrapped, but some servers send
+		// raw deflate data, so check for a zlib header.
+		br := bufio.NewReader(r)
+		hdr, _ := br.Peek(2)
+		if len(hdr) == 2 && hdr[0]&0x0f == 8 && (uint16(hdr[0])<<8|uint16(hdr[1]))%31 == 0 {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, fmt.Errorf("decompressing deflate response: %w", err)
+			}
+			r = zr
+		} else {
+			r = flate.NewReader(br)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", encoding)
+	}
+	if limit > 0 {
+		r = &limitedReader{r: r, left: limit, limit: limit}
+	}
+	return r, nil
+}
+
+// limitedReader fails once more than limit bytes have been read, rather
+// than silently truncating the body as io.LimitReader would.
+type limitedReader struct {
+	r     io.Reader
+	left  int64
+	limit int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.left <= 0 {
+		// Only fail if there's actually more to read.
+		var probe [1]byte
+		if n, err := l.r.Read(probe[:]); n == 0 {
+			return 0, err
+		}
+		return 0, &BodyTooLargeError{Limit: l.limit}
+	}
+	if int64(len(p)) > l.left {
+		p = p[:l.left]
+	}
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	return n, err
+}
+
+// BodyTooLargeError is returned when a response body is larger than the
+// limit DecodeBody was given.
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("response body is larger than the %d byte limit", e.Limit)
+}
@@ -0,0 +1,109 @@
+package met
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// expvarSkip lists expvar keys that are noise rather than metrics: the
+// command line and the runtime's circular buffers of GC pause times.
+var expvarSkip = map[string]bool{
+	"cmdline":           true,
+	"memstats_PauseNs":  true,
+	"memstats_PauseEnd": true,
+}
+
+// ParseJSON flattens a JSON document into untyped metrics. Nested
+// object keys are joined with underscores (memstats.Alloc becomes
+// memstats_Alloc), array elements get an index label, booleans become 0 or
+// 1 and strings are ignored. With expvar set, Go's /debug/vars noise is
+// skipped.
+func ParseJSON(r io.Reader, expvar bool) (map[string]*dto.MetricFamily, error) {
+	var doc any
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding JSON: %w", err)
+	}
+	if _, ok := doc.(map[string]any); !ok {
+		return nil, fmt.Errorf("expected a JSON object at the top level")
+	}
+	fams := make(map[string]*dto.MetricFamily)
+	var walk func(name string, v any, lbls []*dto.LabelPair)
+	walk = func(name string, v any, lbls []*dto.LabelPair) {
+		if expvar && expvarSkip[name] {
+			return
+		}
+		var f float64
+		switch x := v.(type) {
+		case map[string]any:
+			keys := make([]string, 0, len(x))
+			for k := range x {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				child := SanitizeName(k)
+				if name != "" {
+					child = name + "_" + child
+				}
+				walk(child, x[k], lbls)
+			}
+			return
+		case []any:
+			idxLabel := "index"
+			if len(lbls) > 0 {
+				idxLabel += strconv.Itoa(len(lbls))
+			}
+			for i, el := range x {
+				walk(name, el, append(lbls[:len(lbls):len(lbls)], LabelPair(idxLabel, strconv.Itoa(i))))
+			}
+			return
+		case json.Number:
+			var err error
+			if f, err = x.Float64(); err != nil {
+				return
+			}
+		case bool:
+			if x {
+				f = 1
+			}
+		default:
+			return
+		}
+		mf, ok := fams[name]
+		if !ok {
+			mf = &dto.MetricFamily{Name: proto.String(name), Type: dto.MetricType_UNTYPED.Enum()}
+			fams[name] = mf
+		}
+		mf.Metric = append(mf.Metric, &dto.Metric{
+			Label:   lbls,
+			Untyped: &dto.Untyped{Value: proto.Float64(f)},
+		})
+	}
+	walk("", doc, nil)
+	return fams, nil
+}
+
+// SanitizeName maps names like http.server.duration onto valid Prometheus
+// names.
+func SanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// LabelPair makes a label for a sample.
+func LabelPair(name, value string) *dto.LabelPair {
+	return &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)}
+}
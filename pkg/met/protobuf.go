@@ -0,0 +1,31 @@
+package met
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// ParseProtobuf parses an exposition in the delimited protobuf format.
+func ParseProtobuf(r io.Reader) (map[string]*dto.MetricFamily, error) {
+	dec := expfmt.NewDecoder(r, expfmt.NewFormat(expfmt.TypeProtoDelim))
+	fams := make(map[string]*dto.MetricFamily)
+	for {
+		mf := &dto.MetricFamily{}
+		if err := dec.Decode(mf); err != nil {
+			if errors.Is(err, io.EOF) {
+				return fams, nil
+			}
+			return nil, fmt.Errorf("parsing protobuf: %w", err)
+		}
+		// A family can be split across messages, though it rarely is.
+		if prev, ok := fams[mf.GetName()]; ok {
+			prev.Metric = append(prev.Metric, mf.Metric...)
+			continue
+		}
+		fams[mf.GetName()] = mf
+	}
+}
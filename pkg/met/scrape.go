@@ -0,0 +1,161 @@
+// Package met is the scraping engine behind the met CLI, for tools that
+// want to fetch and parse metrics endpoints the same way: compressed and
+// size-capped responses, and the Prometheus text format, Go's expvar and
+// arbitrary JSON documents.
+package met
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Scraper scrapes an HTTP endpoint exposing metrics in Format: prometheus
+// (the default), expvar or json. Prometheus endpoints are asked for the
+// protobuf format, the only one native histograms are exposed in, falling
+// back to text for those that don't offer it.
+type Scraper struct {
+	URL    string
+	Format string
+	// Client is used for requests, or http.DefaultClient if nil.
+	Client *http.Client
+	// MaxBodySize caps the decompressed response body, if non-zero.
+	MaxBodySize int64
+}
+
+// Stats describes a single scrape.
+type Stats struct {
+	// Status is the HTTP status code, or 0 if no response was received.
+	Status int
+	// Bytes is the size of the response body on the wire, before
+	// decompression, or -1 if no response was received.
+	Bytes int64
+	// ParseFailed is set when the response arrived but couldn't be
+	// parsed.
+	ParseFailed bool
+}
+
+// Scrape fetches and parses the endpoint once.
+func (s Scraper) Scrape() (map[string]*dto.MetricFamily, Stats, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	stats := Stats{Bytes: -1}
+	accept := ""
+	if s.Format == "" || s.Format == "prometheus" {
+		accept = acceptProtobuf
+	}
+	resp, err := fetch(client, s.URL, accept)
+	var se StatusError
+	if errors.As(err, &se) {
+		stats.Status = int(se)
+	}
+	if err != nil {
+		return nil, stats, err
+	}
+	defer resp.Body.Close()
+	stats.Status = http.StatusOK
+	// Count the bytes on the wire, before decompression.
+	cr := &countingReader{r: resp.Body}
+	body, err := DecodeBody(cr, resp.Header.Get("Content-Encoding"), s.MaxBodySize)
+	if err != nil {
+		return nil, stats, err
+	}
+	var fams map[string]*dto.MetricFamily
+	if expfmt.ResponseFormat(resp.Header).FormatType() == expfmt.TypeProtoDelim {
+		fams, err = ParseProtobuf(body)
+	} else {
+		fams, err = Parse(body, s.Format)
+	}
+	stats.Bytes = cr.n
+	stats.ParseFailed = err != nil
+	return fams, stats, err
+}
+
+// Body fetches the endpoint's exposition without parsing it, in the text
+// format for Prometheus endpoints.
+func (s Scraper) Body() ([]byte, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := Fetch(client, s.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := DecodeBody(resp.Body, resp.Header.Get("Content-Encoding"), s.MaxBodySize)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(body)
+}
+
+// Parse parses an exposition in format: prometheus (the default), expvar
+// or json.
+func Parse(r io.Reader, format string) (map[string]*dto.MetricFamily, error) {
+	switch format {
+	case "expvar", "json":
+		return ParseJSON(r, format == "expvar")
+	}
+	var parser expfmt.TextParser
+	return parser.TextToMetricFamilies(r)
+}
+
+// acceptProtobuf asks for the delimited protobuf format, or text failing
+// that, as Prometheus does when scraping native histograms.
+const acceptProtobuf = "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7," +
+	"text/plain;version=0.0.4;q=0.3,*/*;q=0.1"
+
+// Fetch GETs url, asking for a compressed response. The body is returned as
+// sent, to be passed through DecodeBody.
+func Fetch(client *http.Client, url string) (*http.Response, error) {
+	return fetch(client, url, "")
+}
+
+func fetch(client *http.Client, url, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Setting this ourselves stops the transport from transparently
+	// decompressing gzip, so both encodings go through DecodeBody.
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, StatusError(resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// StatusError is returned for unsuccessful HTTP responses.
+type StatusError int
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("got status %d from server", int(e))
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}